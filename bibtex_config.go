@@ -51,6 +51,7 @@ const (
 	LockFileExtension          = ".lock"
 	tablesFolderSuffix         = ".tables"
 	scriptsFolderSuffix        = ".scripts"
+	entriesFolderSuffix        = ".entries" // one-file-per-entry textual export (see bibtex_library_entry_files.go)
 
 	// All exportable/importable tables live in <basename>.tables/ as CSV files,
 	// named after their DB table (or "folders" for the bootstrap settings).
@@ -60,6 +61,7 @@ const (
 	ContributorORCIDSeenFilePath  = tablesFolderSuffix + "/contributor_orcid_seen.csv"
 	SupersededFieldValuesFilePath     = tablesFolderSuffix + "/superseded_field_values.csv"
 	EntryDoiAliasesFilePath       = tablesFolderSuffix + "/entry_doi_aliases.csv"
+	EntryVenuesFilePath           = tablesFolderSuffix + "/entry_venues.csv"
 	FieldMappingsFilePath         = tablesFolderSuffix + "/field_mappings.csv"
 	StateNamesFilePath            = tablesFolderSuffix + "/state_names.csv"
 	StateCountriesFilePath        = tablesFolderSuffix + "/state_countries.csv"
@@ -69,6 +71,7 @@ const (
 	KeyOldiesFilePath             = tablesFolderSuffix + "/key_oldies.csv"
 	KeyHintsFilePath              = tablesFolderSuffix + "/key_hints.csv"
 	ScriptFilePath                = scriptsFolderSuffix + "/entry_actions"
+	ConflictPolicyFilePath        = scriptsFolderSuffix + "/conflict_policy.json"
 	DblpParentFilePath            = tablesFolderSuffix + "/dblp_parent.csv"
 	DblpWaivedFilePath            = tablesFolderSuffix + "/dblp_waived.csv"
 	EntryMetadataFilePath         = tablesFolderSuffix + "/entry_metadata.csv"
@@ -80,10 +83,10 @@ const (
 	FoldersCSVFilePath            = tablesFolderSuffix + "/folders.csv"
 	ConfigCSVFilePath             = tablesFolderSuffix + "/config.csv"
 
-	URLsFailedFilePath = "urls_failed.csv"
-
 	DefaultLangIDConfigKey     = "default_langid"
 	DefaultLangIDFallback      = "english"
+	AliasTemplateConfigKey     = "alias_template" // see bibtex_library_alias_template.go
+	AliasSuffixConfigKey       = "alias_suffix"
 )
 
 // Entry flag values stored in entry_flags.csv / the entry_flags SQLite table.
@@ -104,6 +107,10 @@ const (
 	LocalURLField       = "local-url"
 
 	FlagLoneProceedingsWaived = "lone-proceedings-waived"
+
+	// FlagLintWaivedPrefix, followed by a rule name, waives that entry_actions
+	// lint rule for one entry, e.g. "lint-waived:article-doi".
+	FlagLintWaivedPrefix = "lint-waived:"
 )
 
 // AddRequiredEntryFields marks fields as required (must-not-be-empty) for the given entry type.
@@ -12,8 +12,9 @@
  * The .settings file holds the settings that must be known before the DB can be
  * opened (global_folder, cache_folder) plus a couple of standalone display knobs
  * (display_file, display_command) that have no DB to live in yet at the point
- * they're needed. All other settings (key_prefix, csv_delimiter, backup_folder)
- * live in the DB config table.
+ * they're needed, the structured pdf_providers chain of -get_pdfs, and the event
+ * hooks (see bibtex_library_hooks.go). All other
+ * settings (key_prefix, csv_delimiter, backup_folder) live in the DB config table.
  *
  * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
  *
@@ -47,6 +48,12 @@ type TBibTeXFolders struct {
 	// DisplayCommand opens DisplayFile in an external, auto-refreshing viewer. Run
 	// once per bib_check invocation, the first time displayContent() is called.
 	DisplayCommand string `json:"display_command,omitempty"`
+	// PDFProviders is the chain of open-access PDF sources tried by -get_pdfs
+	// (see bibtex_library_pdf_providers.go).
+	PDFProviders []TPDFProvider `json:"pdf_providers,omitempty"`
+	// Hooks run entry_actions rules or external commands on library events
+	// (see bibtex_library_hooks.go).
+	Hooks []THook `json:"hooks,omitempty"`
 }
 
 var (
@@ -192,6 +199,9 @@ func loadBibTeXFolders(settingsPath string) {
 		displayFile = expandHome(f.DisplayFile)
 	}
 	displayCommand = f.DisplayCommand
+	pdfProviders = f.PDFProviders
+	validateHooks(f.Hooks)
+	configuredHooks = f.Hooks
 
 	// key_prefix is no longer stored in .settings. If it is still absent after
 	// reading the legacy field, it will be prompted and written to the DB config
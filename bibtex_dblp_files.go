@@ -7,7 +7,7 @@
  *
  * File-based DBLP store under ~/BiBTeX.Generics/DBLP/.
  * Each entry is entries/<dblp_key>/data.json; title lookups use
- * hash-prefixed link.txt files under titles/.
+ * hash-prefixed link.txt files under titles/, DOI lookups those under dois/.
  * Text values are stored verbatim from the XML; LaTeX conversion via
  * dblpRawToLaTeX happens at read time.
  *
@@ -126,6 +126,12 @@ func dblpTitleLinkPath(hash string) string {
 	return dblpFolder() + "titles/" + hash[0:2] + "/" + hash[2:4] + "/" + hash + "/link.txt"
 }
 
+// dblpDOILinkPath returns the path of the link.txt for a given DOI hash, using the
+// same prefix structure as dblpTitleLinkPath.
+func dblpDOILinkPath(hash string) string {
+	return dblpFolder() + "dois/" + hash[0:2] + "/" + hash[2:4] + "/" + hash + "/link.txt"
+}
+
 // dblpCrossrefChildrenPath returns the path of the children.txt for a given
 // parent DBLP key. The key is used directly as a path component (same convention
 // as entries/), making the index human-navigable.
@@ -203,6 +209,28 @@ func libraryTitleHash(latexTitle string) string {
 	return hex.EncodeToString(h[:])
 }
 
+// dblpDOIHash returns the MD5 hex digest of a normalised DOI. Returns "" for
+// empty DOIs.
+func dblpDOIHash(doi string) string {
+	doi = normalizeDOI(strings.TrimSpace(doi))
+	if doi == "" {
+		return ""
+	}
+	h := md5.Sum([]byte(doi))
+	return hex.EncodeToString(h[:])
+}
+
+// dblpEntryDOIs returns the DOIs among the ee links of a DBLP entry.
+func dblpEntryDOIs(je *TDblpJSONEntry) []string {
+	var dois []string
+	for _, ee := range je.Multi["ee"] {
+		if normalised := NormaliseURLValue(nil, ee); strings.HasPrefix(normalised, "https://doi.org/") {
+			dois = append(dois, strings.TrimPrefix(normalised, "https://doi.org/"))
+		}
+	}
+	return dois
+}
+
 // --- JSON types ---
 
 // TDblpJSONPerson is the JSON representation of an author or editor.
@@ -412,6 +440,15 @@ func readIndexFile(path string) []string {
 	return lines
 }
 
+// writeDblpDOILink appends dblpKey to the link.txt for the given DOI.
+func writeDblpDOILink(doi, dblpKey string) error {
+	hash := dblpDOIHash(doi)
+	if hash == "" {
+		return nil
+	}
+	return appendToIndexFile(dblpDOILinkPath(hash), dblpKey)
+}
+
 // readDblpCrossrefChildren returns the DBLP child keys for a given parent key.
 func readDblpCrossrefChildren(parentKey string) []string {
 	if parentKey == "" {
@@ -446,6 +483,15 @@ func readDblpTitleLinks(hash string) []string {
 	return readIndexFile(dblpTitleLinkPath(hash))
 }
 
+// readDblpDOILinks returns the DBLP keys of the entries with the given DOI.
+func readDblpDOILinks(doi string) []string {
+	hash := dblpDOIHash(doi)
+	if hash == "" {
+		return nil
+	}
+	return readIndexFile(dblpDOILinkPath(hash))
+}
+
 func readDblpMeta() *TDblpMeta {
 	data, err := os.ReadFile(dblpFolder() + "meta.json")
 	if err != nil {
@@ -521,7 +567,7 @@ func pruneEmptyDblpDirs(dir string) {
 }
 
 // removeKeyFromAllIndexes removes dblpKey from every index file it contributed to,
-// using the provided JSON entry to compute title/crossref/person/ORCID hashes.
+// using the provided JSON entry to compute title/DOI/crossref/person/ORCID hashes.
 func removeKeyFromAllIndexes(dblpKey string, je *TDblpJSONEntry) {
 	if je == nil {
 		return
@@ -536,6 +582,12 @@ func removeKeyFromAllIndexes(dblpKey string, je *TDblpJSONEntry) {
 			}
 		}
 	}
+	// DOI index
+	for _, doi := range dblpEntryDOIs(je) {
+		if hash := dblpDOIHash(doi); hash != "" {
+			removeKeyFromIndexFile(dblpDOILinkPath(hash), dblpKey)
+		}
+	}
 	// Crossref children index
 	if je.Fields != nil {
 		if parentKey := je.Fields["crossref"]; parentKey != "" {
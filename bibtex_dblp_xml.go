@@ -287,13 +287,13 @@ func pct(n, total int) int {
 }
 
 // doRebuildDblpTitleIndex walks every data.json in the file store and rebuilds the
-// title index in place. Much faster than a full XML re-import; no XML file required.
+// title index, and the DOI index next to it, in place. Much faster than a full XML re-import; no XML file required.
 // Useful when the title index is incomplete (e.g. after entries were fetched on-demand
 // without being indexed, or after -repair_dblp_manifest cleared the index).
 func doRebuildDblpTitleIndex() {
 	entriesRoot := dblpFolder() + "entries/"
 
-	// Trash the existing title index so we start clean.
+	// Trash the existing title and DOI indexes so we start clean.
 	titlesDir := dblpFolder() + "titles/"
 	if _, err := os.Stat(titlesDir); err == nil {
 		fmt.Fprintf(os.Stderr, "Moving old title index to trash...\n")
@@ -301,6 +301,13 @@ func doRebuildDblpTitleIndex() {
 			fmt.Fprintf(os.Stderr, "Warning: could not move old title index to trash: %s\n", err)
 		}
 	}
+	doisDir := dblpFolder() + "dois/"
+	if _, err := os.Stat(doisDir); err == nil {
+		fmt.Fprintf(os.Stderr, "Moving old DOI index to trash...\n")
+		if err := moveToDblpTrash(doisDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not move old DOI index to trash: %s\n", err)
+		}
+	}
 
 	total := 0
 	if meta := readDblpMeta(); meta != nil {
@@ -316,6 +323,7 @@ func doRebuildDblpTitleIndex() {
 	// appendToIndexFile. Writing all link files in one batch at the end eliminates
 	// the duplicate-check read on each entry and avoids cache-thrashing on large stores.
 	titleLinks := make(map[string][]string, 1<<20)
+	doiLinks := make(map[string][]string, 1<<20)
 
 	var count, errors int
 	start := time.Now()
@@ -342,6 +350,11 @@ func doRebuildDblpTitleIndex() {
 				}
 			}
 		}
+		for _, doi := range dblpEntryDOIs(je) {
+			if hash := dblpDOIHash(doi); hash != "" {
+				doiLinks[hash] = append(doiLinks[hash], dblpKey)
+			}
+		}
 		count++
 		if now := time.Now(); now.Sub(lastReport) >= 5*time.Second {
 			if total > 0 {
@@ -383,6 +396,24 @@ func doRebuildDblpTitleIndex() {
 		fmt.Fprintf(os.Stderr, "  %d link files written.\n", written)
 	}
 
+	if err == nil && len(doiLinks) > 0 {
+		fmt.Fprintf(os.Stderr, "Writing %d DOI link files...\n", len(doiLinks))
+		written := 0
+		for hash, keys := range doiLinks {
+			path := dblpDOILinkPath(hash)
+			if mkErr := os.MkdirAll(filepath.Dir(path), 0755); mkErr != nil {
+				errors++
+				continue
+			}
+			if wErr := os.WriteFile(path, []byte(strings.Join(keys, "\n")+"\n"), 0644); wErr != nil {
+				errors++
+			} else {
+				written++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "  %d DOI link files written.\n", written)
+	}
+
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error walking file store: %s\n", err)
 		os.Exit(1)
@@ -1091,6 +1122,11 @@ outer:
 			}
 		}
 
+		// Write DOI link files.
+		for _, doi := range dblpEntryDOIs(je) {
+			writeDblpDOILink(doi, dblpKey) // non-fatal
+		}
+
 		// Write person indexes: one entry per author/editor, keyed by name and ORCID.
 		for _, p := range je.Authors {
 			writeDblpPersonEntry(p.Name, dblpKey) // non-fatal
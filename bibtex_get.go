@@ -63,11 +63,18 @@ type TBibGetConfig struct {
 	PDFFiles            string   `json:"pdf_files"`        // subset/full: "" | "global" | "local"
 	Format              string   `json:"format"`           // output dialect: "bibdesk" (default) | "jabref"
 	SyncGroups          []string `json:"groups"`           // group names to sync to main DB; all others stay local
+	AliasTemplate       string   `json:"alias_template"`   // local keys of new entries from this template instead of the preferred alias; see bibtex_library_alias_template.go
+	AliasSuffix         string   `json:"alias_suffix"`     // collision suffix for alias_template: "letters" | "numbers" ("" = the library's)
+	VenueFields         bool     `json:"venue_fields"`     // proceedings: add the parsed venue as venue/eventdate (biber_mode) or address
 
 	// Runtime-only (not serialised): all group assignments per canonical key, pre-built
 	// from the .sync state before the write phase. When non-nil, entryGetString uses this
 	// instead of Library.GroupEntries, so local (non-managed) groups are emitted too.
 	entryGroups map[string][]string
+
+	// Runtime-only: directory of the output bib file, against which BibDesk
+	// bdsk-file-N paths are made relative.
+	outputDir string
 }
 
 // migrateRawConfigFileNames migrates "file_name" → "file_names" in a raw JSON map.
@@ -137,6 +144,9 @@ func readBibGetConfig() (TBibGetConfig, bool) {
 		{"pdf_files", json.RawMessage(`""`)},
 		{"format", json.RawMessage(`"bibdesk"`)},
 		{"groups", json.RawMessage(`[]`)},
+		{"alias_template", json.RawMessage(`""`)},
+		{"alias_suffix", json.RawMessage(`""`)},
+		{"venue_fields", json.RawMessage(`false`)},
 	} {
 		if _, present := rawMap[opt.key]; !present {
 			rawMap[opt.key] = opt.val
@@ -396,16 +406,17 @@ func rewriteKeysFile(fileName string, pairs []TBibGetPair, keyMapping bool) {
 
 // TSelectStatement is one parsed statement from a .select file.
 type TSelectStatement struct {
-	Kind   string   // "group", "groups", "name", "orcid", "contributor", "has_pdf", "only_these", "watched", "dblp_waived"
+	Kind   string   // "group", "groups", "name", "orcid", "contributor", "where", "has_pdf", "only_these", "watched", "dblp_waived"
 	Values []string // one or more quoted values (empty for bare-keyword operators)
 }
 
 // readSelectFile parses <fileName>.select. Each statement is:
 //
-//	group   "name";
+//	group   "name";           (static or smart group; glob patterns allowed)
 //	groups  "name1" "name2";
 //	name    "Canonical Author Name";
 //	orcid   "0000-0001-2345-6789";
+//	where   <query>;  (the -find_entries query language, e.g. where type=article and year>=2020;)
 //	has_pdf;          (bare keyword — no values)
 //	only_these;       (bare keyword — no values)
 //
@@ -423,6 +434,14 @@ func readSelectFile(fileName string) ([]TSelectStatement, bool) {
 		if line == "" || strings.HasPrefix(line, "#") {
 			return
 		}
+		if query, isQuery := strings.CutPrefix(line, "where "); isQuery {
+			if _, err := ParseQuery(query); err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: %s: invalid query %q: %s\n", selectPath, query, err)
+				return
+			}
+			stmts = append(stmts, TSelectStatement{"where", []string{query}})
+			return
+		}
 		idx := strings.IndexByte(line, '"')
 		if idx < 0 {
 			// Bare-keyword operators (no quoted values).
@@ -496,6 +515,14 @@ func expandSelectStmts(stmts []TSelectStatement, alreadyIncluded map[string]bool
 						add(resolved)
 					}
 				}
+				// Smart groups (BibDesk smart / JabRef search groups) select by condition.
+				for _, grp := range Library.SmartGroupNames() {
+					if matched, err := filepath.Match(pattern, grp); err == nil && matched {
+						for _, key := range Library.SmartGroupMembers(grp) {
+							add(key)
+						}
+					}
+				}
 			}
 		case "name":
 			for _, name := range s.Values {
@@ -531,6 +558,13 @@ func expandSelectStmts(stmts []TSelectStatement, alreadyIncluded map[string]bool
 					add(resolved)
 				}
 			}
+		case "where":
+			for _, query := range s.Values {
+				keys, _ := Library.QueryEntryKeys(query) // validated by readSelectFile
+				for _, key := range keys {
+					add(key)
+				}
+			}
 		case "has_pdf":
 			for key := range Library.PDFFiles {
 				if resolved := Library.MapEntryKey(key); resolved != "" {
@@ -912,35 +946,11 @@ func (l *TBibTeXLibrary) entryGetString(
 			}
 		}
 
-		// local-url / file: derived from PDFFiles map, never stored in DB.
-		// BibDesk format emits local-url; JabRef format emits file = {:path:PDF}.
+		// local-url / file / bdsk-file-N: derived from PDFFiles and Attachments, never stored in DB.
+		// BibDesk format emits local-url plus bdsk-file-N; JabRef format emits file = {:path:PDF;…}.
 		if field == LocalURLField {
-			if cfg.PDFFiles != "" && l.PDFFiles[canonicalKey] {
-				if cfg.Format == "jabref" {
-					var pdfPath string
-					switch cfg.PDFFiles {
-					case "global":
-						pdfPath = l.FilesRoot + l.FilesFolder + canonicalKey + ".pdf"
-					case "local":
-						if localFilesDir != "" {
-							// Relative path from bib directory: <stem>.files/<outputKey>.pdf
-							pdfPath = filepath.Base(strings.TrimSuffix(localFilesDir, string(filepath.Separator))) +
-								string(filepath.Separator) + outputKey + ".pdf"
-						}
-					}
-					if pdfPath != "" {
-						result += FormatBibTeXFieldAssignment("", "file", ":"+jabrefEscapePath(pdfPath)+":PDF")
-					}
-				} else {
-					switch cfg.PDFFiles {
-					case "global":
-						result += FormatBibTeXFieldAssignment("", field, l.FilesRoot+l.FilesFolder+canonicalKey+".pdf")
-					case "local":
-						if localFilesDir != "" {
-							result += FormatBibTeXFieldAssignment("", field, localFilesDir+outputKey+".pdf")
-						}
-					}
-				}
+			if cfg.PDFFiles != "" {
+				result += l.entryFileFields(canonicalKey, outputKey, localFilesDir, cfg)
 			}
 			continue
 		}
@@ -994,6 +1004,10 @@ func (l *TBibTeXLibrary) entryGetString(
 		result += FormatBibTeXFieldAssignment("", "note", urldateNote)
 	}
 
+	if cfg.VenueFields {
+		result += l.venueFieldAssignments(entry, cfg)
+	}
+
 	// JabRef format: emit groups = {Group A, Group B} for each group this entry belongs to.
 	// When cfg.entryGroups is set (subset sync with .sync DB populated), emit all groups
 	// (managed + local) from that map. Otherwise fall back to Library.GroupEntries for
@@ -1064,6 +1078,12 @@ func writePullSync(cfg TBibGetConfig, baseDir string) []TBibGetPair {
 	// Bare keys (localKey == "") are resolved to their preferred alias when
 	// key_mapping is on; in no-mapping mode bare keys stay as canonical keys.
 	resolvedSeen := map[string]string{} // canonical → first local key seen
+	localInUse := map[string]bool{}     // local keys taken in this file, for alias_template collisions
+	for _, p := range pairs {
+		if p.localKey != "" {
+			localInUse[p.localKey] = true
+		}
+	}
 	for i, p := range pairs {
 		resolved := Library.MapEntryKey(p.canonicalKey)
 		if resolved == p.canonicalKey {
@@ -1093,7 +1113,7 @@ func writePullSync(cfg TBibGetConfig, baseDir string) []TBibGetPair {
 			// preferred alias so the output uses the human-readable form.
 			localKey := p.canonicalKey
 			if localKey == resolved {
-				if preferred := Library.PreferredKey(resolved); preferred != "" {
+				if preferred := syncFileAlias(cfg, resolved, localInUse); preferred != "" {
 					localKey = preferred
 				}
 			}
@@ -1210,7 +1230,7 @@ func writePullSync(cfg TBibGetConfig, baseDir string) []TBibGetPair {
 	for _, canonical := range extraCanonicals {
 		outputKey := canonical
 		if cfg.KeyMapping {
-			if preferred := Library.PreferredKey(canonical); preferred != "" {
+			if preferred := syncFileAlias(cfg, canonical, localInUse); preferred != "" {
 				outputKey = preferred
 			}
 		}
@@ -1258,7 +1278,7 @@ func writePullSync(cfg TBibGetConfig, baseDir string) []TBibGetPair {
 		}
 		localKey := resolvedCrossref
 		if cfg.KeyMapping {
-			if preferred := Library.PreferredKey(resolvedCrossref); preferred != "" {
+			if preferred := syncFileAlias(cfg, resolvedCrossref, localInUse); preferred != "" {
 				localKey = preferred
 			}
 		}
@@ -1279,6 +1299,7 @@ func writePullSync(cfg TBibGetConfig, baseDir string) []TBibGetPair {
 
 	// Write output file.
 	outPath := resolveRelative(cfg.FileName + BibFileExtension)
+	cfg.outputDir = filepath.Dir(outPath)
 
 	// Compute local files dir and sync PDFs before writing (pdf_files="local").
 	localFilesDir := ""
@@ -1583,6 +1604,7 @@ func enforceInfoPolicy(cfg TBibGetConfig) TBibGetConfig {
 		cfg.UrldateAsNote = false
 		cfg.BiberMode = false
 		cfg.Shorten = false
+		cfg.VenueFields = false
 	}
 	return cfg
 }
@@ -1970,6 +1992,16 @@ func doSync(filter string) {
 				break
 			}
 		}
+		// The DB side of the sync is done: after_sync hooks run now, so their
+		// changes are included in the bib files written by phase 2.
+		if !Library.QuitWasRequested() {
+			var synced []string
+			for _, f := range files {
+				synced = append(synced, f.cfg.FileName)
+			}
+			Library.fireHook(THookEvent{Event: HookAfterSync, Files: synced})
+			Library.flushHooks()
+		}
 	}
 
 	// Phase 2: write all output bib files from the (now fully updated) DB.
@@ -0,0 +1,645 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - homework
+ *   - bibtex_homework_tui
+ *
+ * Full-screen terminal UI for homework triage (-homework -tui).
+ *
+ * All pending items are listed in one queue: potential duplicate pairs (the
+ * pairs behind countUnresolvedGroups), DBLP candidates (countDblpCandidates),
+ * superseded author/editor values and ambiguous contributor names. The selected
+ * item is shown with its candidate records side by side, differing fields marked.
+ *
+ * Input is line based (stdin is read line by line, see readStdinLine):
+ *
+ *	n / p / <enter>   next / previous item
+ *	j N               jump to item N of the (filtered) list
+ *	/ text            only list items mentioning text ("/" alone clears)
+ *	f kind            only list items of one kind ("f" alone clears)
+ *	s                 skip: the item stays in the queue, marked as skipped
+ *	q                 quit
+ *
+ * plus the decisions of the item's kind, shown in the command line. Decisions are
+ * applied through the same library calls as the linear homework; any question a
+ * decision raises is asked on the normal screen.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Homework item kinds.
+const (
+	HomeworkDuplicate   = "duplicate"
+	HomeworkDblp        = "dblp"
+	HomeworkSuperseded  = "superseded"
+	HomeworkContributor = "contributor"
+)
+
+// Terminal control sequences.
+const (
+	tuiEnterScreen = "\033[?1049h"
+	tuiLeaveScreen = "\033[?1049l"
+	tuiClearScreen = "\033[H\033[2J"
+	tuiReverse     = "\033[7m"
+	tuiBold        = "\033[1m"
+	tuiPlain       = "\033[0m"
+)
+
+// THomeworkItem is one pending homework decision.
+type THomeworkItem struct {
+	Kind       string
+	Keys       []string // duplicate: the pair; dblp, superseded: the entry
+	Field      string   // superseded: author or editor
+	Value      string   // dblp: the DBLP key; superseded: the superseded value; contributor: the ambiguous name
+	Candidates []string // contributor: the contributor ids the name is an alias for
+	Skipped    bool
+}
+
+// THomeworkColumn is one record of the side-by-side view.
+type THomeworkColumn struct {
+	Title  string
+	Fields map[string]string
+}
+
+// THomeworkTUI is the state of a -homework -tui session.
+type THomeworkTUI struct {
+	items   []*THomeworkItem
+	visible []*THomeworkItem // items passing the filters
+	current int              // index in visible
+	filter  string           // "/ text"
+	kind    string           // "f kind"
+	status  string           // feedback on the last command
+	decided int
+}
+
+// homeworkKindDecisions gives the decisions per kind, as shown in the command line.
+var homeworkKindDecisions = map[string]string{
+	HomeworkDuplicate:   "m=merge right into left, M=merge left into right, d=different",
+	HomeworkDblp:        "a=accept DBLP key, r=reject",
+	HomeworkSuperseded:  "a=adopt superseded value, k=keep current",
+	HomeworkContributor: "m N=merge all into candidate N",
+}
+
+// doHomeworkTUI runs the homework triage in a full-screen terminal UI. Without a
+// terminal, or when replaying answers, it runs the linear homework instead.
+func doHomeworkTUI() {
+	if !isTTY || answersReplaying() {
+		Reporting.Warning(WarningHomeworkTUINoTerminal)
+		doHomework()
+		return
+	}
+	if !openLibraryToUpdate() {
+		return
+	}
+	Library.ReadKeyNonDoublesFile()
+
+	t := &THomeworkTUI{items: collectHomeworkItems()}
+	t.applyFilters()
+	fmt.Fprint(os.Stderr, tuiEnterScreen)
+	for !Library.QuitWasRequested() {
+		t.render()
+		line, ok := <-stdinCh
+		if !ok || !t.command(strings.TrimSpace(line)) {
+			break
+		}
+	}
+	fmt.Fprint(os.Stderr, tuiLeaveScreen)
+
+	skipped := 0
+	for _, it := range t.items {
+		if it.Skipped {
+			skipped++
+		}
+	}
+	Library.Progress(ProgressHomeworkTUIDone, t.decided, skipped, len(t.items))
+}
+
+// collectHomeworkItems lists all pending homework items, grouped by kind.
+func collectHomeworkItems() []*THomeworkItem {
+	items := append(collectDuplicateItems(), collectDblpCandidateItems()...)
+
+	rows, err := bibQuery(`SELECT entry_key, field, value FROM superseded_field_values WHERE field IN ('author', 'editor') AND triage_status IS NULL ORDER BY entry_key, field`)
+	if err != nil {
+		Library.Warning("Could not query superseded_field_values: %s", err)
+	} else {
+		for rows.Next() {
+			it := &THomeworkItem{Kind: HomeworkSuperseded, Keys: make([]string, 1)}
+			if rows.Scan(&it.Keys[0], &it.Field, &it.Value) == nil {
+				items = append(items, it)
+			}
+		}
+		rows.Close()
+	}
+
+	var names []string
+	for name, ids := range Library.AmbiguousNameToContributorIDs {
+		if len(ids) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		items = append(items, &THomeworkItem{Kind: HomeworkContributor, Value: name, Candidates: Library.AmbiguousNameToContributorIDs[name]})
+	}
+	return items
+}
+
+// collectDuplicateItems lists the potential duplicate pairs of the title index
+// that are neither non-doubles nor evidently different entries.
+func collectDuplicateItems() []*THomeworkItem {
+	var duplicates []*THomeworkItem
+	for _, keys := range Library.TitleIndex {
+		var canonicals []string
+		for _, k := range keys.ElementsSorted() {
+			if k == Library.MapEntryKey(k) {
+				canonicals = append(canonicals, k)
+			}
+		}
+		for i, a := range canonicals {
+			for _, b := range canonicals[i+1:] {
+				it := &THomeworkItem{Kind: HomeworkDuplicate, Keys: []string{a, b}}
+				if it.pending() {
+					duplicates = append(duplicates, it)
+				}
+			}
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return strings.Join(duplicates[i].Keys, " ") < strings.Join(duplicates[j].Keys, " ")
+	})
+	return duplicates
+}
+
+// collectDblpCandidateItems lists the unresolved DBLP candidates of entries
+// without a DBLP key (those counted by countDblpCandidates), one item per candidate.
+func collectDblpCandidateItems() []*THomeworkItem {
+	var candidates []*THomeworkItem
+	forEachBibEntryKey(func(key string) bool {
+		if Library.EntryFieldValueity(key, DBLPField) != "" {
+			return true
+		}
+		hash := libraryTitleHash(Library.EntryFieldValueity(key, TitleField))
+		if hash == "" {
+			return true
+		}
+		existing := Library.NonDoubleEntries[key]
+		for _, c := range readDblpTitleLinks(hash) {
+			if !existing.Set().Contains(KeyForDBLP(c)) {
+				candidates = append(candidates, &THomeworkItem{Kind: HomeworkDblp, Keys: []string{key}, Value: c})
+			}
+		}
+		return true
+	})
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Keys[0] < candidates[j].Keys[0] })
+	return candidates
+}
+
+// pending reports whether the item still needs a decision, after decisions on
+// other items (merges in particular) may have resolved it. Entry keys are
+// updated to their current canonical keys.
+func (it *THomeworkItem) pending() bool {
+	for i, key := range it.Keys {
+		it.Keys[i] = Library.MapEntryKey(key)
+		if !Library.EntryExists(it.Keys[i]) {
+			return false
+		}
+	}
+	switch it.Kind {
+	case HomeworkDuplicate:
+		a, b := it.Keys[0], it.Keys[1]
+		return a != b && !Library.NonDoubleEntries[a].Set().Contains(b) && !Library.EvidenceForBeingDifferentEntries(a, b)
+	case HomeworkDblp:
+		key := it.Keys[0]
+		return Library.EntryFieldValueity(key, DBLPField) == "" &&
+			!Library.NonDoubleEntries[key].Set().Contains(Library.MapEntryKey(KeyForDBLP(it.Value)))
+	case HomeworkSuperseded:
+		var n int
+		bibQueryRow(`SELECT COUNT(*) FROM superseded_field_values WHERE entry_key=? AND field=? AND value=? AND triage_status IS NULL`, it.Keys[0], it.Field, it.Value).Scan(&n)
+		return n > 0
+	case HomeworkContributor:
+		var live []string
+		for _, id := range it.Candidates {
+			if Library.ContributorByID[id] != nil {
+				live = append(live, id)
+			}
+		}
+		it.Candidates = live
+		return len(live) > 1
+	}
+	return false
+}
+
+// label is the one-line description of the item in the list pane.
+func (it *THomeworkItem) label() string {
+	switch it.Kind {
+	case HomeworkDuplicate:
+		return it.Keys[0] + " ↔ " + it.Keys[1] + "  " + Library.EntryFieldValueity(it.Keys[0], TitleField)
+	case HomeworkDblp:
+		return it.Keys[0] + " → " + KeyForDBLP(it.Value) + "  " + Library.EntryFieldValueity(it.Keys[0], TitleField)
+	case HomeworkSuperseded:
+		return it.Keys[0] + " " + it.Field + ": " + it.Value
+	case HomeworkContributor:
+		return fmt.Sprintf("%q is an alias for %d contributors", it.Value, len(it.Candidates))
+	}
+	return ""
+}
+
+// columns returns the records to show side by side, and the order of their rows.
+func (it *THomeworkItem) columns() ([]THomeworkColumn, []string) {
+	switch it.Kind {
+	case HomeworkDuplicate:
+		left, right := loadEntryFromDb(it.Keys[0]).Fields, loadEntryFromDb(it.Keys[1]).Fields
+		return []THomeworkColumn{{it.Keys[0], left}, {it.Keys[1], right}}, homeworkFieldOrder(left, right)
+	case HomeworkDblp:
+		left := loadEntryFromDb(it.Keys[0]).Fields
+		right := map[string]string{}
+		if e := dblpEntryFromFile(it.Value); e != nil {
+			right = e.Fields
+		}
+		return []THomeworkColumn{{it.Keys[0], left}, {KeyForDBLP(it.Value), right}}, homeworkFieldOrder(left, right)
+	case HomeworkSuperseded:
+		// One row per name, so a single differing name stands out.
+		current, superseded := map[string]string{}, map[string]string{}
+		var rows []string
+		currentNames := strings.Split(Library.EntryFieldValueity(it.Keys[0], it.Field), " and ")
+		supersededNames := strings.Split(it.Value, " and ")
+		for i := 0; i < len(currentNames) || i < len(supersededNames); i++ {
+			row := fmt.Sprintf("%s %d", it.Field, i+1)
+			rows = append(rows, row)
+			if i < len(currentNames) {
+				current[row] = strings.TrimSpace(currentNames[i])
+			}
+			if i < len(supersededNames) {
+				superseded[row] = strings.TrimSpace(supersededNames[i])
+			}
+		}
+		return []THomeworkColumn{{it.Keys[0] + " (current)", current}, {"superseded", superseded}}, rows
+	case HomeworkContributor:
+		var columns []THomeworkColumn
+		for i, id := range it.Candidates {
+			cand := Library.ContributorByID[id]
+			if cand == nil {
+				// Merged away since the queue was built; pending() drops the item.
+				columns = append(columns, THomeworkColumn{fmt.Sprintf("%d: %s", i+1, id), map[string]string{"name": "(no longer exists)"}})
+				continue
+			}
+			var entryCount int
+			bibQueryRow(`SELECT COUNT(*) FROM contributor_roles WHERE contributor_id = ?`, id).Scan(&entryCount) //nolint:errcheck
+			columns = append(columns, THomeworkColumn{fmt.Sprintf("%d: %s", i+1, id), map[string]string{
+				"name":    cand.Name,
+				"orcid":   cand.ORCID,
+				"dblp":    cand.DblpKey,
+				"entries": strconv.Itoa(entryCount),
+			}})
+		}
+		return columns, []string{"name", "orcid", "dblp", "entries"}
+	}
+	return nil, nil
+}
+
+// homeworkFieldOrder lists the fields of two entries: the identifying ones
+// first, then the others alphabetically.
+func homeworkFieldOrder(left, right map[string]string) []string {
+	leading := []string{EntryTypeField, TitleField, "author", "editor", "year", DBLPField, "doi"}
+	seen := TStringSetNew()
+	var fields []string
+	for _, f := range leading {
+		if left[f] != "" || right[f] != "" {
+			fields = append(fields, f)
+		}
+		seen.Add(f)
+	}
+	var rest []string
+	for _, m := range []map[string]string{left, right} {
+		for f, v := range m {
+			if v != "" && !seen.Contains(f) {
+				seen.Add(f)
+				rest = append(rest, f)
+			}
+		}
+	}
+	sort.Strings(rest)
+	return append(fields, rest...)
+}
+
+// applyFilters recomputes the visible items, keeping the selection where possible.
+func (t *THomeworkTUI) applyFilters() {
+	var selected *THomeworkItem
+	if t.current < len(t.visible) {
+		selected = t.visible[t.current]
+	}
+	needle := strings.ToLower(t.filter)
+	t.visible = nil
+	t.current = 0
+	for _, it := range t.items {
+		if t.kind != "" && it.Kind != t.kind {
+			continue
+		}
+		if needle != "" && !strings.Contains(strings.ToLower(it.Kind+" "+it.label()), needle) {
+			continue
+		}
+		if it == selected {
+			t.current = len(t.visible)
+		}
+		t.visible = append(t.visible, it)
+	}
+}
+
+// prune drops the items that no longer need a decision.
+func (t *THomeworkTUI) prune() {
+	var items []*THomeworkItem
+	for _, it := range t.items {
+		if it.pending() {
+			items = append(items, it)
+		}
+	}
+	t.items = items
+	t.applyFilters()
+}
+
+// command handles one input line; it returns false to end the session.
+func (t *THomeworkTUI) command(line string) bool {
+	t.status = ""
+	verb, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(line, "/") {
+		verb, arg = "/", strings.TrimSpace(line[1:])
+	}
+	switch verb {
+	case "q":
+		return false
+	case "", "n":
+		if t.current < len(t.visible)-1 {
+			t.current++
+		}
+		return true
+	case "p":
+		if t.current > 0 {
+			t.current--
+		}
+		return true
+	case "j":
+		if n, err := strconv.Atoi(arg); err == nil && n >= 1 && n <= len(t.visible) {
+			t.current = n - 1
+		} else {
+			t.status = fmt.Sprintf("No item %q", arg)
+		}
+		return true
+	case "/":
+		t.filter = arg
+		t.applyFilters()
+		return true
+	case "f":
+		if arg != "" && homeworkKindDecisions[arg] == "" {
+			t.status = fmt.Sprintf("Unknown kind %q", arg)
+			return true
+		}
+		t.kind = arg
+		t.applyFilters()
+		return true
+	}
+	if t.current >= len(t.visible) {
+		t.status = fmt.Sprintf("Unknown command %q", line)
+		return true
+	}
+	it := t.visible[t.current]
+	if verb == "s" {
+		it.Skipped = true
+		if t.current < len(t.visible)-1 {
+			t.current++
+		}
+		return true
+	}
+	if !it.validDecision(verb, arg) {
+		t.status = fmt.Sprintf("Unknown command %q (%s)", line, homeworkKindDecisions[it.Kind])
+		return true
+	}
+
+	// Library calls may report progress or ask questions: leave the full screen.
+	fmt.Fprint(os.Stderr, tuiLeaveScreen)
+	Library.ResetQuestionFlag()
+	it.decide(verb, arg)
+	t.decided++
+	t.prune()
+	fmt.Fprint(os.Stderr, tuiEnterScreen)
+	return true
+}
+
+// validDecision reports whether verb (with arg) is a decision for the item's kind.
+func (it *THomeworkItem) validDecision(verb, arg string) bool {
+	switch it.Kind {
+	case HomeworkDuplicate:
+		return arg == "" && (verb == "m" || verb == "M" || verb == "d")
+	case HomeworkDblp:
+		return arg == "" && (verb == "a" || verb == "r")
+	case HomeworkSuperseded:
+		return arg == "" && (verb == "a" || verb == "k")
+	case HomeworkContributor:
+		n, err := strconv.Atoi(arg)
+		return verb == "m" && err == nil && n >= 1 && n <= len(it.Candidates)
+	}
+	return false
+}
+
+// decide applies a decision through the same library calls as the linear homework.
+func (it *THomeworkItem) decide(verb, arg string) {
+	switch it.Kind {
+	case HomeworkDuplicate:
+		switch verb {
+		case "m", "M":
+			source, target := it.Keys[1], it.Keys[0]
+			if verb == "M" {
+				source, target = target, source
+			}
+			Library.MergeDecidedEntries(source, target, func(sourceYear, targetYear string) bool {
+				if Library.ConfirmMergeDespiteYears(source, target, sourceYear, targetYear) {
+					return true
+				}
+				if !Library.QuitWasRequested() {
+					Library.AddNonDoubleEntries(source, target)
+				}
+				return false
+			})
+		case "d":
+			Library.AddNonDoubleEntries(it.Keys[0], it.Keys[1])
+		}
+	case HomeworkDblp:
+		switch verb {
+		case "a":
+			Library.AssociateDblpKey(it.Keys[0], it.Value)
+			doAllChecks(Library.MapEntryKey(it.Keys[0]))
+		case "r":
+			Library.AddNonDoubleEntries(it.Keys[0], KeyForDBLP(it.Value))
+			flushWorkingDbToHome()
+		}
+	case HomeworkSuperseded:
+		switch verb {
+		case "a":
+			Library.SetEntryFieldValue(it.Keys[0], it.Field, it.Value)
+			Library.Progress("Adopted superseded author/editor list for %s %s", it.Keys[0], it.Field)
+			retireSupersededValue(it.Keys[0], it.Field, it.Value)
+		case "k":
+			markSupersededKept(it.Keys[0], it.Field, it.Value)
+		}
+	case HomeworkContributor:
+		n, _ := strconv.Atoi(arg)
+		mergeContributorsInto(it.Candidates, it.Candidates[n-1])
+	}
+}
+
+// render draws the full screen: header, list pane, side-by-side view and command line.
+func (t *THomeworkTUI) render() {
+	width, height := terminalSize()
+	var b strings.Builder
+	b.WriteString(tuiClearScreen)
+
+	counts := map[string]int{}
+	skipped := 0
+	for _, it := range t.items {
+		counts[it.Kind]++
+		if it.Skipped {
+			skipped++
+		}
+	}
+	header := fmt.Sprintf("Homework: %d pending (%d %s, %d %s, %d %s, %d %s), %d skipped, %d decided",
+		len(t.items), counts[HomeworkDuplicate], HomeworkDuplicate, counts[HomeworkDblp], HomeworkDblp,
+		counts[HomeworkSuperseded], HomeworkSuperseded, counts[HomeworkContributor], HomeworkContributor, skipped, t.decided)
+	if t.kind != "" || t.filter != "" {
+		header += fmt.Sprintf(" — showing %d [%s %s]", len(t.visible), t.kind, t.filter)
+	}
+	b.WriteString(tuiBold + tuiFit(header, width) + tuiPlain + "\n")
+
+	// List pane: a third of the screen, scrolled to keep the selection in view.
+	listHeight := max(3, (height-6)/3)
+	first := max(0, min(t.current-listHeight/2, len(t.visible)-listHeight))
+	for i := first; i < first+listHeight; i++ {
+		if i >= len(t.visible) {
+			b.WriteString("\n")
+			continue
+		}
+		it := t.visible[i]
+		mark := " "
+		if it.Skipped {
+			mark = "s"
+		}
+		line := tuiFit(fmt.Sprintf("%4d %s %-11s %s", i+1, mark, it.Kind, it.label()), width)
+		if i == t.current {
+			line = tuiReverse + line + tuiPlain
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(strings.Repeat("─", width) + "\n")
+
+	// Side-by-side view of the selected item.
+	detailHeight := height - listHeight - 5
+	if t.current < len(t.visible) {
+		b.WriteString(tuiSideBySide(t.visible[t.current], width, detailHeight))
+	} else {
+		b.WriteString(tuiFit("Nothing to do.", width) + "\n" + strings.Repeat("\n", max(0, detailHeight-1)))
+	}
+	b.WriteString(strings.Repeat("─", width) + "\n")
+
+	help := "n/p/j N, / text, f kind, s=skip, q=quit"
+	if t.current < len(t.visible) {
+		help = homeworkKindDecisions[t.visible[t.current].Kind] + "; " + help
+	}
+	if t.status != "" {
+		help = t.status
+	}
+	b.WriteString(tuiFit(help, width) + "\n> ")
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+// tuiSideBySide renders the records of it in columns of height lines, marking
+// rows whose values differ with ≠.
+func tuiSideBySide(it *THomeworkItem, width, height int) string {
+	columns, rows := it.columns()
+	if len(columns) == 0 || height < 1 {
+		return ""
+	}
+	nameWidth := 12
+	colWidth := max(10, (width-nameWidth-2)/len(columns)-1)
+	var b strings.Builder
+	line := tuiPad("", nameWidth) + "  "
+	for _, c := range columns {
+		line += tuiPad(c.Title, colWidth) + " "
+	}
+	b.WriteString(tuiBold + tuiFit(line, width) + tuiPlain + "\n")
+	lines := 1
+	for _, row := range rows {
+		if lines == height-1 && len(rows) > lines {
+			b.WriteString(tuiFit(fmt.Sprintf("… %d more field(s)", len(rows)-lines+1), width) + "\n")
+			lines++
+			break
+		}
+		differs := false
+		for _, c := range columns[1:] {
+			differs = differs || c.Fields[row] != columns[0].Fields[row]
+		}
+		marker := "  "
+		if differs {
+			marker = "≠ "
+		}
+		line := tuiPad(row, nameWidth) + marker
+		for _, c := range columns {
+			line += tuiPad(c.Fields[row], colWidth) + " "
+		}
+		b.WriteString(tuiFit(line, width) + "\n")
+		lines++
+	}
+	b.WriteString(strings.Repeat("\n", max(0, height-lines)))
+	return b.String()
+}
+
+// tuiFlatten turns line breaks and tabs into spaces.
+var tuiFlatten = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// tuiFit cuts s to width runes, flattening line breaks.
+func tuiFit(s string, width int) string {
+	s = tuiFlatten.Replace(s)
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:max(0, width-1)]) + "…"
+}
+
+// tuiPad cuts or pads s to exactly width runes.
+func tuiPad(s string, width int) string {
+	s = tuiFit(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+// terminalSize returns the number of columns and lines of the terminal, as
+// reported by stty, or 80x24 when it cannot be determined.
+func terminalSize() (int, int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err == nil {
+		if fields := strings.Fields(string(out)); len(fields) == 2 {
+			lines, errLines := strconv.Atoi(fields[0])
+			columns, errColumns := strconv.Atoi(fields[1])
+			if errLines == nil && errColumns == nil && lines > 10 && columns > 40 {
+				return columns, lines
+			}
+		}
+	}
+	return 80, 24
+}
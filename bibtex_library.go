@@ -57,7 +57,8 @@ type (
 		FilesFolder  string   // Path to the PDF files folder, relative to FilesRoot
 		Comments     []string // The Comments included in a BibTeX library. These are not always "just" Comments. BiBDesk uses this to store (as XML) information on e.g. static groups.
 		GroupEntries TStringSetMap
-		TitleIndex   TStringSetMap //
+		SmartGroups  map[string]*TSmartGroup // BibDesk smart / JabRef search groups parsed from Comments; nil until first use (see bibtex_library_smart_groups.go)
+		TitleIndex   TStringSetMap           //
 		//		BookTitleIndex                   TStringSetMap             //
 		ISBNIndex                  TStringSetMap                           //
 		DOIIndex                   TStringSetMap                           //
@@ -80,14 +81,15 @@ type (
 		DblpSourceData             TSourceFieldData                        // pre-computed delivery snapshot; set around MaybeMergeDBLPEntry calls
 		EntryFlags                 map[string]TStringSet                   // canonical key → set of flag strings
 		harvestNameAliases         bool
-		harvestCapturePDFFields    bool            // when true: file/local-url pass through for harvest PDF copy
-		harvestSourceDir           string          // directory of the source bib file; used for relative PDF paths
-		harvestSyncGroups          TStringSet      // groups to sync to main DB during harvest (from config)
-		subsetLocalGroups          TStringSetMap   // local groups loaded for current subset write pass
-		jabrefGroupingBlock        string          // verbatim @Comment{jabref-meta: grouping:...} from source bib
-		jabrefMetaBlocks           []string        // other @Comment{jabref-meta: ...} blocks carried verbatim
-		bibdeskMetaBlocks          []string        // @Comment{BibDesk ...} blocks (not Static Groups) carried verbatim
-		PDFFiles                   map[string]bool // keys with a <key>.pdf in FilesFolder; populated by LoadPDFFiles
+		harvestCapturePDFFields    bool                      // when true: file/local-url pass through for harvest PDF copy
+		harvestSourceDir           string                    // directory of the source bib file; used for relative PDF paths
+		harvestSyncGroups          TStringSet                // groups to sync to main DB during harvest (from config)
+		subsetLocalGroups          TStringSetMap             // local groups loaded for current subset write pass
+		jabrefGroupingBlock        string                    // verbatim @Comment{jabref-meta: grouping:...} from source bib
+		jabrefMetaBlocks           []string                  // other @Comment{jabref-meta: ...} blocks carried verbatim
+		bibdeskMetaBlocks          []string                  // @Comment{BibDesk ...} blocks (not Static Groups) carried verbatim
+		PDFFiles                   map[string]bool           // keys with a <key>.pdf in FilesFolder; populated by LoadPDFFiles
+		Attachments                map[string][]*TAttachment // key → attachments other than <key>.pdf in FilesFolder; populated by LoadPDFFiles
 		capturedDBLPEntry          *TBibTeXEntry
 		capturedHarvestEntries     *[]TBibTeXEntry // when non-nil, parsed entries collected here instead of DB
 		URLsIgnore                 TStringSet
@@ -198,7 +200,13 @@ func (l *TBibTeXLibrary) EntryInvolvedInWarning(key string) {
 
 // SetEntryFieldValue writes a field value to the DB for the given entry.
 func (l *TBibTeXLibrary) SetEntryFieldValue(entry, field, value string) {
+	if !fieldChangeHooked() {
+		upsertBibEntryField(entry, field, value)
+		return
+	}
+	old := l.EntryFieldValueity(entry, field)
 	upsertBibEntryField(entry, field, value)
+	l.fireFieldChanged(entry, field, old, value)
 }
 
 // SetEntryType writes the entry type to the DB, or to the captured DBLP entry when active.
@@ -772,6 +780,7 @@ func (l *TBibTeXLibrary) MergeEntries(sourceRAW, targetRAW string) string {
 				block += "\nEntry " + source + ":\n" + l.entryDisplayString(source)
 				block += "Entry " + target + ":\n" + l.entryDisplayString(target)
 				if isDifferent {
+					askingAbout(source, target)
 					if !l.WarningYesNoQuestion(QuestionMergeAnyway, "%s", block) {
 						return target
 					}
@@ -875,8 +884,9 @@ func (l *TBibTeXLibrary) MergeEntries(sourceRAW, targetRAW string) string {
 			if !l.KeyIsTemporary.Contains(source) {
 				l.AddKeyAlias(source, target)
 				l.AddNonDoubleEntries(source, target)
-				// Rename source PDF to target name so the file stays associated.
+				// Rename source PDF and attachments to target name so the files stay associated.
 				l.mergePDFFile(source, target)
+				l.mergeAttachments(source, target)
 			}
 			l.ReassignEntryFieldMappings(source, target)
 			l.transferMetadata(source, target)
@@ -902,6 +912,10 @@ func (l *TBibTeXLibrary) MergeEntries(sourceRAW, targetRAW string) string {
 			l.TitleIndex.AddValueToStringSetMap(targetEntry.FieldValue(TitleField), target)
 
 			l.CheckEntry(l.buildEntry(target))
+
+			if !l.KeyIsTemporary.Contains(source) {
+				l.fireHook(THookEvent{Event: HookEntryMerged, Keys: []string{target}, MergedFrom: source})
+			}
 		}
 
 		return target
@@ -1113,6 +1127,9 @@ func (l *TBibTeXLibrary) MaybeMergeEntries(sourceRAW, targetRAW string) {
 			}
 		}
 		if source != target && !l.NonDoubleEntries[source].Set().Contains(target) && !l.EvidenceForBeingDifferentEntries(source, target) {
+			if l.mergeByPolicy(source, target) {
+				return
+			}
 			l.Warning("Found potential double entries")
 
 			sourceEntry := l.entryDisplayString(source)
@@ -1126,6 +1143,7 @@ func (l *TBibTeXLibrary) MaybeMergeEntries(sourceRAW, targetRAW string) {
 				l.Warning("Empty target entry: %s", target)
 			}
 
+			askingAbout(source, target)
 			if l.WarningYesNoQuestion("Merge these entries", "First entry:\n%s\nSecond entry:\n%s", sourceEntry, targetEntry) {
 				confirmed := true
 				// A "y" here is easy to fire off on autopilot when scanning a long
@@ -1133,12 +1151,8 @@ func (l *TBibTeXLibrary) MaybeMergeEntries(sourceRAW, targetRAW string) {
 				// these are two genuinely different publications wearing the same
 				// title (e.g. a book and an unrelated encyclopedia chapter), so
 				// double-check before doing something merges can't cleanly undo.
-				sourceYear := l.EntryFieldValueity(source, "year")
-				targetYear := l.EntryFieldValueity(target, "year")
-				if sourceYear != "" && targetYear != "" && sourceYear != targetYear {
-					confirmed = l.WarningYesNoQuestion("The years differ — are you sure you want to merge these entries",
-						"First entry (year %s):\n%s\nSecond entry (year %s):\n%s",
-						sourceYear, sourceEntry, targetYear, targetEntry)
+				if sourceYear, targetYear, differ := l.mergeYearsDiffer(source, target); differ {
+					confirmed = l.ConfirmMergeDespiteYears(source, target, sourceYear, targetYear)
 				}
 				if confirmed {
 					l.MergeEntries(source, target)
@@ -1155,6 +1169,39 @@ func (l *TBibTeXLibrary) MaybeMergeEntries(sourceRAW, targetRAW string) {
 	}
 }
 
+// mergeYearsDiffer returns the years of source and target, and whether both are
+// known and differ.
+func (l *TBibTeXLibrary) mergeYearsDiffer(source, target string) (string, string, bool) {
+	sourceYear := l.EntryFieldValueity(source, "year")
+	targetYear := l.EntryFieldValueity(target, "year")
+	return sourceYear, targetYear, sourceYear != "" && targetYear != "" && sourceYear != targetYear
+}
+
+// ConfirmMergeDespiteYears asks whether source and target, whose years differ,
+// should be merged all the same.
+func (l *TBibTeXLibrary) ConfirmMergeDespiteYears(source, target, sourceYear, targetYear string) bool {
+	askingAbout(source, target)
+	return l.WarningYesNoQuestion("The years differ — are you sure you want to merge these entries",
+		"First entry (year %s):\n%s\nSecond entry (year %s):\n%s",
+		sourceYear, l.entryDisplayString(source), targetYear, l.entryDisplayString(target))
+}
+
+// MergeDecidedEntries merges source into target once a curator decided so outside
+// MaybeMergeEntries, as in the homework TUI and the review server. As there, a
+// merge rule of the conflict policy takes precedence, and entries whose years
+// differ are only merged when confirmYears agrees. Reports whether they were merged.
+func (l *TBibTeXLibrary) MergeDecidedEntries(source, target string, confirmYears func(sourceYear, targetYear string) bool) bool {
+	source, target = l.MapEntryKey(source), l.MapEntryKey(target)
+	if l.mergeByPolicy(source, target) {
+		return true
+	}
+	if sourceYear, targetYear, differ := l.mergeYearsDiffer(source, target); differ && !confirmYears(sourceYear, targetYear) {
+		return false
+	}
+	l.MergeEntries(source, target)
+	return true
+}
+
 func (l *TBibTeXLibrary) MaybeMergeEntrySet(keys TStringSet) {
 	if keys.Size() > 1 {
 		sortedkeys := keys.ElementsSorted()
@@ -1386,6 +1433,15 @@ func (l *TBibTeXLibrary) SetEntryFlag(key, flag string) {
 	}
 }
 
+// ClearEntryFlag removes flag from key's flag set and deletes it from entry_metadata immediately.
+func (l *TBibTeXLibrary) ClearEntryFlag(key, flag string) {
+	canon := l.MapEntryKey(key)
+	if flags, ok := l.EntryFlags[canon]; ok && flags.Set().Contains(flag) {
+		flags.Set().Delete(flag)
+		dbExecSave("ClearEntryFlag", `DELETE FROM entry_metadata WHERE entry_key = ? AND property = ?`, canon, flag)
+	}
+}
+
 // buildEntry loads a TBibTeXEntry snapshot for key from the DB.
 func (l *TBibTeXLibrary) buildEntry(key string) *TBibTeXEntry {
 	return loadEntryFromDb(key)
@@ -1429,10 +1485,18 @@ func (l *TBibTeXLibrary) setEntryField(entry *TBibTeXEntry, field, value string)
 		}
 		return
 	}
+	old := ""
+	hooked := fieldChangeHooked()
+	if hooked {
+		old = l.EntryFieldValueity(entry.Key, field)
+	}
 	upsertBibEntryField(entry.Key, field, value)
 	if entryCache == nil {
 		entry.Fields[field] = value
 	}
+	if hooked {
+		l.fireFieldChanged(entry.Key, field, old, value)
+	}
 }
 
 // deleteEntryField removes a field from the entry. When the entry is open,
@@ -1443,10 +1507,18 @@ func (l *TBibTeXLibrary) deleteEntryField(entry *TBibTeXEntry, field string) {
 		delete(entry.Fields, field)
 		return
 	}
+	old := ""
+	hooked := fieldChangeHooked()
+	if hooked {
+		old = l.EntryFieldValueity(entry.Key, field)
+	}
 	deleteBibEntryField(entry.Key, field)
 	if entryCache == nil {
 		delete(entry.Fields, field)
 	}
+	if hooked {
+		l.fireFieldChanged(entry.Key, field, old, "")
+	}
 }
 
 // DeleteEntry removes a canonical entry and all associated index data:
@@ -1469,13 +1541,14 @@ func (l *TBibTeXLibrary) DeleteEntry(key string) {
 	})
 	l.KeyOldies.DeleteByTarget(key)
 	l.TitleIndex.DeleteValueFromStringSetMap(TeXStringIndexer(l.EntryFieldValueity(key, TitleField)), key)
-	// Move PDF to library trash before removing the DB entry.
+	// Move PDF and attachments to library trash before removing the DB entry.
 	if l.PDFFiles[key] {
 		if !l.moveToLibraryTrash(l.FilesRoot + l.FilesFolder + key + ".pdf") {
 			dbInteraction.Warning("DeleteEntry: could not move PDF for %s to trash — left in place", key)
 		}
 		delete(l.PDFFiles, key)
 	}
+	l.trashAttachments(key)
 	// Clean up entry_metadata so no orphan rows accumulate after deletion.
 	if props, ok := l.Metadata[key]; ok {
 		for prop := range props {
@@ -0,0 +1,346 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_alias_template
+ *
+ * Template-based preferred aliases. Without a template, derivePreferredAlias
+ * uses the built-in <surname><year><keyword> scheme. A template is set for the
+ * library with the alias_template config key, and per sync file with the
+ * alias_template option of its (bib.)config. Placeholders:
+ *
+ *	{author} {Author} {AUTHOR}  surname of the first author (as in deriveAliasBase)
+ *	{year} {yy}                 year, in four or two digits
+ *	{word} {Word}               first title keyword
+ *	{words}                     title keywords: each single keyword, then adjacent
+ *	                            pairs, and so on, until one does not collide
+ *	{dblp} {venue}              DBLP key (e.g. conf/er/Smith20) and its venue code
+ *
+ * Alternatives are separated by "|": the first one whose placeholders can all be
+ * filled is used. Named styles: authoryear, author:year:firstword, dblp, scholar.
+ * Collisions are resolved with a suffix (alias_suffix): letters (a, b, …; the
+ * default) or numbers (-2, -3, …).
+ *
+ * Changing the library template gives entries new aliases; the old ones are kept
+ * as key_oldies, so .keys files and papers that use them keep resolving. Only
+ * aliases generated by the built-in scheme or an earlier template are replaced:
+ * generated aliases are recorded as such in lineage, while one set by hand
+ * (-set_preferred_alias) or by a script stays.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Collision suffixes of alias templates.
+const (
+	AliasSuffixLetters = "letters"
+	AliasSuffixNumbers = "numbers"
+
+	aliasSuffixMaxTries = 702 // a … z, aa … zz
+
+	// AliasSourceGenerated is the lineage source of preferred aliases derived by
+	// the built-in scheme or an alias template.
+	AliasSourceGenerated = "generated"
+)
+
+var (
+	aliasTemplate string // library alias template (alias_template config key); "" = built-in scheme
+	aliasSuffix   string // library collision suffix (alias_suffix config key); "" = letters
+
+	// aliasTemplateStyles maps the named styles to their templates.
+	aliasTemplateStyles = map[string]string{
+		"authoryear":            "{Author}{year}",
+		"author:year:firstword": "{Author}:{year}:{Word}",
+		"dblp":                  "DBLP:{dblp}|{Author}{year}",
+		"scholar":               "{author}{year}{word}",
+	}
+
+	aliasTemplatePlaceholder = regexp.MustCompile(`\{[A-Za-z]+\}`)
+	validTemplateAlias       = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9:._/+-]*[A-Za-z0-9])?$`)
+	aliasLetterSuffix        = regexp.MustCompile(`^[a-z]{1,2}$`)
+	aliasNumberSuffix        = regexp.MustCompile(`^-[0-9]+$`)
+)
+
+// expandAliasTemplate returns the template of a named style, or template itself.
+func expandAliasTemplate(template string) string {
+	if style, ok := aliasTemplateStyles[strings.ToLower(template)]; ok {
+		return style
+	}
+	return template
+}
+
+// validPreferredAlias reports whether alias is well-formed for the library's
+// alias scheme: the built-in format, or any plain key when a template is set.
+func validPreferredAlias(alias string) bool {
+	if aliasTemplate == "" {
+		return validPreferredKeyAlias.MatchString(alias)
+	}
+	return validTemplateAlias.MatchString(alias)
+}
+
+// setGeneratedPreferredAlias sets alias, derived by the built-in scheme or the
+// alias template, as entry's preferred alias, recording it as generated.
+func (l *TBibTeXLibrary) setGeneratedPreferredAlias(entry *TBibTeXEntry, alias string) {
+	l.setPreferredAlias(entry, alias)
+	l.setLineage(entry.Key, PreferredAliasField, alias, AliasSourceGenerated, false)
+}
+
+// preferredAliasGenerated reports whether alias, key's preferred alias, was
+// generated rather than set by hand or by a script. Aliases from before this was
+// recorded count as generated when they have the built-in format.
+func (l *TBibTeXLibrary) preferredAliasGenerated(key, alias string) bool {
+	if rec, ok := l.LineageMap[key][PreferredAliasField]; ok && rec.Value == alias {
+		return rec.Source == AliasSourceGenerated
+	}
+	return validPreferredKeyAlias.MatchString(alias)
+}
+
+// keepReplacedAlias keeps alias, a preferred alias replaced under a new alias
+// template, as a key_oldie of key, and notes it in the entry's metadata so that
+// CheckKeyOldiesConsistency does not move it back to key_hints.
+func (l *TBibTeXLibrary) keepReplacedAlias(alias, key string) {
+	canonical := l.MapEntryKey(key)
+	if existing := l.KeyOldies.Get(alias); existing != "" && existing != canonical {
+		l.Warning(WarningAmbiguousKeyOldie, alias, existing, canonical)
+		return
+	}
+	if hint := l.HintToKey.GetValue(alias); hint != "" {
+		if l.MapEntryKey(hint) != canonical {
+			l.Warning(WarningAmbiguousKeyOldie, alias, hint, canonical)
+			return
+		}
+		l.HintToKey.Delete(alias)
+	}
+	l.KeyOldies.Set(alias, canonical)
+	if !l.replacedAlias(alias, canonical) {
+		l.SetMetadata(canonical, MetaPropReplacedAliases, strings.TrimSpace(l.GetMetadata(canonical, MetaPropReplacedAliases)+" "+alias))
+	}
+}
+
+// replacedAlias reports whether alias is a replaced preferred alias of key
+// (see keepReplacedAlias).
+func (l *TBibTeXLibrary) replacedAlias(alias, key string) bool {
+	for _, replaced := range strings.Fields(l.GetMetadata(key, MetaPropReplacedAliases)) {
+		if replaced == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasSuffixFor returns the n-th (from 1) collision suffix of kind.
+func aliasSuffixFor(kind string, n int) string {
+	if kind == AliasSuffixNumbers {
+		return "-" + strconv.Itoa(n+1)
+	}
+	if n <= 26 {
+		return string(rune('a' + n - 1))
+	}
+	return string(rune('a'+(n-27)/26)) + string(rune('a'+(n-27)%26))
+}
+
+// capitalise upper-cases the first letter of s.
+func capitalise(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// aliasTemplateBases returns the alias candidates template yields for entry,
+// before collision suffixes: one per alternative, or one per keyword combination
+// when the alternative uses {words}. Placeholder values are derived on first use,
+// so an alternative that does not mention the author does not warn about it.
+func (l *TBibTeXLibrary) aliasTemplateBases(entry *TBibTeXEntry, template string) []string {
+	var (
+		derived       bool
+		surname, year string
+		keywords      []string
+	)
+	value := func(placeholder string) []string {
+		switch placeholder {
+		case "{author}", "{Author}", "{AUTHOR}", "{year}", "{yy}":
+			if !derived {
+				derived = true
+				surname, year = l.deriveAliasSurnameAndYear(entry)
+			}
+		case "{word}", "{Word}", "{words}":
+			if keywords == nil {
+				keywords = titleKeywords(entry.FieldValue(TitleField))
+			}
+		}
+		dblpKey := strings.TrimPrefix(entry.FieldValue(DBLPField), "DBLP:")
+		one := func(v string) []string {
+			if v == "" {
+				return nil
+			}
+			return []string{v}
+		}
+		switch placeholder {
+		case "{author}":
+			return one(surname)
+		case "{Author}":
+			return one(capitalise(surname))
+		case "{AUTHOR}":
+			return one(strings.ToUpper(surname))
+		case "{year}":
+			return one(year)
+		case "{yy}":
+			if len(year) < 2 {
+				return nil
+			}
+			return one(year[len(year)-2:])
+		case "{word}", "{Word}":
+			if len(keywords) == 0 {
+				return nil
+			}
+			if placeholder == "{Word}" {
+				return one(capitalise(keywords[0]))
+			}
+			return one(keywords[0])
+		case "{words}":
+			var combinations []string
+			for length := 1; length <= len(keywords); length++ {
+				for start := 0; start+length <= len(keywords); start++ {
+					combinations = append(combinations, strings.Join(keywords[start:start+length], ""))
+				}
+			}
+			return combinations
+		case "{dblp}":
+			return one(dblpKey)
+		case "{venue}":
+			if parts := strings.Split(dblpKey, "/"); len(parts) >= 2 {
+				return one(stripNonAlphaNum.ReplaceAllString(TeXStringIndexer(parts[len(parts)-2]), ""))
+			}
+			return nil
+		}
+		l.ReportEntryWarning(entry.Key, WarningUnknownAliasPlaceholder, placeholder)
+		return nil
+	}
+
+	for _, alternative := range strings.Split(expandAliasTemplate(template), "|") {
+		bases := []string{""}
+		for _, part := range splitAliasTemplate(alternative) {
+			values := []string{part}
+			if aliasTemplatePlaceholder.FindString(part) == part {
+				values = value(part)
+			}
+			if len(values) == 0 {
+				bases = nil
+				break
+			}
+			var extended []string
+			for _, base := range bases {
+				for _, v := range values {
+					extended = append(extended, base+v)
+				}
+			}
+			bases = extended
+		}
+		if len(bases) > 0 {
+			return bases
+		}
+	}
+	return nil
+}
+
+// splitAliasTemplate splits a template alternative into literal text and placeholders.
+func splitAliasTemplate(alternative string) []string {
+	var parts []string
+	last := 0
+	for _, loc := range aliasTemplatePlaceholder.FindAllStringIndex(alternative, -1) {
+		if loc[0] > last {
+			parts = append(parts, alternative[last:loc[0]])
+		}
+		parts = append(parts, alternative[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	if last < len(alternative) {
+		parts = append(parts, alternative[last:])
+	}
+	return parts
+}
+
+// aliasTakenByOther reports whether alias already refers to an entry other than key.
+func (l *TBibTeXLibrary) aliasTakenByOther(key, alias string) bool {
+	if target := l.HintToKey.GetValue(alias); target != "" && l.MapEntryKey(target) != key {
+		return true
+	}
+	if target := l.KeyOldies.Get(alias); target != "" && target != key {
+		return true
+	}
+	return false
+}
+
+// deriveTemplateAlias returns the first alias template yields for entry that is
+// not taken: the bases in order, then the first base with collision suffixes.
+// Returns "" (after an entry warning) when the template yields nothing usable.
+func (l *TBibTeXLibrary) deriveTemplateAlias(entry *TBibTeXEntry, template, suffix string, taken func(string) bool) string {
+	bases := l.aliasTemplateBases(entry, template)
+	if len(bases) == 0 {
+		l.ReportEntryWarning(entry.Key, WarningCannotDeriveTemplateAlias, template)
+		return ""
+	}
+	for _, base := range bases {
+		if validTemplateAlias.MatchString(base) && !taken(base) {
+			return base
+		}
+	}
+	if validTemplateAlias.MatchString(bases[0]) {
+		for n := 1; n <= aliasSuffixMaxTries; n++ {
+			if candidate := bases[0] + aliasSuffixFor(suffix, n); !taken(candidate) {
+				return candidate
+			}
+		}
+	}
+	l.ReportEntryWarning(entry.Key, WarningCannotDeriveUniquePreferredAlias, bases[0])
+	return ""
+}
+
+// aliasFitsTemplate reports whether alias is one that template can yield for
+// entry, with or without a collision suffix, so that aliases from the current
+// template are kept even when a fresh derivation would now pick another one.
+func (l *TBibTeXLibrary) aliasFitsTemplate(entry *TBibTeXEntry, alias, template, suffix string) bool {
+	suffixPattern := aliasLetterSuffix
+	if suffix == AliasSuffixNumbers {
+		suffixPattern = aliasNumberSuffix
+	}
+	for _, base := range l.aliasTemplateBases(entry, template) {
+		if alias == base || (strings.HasPrefix(alias, base) && suffixPattern.MatchString(alias[len(base):])) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncFileAlias returns the local key for canonical in a sync file: the alias
+// the file's own alias_template yields, or the entry's preferred alias when the
+// file has no template. inUse holds the local keys already taken in the file.
+func syncFileAlias(cfg TBibGetConfig, canonical string, inUse map[string]bool) string {
+	if cfg.AliasTemplate == "" {
+		return Library.PreferredKey(canonical)
+	}
+	suffix := cfg.AliasSuffix
+	if suffix == "" {
+		suffix = aliasSuffix
+	}
+	entry := Library.buildEntry(canonical)
+	alias := Library.deriveTemplateAlias(entry, cfg.AliasTemplate, suffix, func(candidate string) bool {
+		return inUse[candidate] || Library.aliasTakenByOther(canonical, candidate)
+	})
+	if alias != "" {
+		inUse[alias] = true
+	}
+	return alias
+}
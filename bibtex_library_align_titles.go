@@ -247,13 +247,13 @@ func (l *TBibTeXLibrary) handleAlignHit(key string, h titleAlignHit, autoAccept
 	fmt.Fprintf(os.Stderr, "Proposed: %s\n", h.proposed)
 	fmt.Fprint(os.Stderr, "QUESTION: Action (a=accept, m=modify, s=skip, w=waive, q=quit): ")
 	for {
-		option := readStdinLine()
+		option := answerLine("Title/booktitle alignment action", []any{key, h.kind}, []string{"a", "m", "s", "w"}, "s")
 		switch option {
 		case "a":
 			l.applyAlignHit(key, h, h.proposed)
 			return false
 		case "m":
-			newTitle, err := l.AskForInput("New title (Enter = keep proposed)")
+			newTitle, err := l.AskForInput("New title (Enter = keep proposed)", key, h.kind)
 			if err != nil || newTitle == "" {
 				newTitle = h.proposed
 			}
@@ -265,7 +265,7 @@ func (l *TBibTeXLibrary) handleAlignHit(key string, h titleAlignHit, autoAccept
 			l.SetMetadata(key, waiverPropForKind(h.kind), time.Now().Format("2006-01-02"))
 			return false
 		case "q":
-			// This "q" is handled by its own raw readStdinLine loop, not
+			// This "q" is handled by its own answerLine loop, not
 			// WarningQuestion, so it must call quitNow() itself rather than
 			// relying on the interaction layer to do it.
 			quitNow()
@@ -348,7 +348,7 @@ func (l *TBibTeXLibrary) CheckAlignBooktitleCountries() {
 	// Phase 2: action.
 	fmt.Fprint(os.Stderr, "QUESTION: Proceed? (a=accept all, i=interactive, q=quit): ")
 	for {
-		option := readStdinLine()
+		option := answerLine("Proceed with title/booktitle alignment", nil, []string{"a", "i"}, "q")
 		switch option {
 		case "a":
 			for _, kh := range allHits {
@@ -364,7 +364,7 @@ func (l *TBibTeXLibrary) CheckAlignBooktitleCountries() {
 			}
 			return
 		case "q":
-			// Own raw readStdinLine loop, not WarningQuestion — see the matching
+			// Own answerLine loop, not WarningQuestion — see the matching
 			// comment in handleAlignHit.
 			quitNow()
 			return
@@ -0,0 +1,469 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_attachments
+ *
+ * Attachments of entries beyond the paper itself: slides, supplementary material
+ * (code archives, data, reviewer reports), preprints and camera-ready versions.
+ *
+ * Attachments live next to the <key>.pdf files in FilesFolder, named
+ *
+ *	<key>.<role>.<ext>       first attachment with this role
+ *	<key>.<role>-<n>.<ext>   n-th attachment with this role (n >= 2)
+ *
+ * so that, like the PDFs themselves, they are tracked by their presence on disk
+ * rather than in the database. The paper role is the <key>.pdf file.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attachment roles.
+const (
+	AttachmentRolePaper       = "paper"
+	AttachmentRoleSlides      = "slides"
+	AttachmentRoleSupplement  = "supplement"
+	AttachmentRolePreprint    = "preprint"
+	AttachmentRoleCameraReady = "camera-ready"
+)
+
+// attachmentRoles lists the roles in the order in which attachments are listed
+// and emitted.
+var attachmentRoles = []string{
+	AttachmentRolePaper,
+	AttachmentRoleSlides,
+	AttachmentRoleSupplement,
+	AttachmentRolePreprint,
+	AttachmentRoleCameraReady,
+}
+
+// attachmentNamePattern matches the file name of a non-paper attachment.
+var attachmentNamePattern = regexp.MustCompile(
+	`^(.+)\.(slides|supplement|preprint|camera-ready)(?:-([2-9]|[1-9][0-9]+))?\.([A-Za-z0-9]+)$`)
+
+// TAttachment is a file attached to an entry.
+type TAttachment struct {
+	Key   string // canonical key of the entry
+	Role  string // one of attachmentRoles
+	Index int    // 1 for the first attachment with this role, 2 for the second, …
+	Type  string // file type: the lower-case extension, e.g. "pdf", "zip"
+	Name  string // file name within FilesFolder
+	md5   string // content MD5; computed on first use (see AttachmentMD5)
+}
+
+// attachmentRoleRank returns the position of role in attachmentRoles.
+func attachmentRoleRank(role string) int {
+	for i, r := range attachmentRoles {
+		if r == role {
+			return i
+		}
+	}
+	return len(attachmentRoles)
+}
+
+// isAttachmentRole reports whether role is a known attachment role.
+func isAttachmentRole(role string) bool {
+	return attachmentRoleRank(role) < len(attachmentRoles)
+}
+
+// attachmentFileName returns the file name of an attachment of key.
+func attachmentFileName(key, role string, index int, fileType string) string {
+	if role == AttachmentRolePaper {
+		return key + ".pdf"
+	}
+	if index > 1 {
+		return key + "." + role + "-" + strconv.Itoa(index) + "." + fileType
+	}
+	return key + "." + role + "." + fileType
+}
+
+// parseAttachmentName splits the file name of a non-paper attachment into its
+// parts. It returns false for all other names, including <key>.pdf.
+func parseAttachmentName(name string) (TAttachment, bool) {
+	match := attachmentNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return TAttachment{}, false
+	}
+	index := 1
+	if match[3] != "" {
+		index, _ = strconv.Atoi(match[3])
+	}
+	return TAttachment{
+		Key:   match[1],
+		Role:  match[2],
+		Index: index,
+		Type:  strings.ToLower(match[4]),
+		Name:  name,
+	}, true
+}
+
+// sortAttachments orders attachments by role and index.
+func sortAttachments(attachments []*TAttachment) {
+	sort.Slice(attachments, func(i, j int) bool {
+		ri, rj := attachmentRoleRank(attachments[i].Role), attachmentRoleRank(attachments[j].Role)
+		if ri != rj {
+			return ri < rj
+		}
+		return attachments[i].Index < attachments[j].Index
+	})
+}
+
+// addLoadedAttachment records an attachment found in FilesFolder.
+func (l *TBibTeXLibrary) addLoadedAttachment(a TAttachment) {
+	if l.Attachments == nil {
+		l.Attachments = map[string][]*TAttachment{}
+	}
+	l.Attachments[a.Key] = append(l.Attachments[a.Key], &a)
+	sortAttachments(l.Attachments[a.Key])
+}
+
+// AttachmentPath returns the full path of an attachment.
+func (l *TBibTeXLibrary) AttachmentPath(a *TAttachment) string {
+	return l.FilesRoot + l.FilesFolder + a.Name
+}
+
+// AttachmentMD5 returns the MD5 of the content of an attachment. For PDFs this
+// ignores the metadata we embedded ourselves (see pdfContentMD5).
+func (l *TBibTeXLibrary) AttachmentMD5(a *TAttachment) string {
+	if a.md5 == "" {
+		if a.Type == "pdf" {
+			a.md5 = pdfContentMD5(l.AttachmentPath(a))
+		} else {
+			a.md5 = MD5ForFile(l.AttachmentPath(a))
+		}
+	}
+	return a.md5
+}
+
+// EntryAttachments returns all files attached to key: its paper (<key>.pdf),
+// when there is one, followed by its other attachments by role and index.
+func (l *TBibTeXLibrary) EntryAttachments(key string) []*TAttachment {
+	var result []*TAttachment
+	if l.PDFFiles[key] {
+		result = append(result, &TAttachment{
+			Key:   key,
+			Role:  AttachmentRolePaper,
+			Index: 1,
+			Type:  "pdf",
+			Name:  attachmentFileName(key, AttachmentRolePaper, 1, "pdf"),
+		})
+	}
+	return append(result, l.Attachments[key]...)
+}
+
+// nextAttachmentIndex returns the first free index for a new attachment of key
+// with the given role.
+func (l *TBibTeXLibrary) nextAttachmentIndex(key, role string) int {
+	index := 1
+	for _, a := range l.Attachments[key] {
+		if a.Role == role && a.Index >= index {
+			index = a.Index + 1
+		}
+	}
+	return index
+}
+
+// attachmentWithMD5 returns the attachment of key with the given content MD5, if any.
+func (l *TBibTeXLibrary) attachmentWithMD5(key, md5 string) *TAttachment {
+	for _, a := range l.EntryAttachments(key) {
+		if l.AttachmentMD5(a) == md5 {
+			return a
+		}
+	}
+	return nil
+}
+
+// fileContentMD5 returns the content MD5 of path, as AttachmentMD5 would.
+func fileContentMD5(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return pdfContentMD5(path)
+	}
+	return MD5ForFile(path)
+}
+
+// AddAttachment copies the file at srcPath into FilesFolder as an attachment of
+// key with the given role. A file whose content is already attached to key is
+// not added again; the existing attachment is returned instead.
+func (l *TBibTeXLibrary) AddAttachment(key, role, srcPath string) (*TAttachment, error) {
+	if !isAttachmentRole(role) {
+		return nil, fmt.Errorf("unknown attachment role %q (use one of %s)", role, strings.Join(attachmentRoles, ", "))
+	}
+	fileType := strings.ToLower(strings.TrimPrefix(filepath.Ext(srcPath), "."))
+	if fileType == "" {
+		return nil, fmt.Errorf("%s has no file extension", srcPath)
+	}
+	if existing := l.attachmentWithMD5(key, fileContentMD5(srcPath)); existing != nil {
+		return existing, nil
+	}
+
+	a := TAttachment{Key: key, Role: role, Index: 1, Type: fileType}
+	if role == AttachmentRolePaper {
+		if fileType != "pdf" {
+			return nil, fmt.Errorf("the paper of an entry must be a PDF file")
+		}
+		if l.PDFFiles[key] {
+			return nil, fmt.Errorf("%s already has a paper; attach this one as %s or %s", key, AttachmentRolePreprint, AttachmentRoleCameraReady)
+		}
+	} else {
+		a.Index = l.nextAttachmentIndex(key, role)
+	}
+	a.Name = attachmentFileName(key, role, a.Index, fileType)
+
+	destPath := l.AttachmentPath(&a)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
+		return nil, err
+	}
+	if err := copyFile(srcPath, destPath); err != nil {
+		return nil, err
+	}
+	if role == AttachmentRolePaper {
+		l.PDFFiles[key] = true
+	} else {
+		l.addLoadedAttachment(a)
+	}
+	return &a, nil
+}
+
+// moveAttachment renames attachment a of one entry into a new attachment of
+// targetKey with the same role, using the first free index. When targetKey
+// already has an attachment with the same content, a is moved to the library
+// trash instead.
+func (l *TBibTeXLibrary) moveAttachment(a *TAttachment, targetKey string) {
+	srcPath := l.AttachmentPath(a)
+	if existing := l.attachmentWithMD5(targetKey, l.AttachmentMD5(a)); existing != nil {
+		if !l.moveToLibraryTrash(srcPath) {
+			l.Warning(WarningAttachmentNotMoved, a.Name, l.BaseName+".trash")
+			return
+		}
+		l.Progress(ProgressAttachmentDuplicateTrashed, a.Name, existing.Name)
+		l.removeLoadedAttachment(a)
+		return
+	}
+
+	moved := *a
+	moved.Key = targetKey
+	moved.Index = l.nextAttachmentIndex(targetKey, a.Role)
+	moved.Name = attachmentFileName(targetKey, a.Role, moved.Index, a.Type)
+	if err := os.Rename(srcPath, l.AttachmentPath(&moved)); err != nil {
+		l.Warning(WarningAttachmentNotRenamed, a.Name, moved.Name, err)
+		return
+	}
+	l.removeLoadedAttachment(a)
+	l.addLoadedAttachment(moved)
+	l.Progress(ProgressAttachmentRenamed, a.Name, moved.Name)
+}
+
+// removeLoadedAttachment forgets attachment a.
+func (l *TBibTeXLibrary) removeLoadedAttachment(a *TAttachment) {
+	kept := l.Attachments[a.Key][:0]
+	for _, other := range l.Attachments[a.Key] {
+		if other.Name != a.Name {
+			kept = append(kept, other)
+		}
+	}
+	if len(kept) == 0 {
+		delete(l.Attachments, a.Key)
+	} else {
+		l.Attachments[a.Key] = kept
+	}
+}
+
+// mergeAttachments transfers the attachments of sourceKey to targetKey as part of
+// a merge (the counterpart of mergePDFFile). As an entry can have several
+// attachments with the same role, they are renumbered rather than asked about;
+// only attachments whose content the target already has are discarded.
+func (l *TBibTeXLibrary) mergeAttachments(sourceKey, targetKey string) {
+	attachments := append([]*TAttachment(nil), l.Attachments[sourceKey]...)
+	for _, a := range attachments {
+		l.moveAttachment(a, targetKey)
+	}
+}
+
+// trashAttachments moves all non-paper attachments of key to the library trash.
+func (l *TBibTeXLibrary) trashAttachments(key string) {
+	for _, a := range l.Attachments[key] {
+		if !l.moveToLibraryTrash(l.AttachmentPath(a)) {
+			l.Warning(WarningAttachmentNotMoved, a.Name, l.BaseName+".trash")
+		}
+	}
+	delete(l.Attachments, key)
+}
+
+// scanOrphanAttachment handles one attachment found by ScanOrphanPDFs: attachments
+// of aliases move to the canonical entry, and attachments without any entry move
+// to the library trash. It returns whether the file was renamed or moved.
+func (l *TBibTeXLibrary) scanOrphanAttachment(a TAttachment, logOrphan func(string)) (renamed, moved bool) {
+	if l.EntryExists(a.Key) {
+		return false, false
+	}
+	if canonical := l.MapEntryKey(a.Key); canonical != "" && canonical != a.Key && l.EntryExists(canonical) {
+		l.moveAttachment(&a, canonical)
+		return !FileExists(l.AttachmentPath(&a)), false
+	}
+	logOrphan(a.Name)
+	if !l.moveToLibraryTrash(l.AttachmentPath(&a)) {
+		l.Warning(WarningAttachmentNotMoved, a.Name, l.BaseName+".trash")
+		return false, false
+	}
+	return false, true
+}
+
+// --- Emission in sync output ---
+
+// jabrefFileType returns the JabRef external file type for a file type.
+func jabrefFileType(fileType string) string {
+	return strings.ToUpper(fileType)
+}
+
+// jabrefFileValue returns the value of a JabRef file field listing the given
+// files, one description:path:type triple per attachment. The paper has an empty
+// description, so that tools looking for "the" PDF pick it; other attachments
+// are described by their role.
+func jabrefFileValue(attachments []*TAttachment, paths []string) string {
+	parts := make([]string, len(attachments))
+	for i, a := range attachments {
+		description := a.Role
+		if a.Role == AttachmentRolePaper {
+			description = ""
+		}
+		parts[i] = description + ":" + jabrefEscapePath(paths[i]) + ":" + jabrefFileType(a.Type)
+	}
+	return strings.Join(parts, ";")
+}
+
+// bibdeskFileValue returns the value of a BibDesk bdsk-file-N field for the file
+// at relativePath (relative to the bib file): a base64-encoded keyed archive of
+// a dictionary holding the relative path. BibDesk resolves the file from this
+// path and adds its own bookmark data the next time it saves the entry.
+func bibdeskFileValue(relativePath string) string {
+	uid := func(n int) string {
+		return "<dict><key>CF$UID</key><integer>" + strconv.Itoa(n) + "</integer></dict>"
+	}
+	plist := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n" +
+		`<plist version="1.0"><dict>` +
+		`<key>$archiver</key><string>NSKeyedArchiver</string>` +
+		`<key>$objects</key><array>` +
+		`<string>$null</string>` +
+		`<dict><key>$class</key>` + uid(4) +
+		`<key>NS.keys</key><array>` + uid(2) + `</array>` +
+		`<key>NS.objects</key><array>` + uid(3) + `</array></dict>` +
+		`<string>relativePath</string>` +
+		`<string>` + html.EscapeString(relativePath) + `</string>` +
+		`<dict><key>$classes</key><array><string>NSDictionary</string><string>NSObject</string></array>` +
+		`<key>$classname</key><string>NSDictionary</string></dict>` +
+		`</array>` +
+		`<key>$top</key><dict><key>root</key>` + uid(1) + `</dict>` +
+		`<key>$version</key><integer>100000</integer>` +
+		`</dict></plist>` + "\n"
+	return base64.StdEncoding.EncodeToString([]byte(plist))
+}
+
+// bibdeskFileFieldLimit is the number of bdsk-file-N fields BibTeXAllowedEntryFields knows.
+const bibdeskFileFieldLimit = 9
+
+// entryFileFields returns the field assignments linking the paper and attachments
+// of canonicalKey in a get-output file (pdf_files = "global" or "local"):
+//
+//   - BibDesk: local-url = {<paper path>}, plus bdsk-file-1 … bdsk-file-9 for the
+//     paper and the other attachments, relative to the output bib file.
+//   - JabRef: one file = {:path:PDF;slides:path:PDF;…} field.
+//
+// In local mode the files are those in localFilesDir, named after outputKey
+// (see syncLocalPDFs).
+func (l *TBibTeXLibrary) entryFileFields(canonicalKey, outputKey, localFilesDir string, cfg TBibGetConfig) string {
+	attachments := l.EntryAttachments(canonicalKey)
+	if len(attachments) == 0 {
+		return ""
+	}
+
+	// Absolute paths, and the paths as written for JabRef: absolute for global,
+	// and relative from the bib directory (<stem>.files/<name>) for local.
+	absPaths := make([]string, len(attachments))
+	jabrefPaths := make([]string, len(attachments))
+	for i, a := range attachments {
+		switch cfg.PDFFiles {
+		case "global":
+			absPaths[i] = l.AttachmentPath(a)
+			jabrefPaths[i] = absPaths[i]
+		case "local":
+			if localFilesDir == "" {
+				return ""
+			}
+			name := attachmentFileName(outputKey, a.Role, a.Index, a.Type)
+			absPaths[i] = localFilesDir + name
+			jabrefPaths[i] = filepath.Base(strings.TrimSuffix(localFilesDir, string(filepath.Separator))) +
+				string(filepath.Separator) + name
+		default:
+			return ""
+		}
+	}
+
+	if cfg.Format == "jabref" {
+		return FormatBibTeXFieldAssignment("", JabrefFileField, jabrefFileValue(attachments, jabrefPaths))
+	}
+
+	result := ""
+	if attachments[0].Role == AttachmentRolePaper {
+		result += FormatBibTeXFieldAssignment("", LocalURLField, absPaths[0])
+	}
+	for i, path := range absPaths {
+		if i == bibdeskFileFieldLimit {
+			break
+		}
+		relativePath := path
+		if cfg.outputDir != "" {
+			if rel, err := filepath.Rel(cfg.outputDir, path); err == nil {
+				relativePath = rel
+			}
+		}
+		result += FormatBibTeXFieldAssignment("", "bdsk-file-"+strconv.Itoa(i+1), bibdeskFileValue(relativePath))
+	}
+	return result
+}
+
+// --- Adding attachments ---
+
+// doAttach attaches a file to an entry: -attach <key> <role> <file>.
+func doAttach(args []string) {
+	if !openLibraryToUpdate() {
+		return
+	}
+	key := resolveInputKey(cleanKey(args[0]))
+	if !Library.EntryExists(key) {
+		Library.Warning(WarningAttachUnknownKey, args[0])
+		return
+	}
+	srcPath, err := filepath.Abs(args[2])
+	if err == nil && !FileExists(srcPath) {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		Library.Warning(WarningAttachmentNotAdded, args[2], key, err)
+		return
+	}
+	a, err := Library.AddAttachment(key, strings.ToLower(args[1]), srcPath)
+	if err != nil {
+		Library.Warning(WarningAttachmentNotAdded, args[2], key, err)
+		return
+	}
+	Library.Progress(ProgressAttachmentAdded, filepath.Base(srcPath), key, a.Role, a.Name)
+}
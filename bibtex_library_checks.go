@@ -253,11 +253,12 @@ func (l *TBibTeXLibrary) CheckKeyOldiesConsistency() {
 	// a non-EP-format oldie is a citation shorthand or demoted preferred alias that
 	// belongs in key_hints instead — migrate it there. Predates the AddKeyAlias
 	// guard that now prevents new violations; this repairs any left over from
-	// before that guard existed.
+	// before that guard existed. Preferred aliases replaced under a new alias
+	// template are the exception (see keepReplacedAlias).
 	var misplaced []struct{ oldie, key string }
 	var ghosts []struct{ oldie, key string }
 	l.KeyOldies.ForEachPersistent(func(oldie, key string) {
-		if !IsValidKey(oldie) {
+		if !IsValidKey(oldie) && !l.replacedAlias(oldie, key) {
 			misplaced = append(misplaced, struct{ oldie, key string }{oldie, key})
 			return
 		}
@@ -515,6 +516,17 @@ func splitOnUnbracedSpaces(s string) []string {
 // Year falls back to the crossref parent's year if the entry has none.
 // Warns and returns "" if surname or year cannot be determined after all fallbacks.
 func (l *TBibTeXLibrary) deriveAliasBase(entry *TBibTeXEntry) string {
+	surname, year := l.deriveAliasSurnameAndYear(entry)
+	if surname == "" || year == "" {
+		return ""
+	}
+	return surname + year
+}
+
+// deriveAliasSurnameAndYear returns the two parts of deriveAliasBase separately,
+// for the alias templates (see bibtex_library_alias_template.go). Both are ""
+// when either cannot be determined.
+func (l *TBibTeXLibrary) deriveAliasSurnameAndYear(entry *TBibTeXEntry) (string, string) {
 	nameField := entry.FieldValue("author")
 	if nameField == "" {
 		nameField = entry.FieldValue("editor")
@@ -559,7 +571,7 @@ func (l *TBibTeXLibrary) deriveAliasBase(entry *TBibTeXEntry) string {
 	}
 	if nameField == "" {
 		l.ReportEntryWarning(entry.Key, WarningCannotDeriveAliasNoName)
-		return ""
+		return "", ""
 	}
 
 	first := strings.TrimSpace(strings.Split(nameField, " and ")[0])
@@ -570,8 +582,8 @@ func (l *TBibTeXLibrary) deriveAliasBase(entry *TBibTeXEntry) string {
 	} else {
 		tokens := splitOnUnbracedSpaces(first)
 		if len(tokens) == 0 {
-			l.Warning(WarningCannotDeriveAliasNoName, entry.Key)
-			return ""
+			l.Warning("Entry %s: "+WarningCannotDeriveAliasNoName, entry.Key)
+			return "", ""
 		}
 		surnameRaw = tokens[len(tokens)-1]
 		if allDigits.MatchString(surnameRaw) {
@@ -586,12 +598,12 @@ func (l *TBibTeXLibrary) deriveAliasBase(entry *TBibTeXEntry) string {
 
 	if strings.Contains(surnameRaw, `\unicode{`) {
 		l.ReportEntryWarning(entry.Key, WarningCannotDeriveAliasEmptySurname, surnameRaw)
-		return ""
+		return "", ""
 	}
 	surname := stripNonAlphaNum.ReplaceAllString(TeXStringIndexer(surnameRaw), "")
 	if surname == "" {
 		l.ReportEntryWarning(entry.Key, WarningCannotDeriveAliasEmptySurname, surnameRaw)
-		return ""
+		return "", ""
 	}
 
 	year := entry.FieldValue("year")
@@ -600,10 +612,10 @@ func (l *TBibTeXLibrary) deriveAliasBase(entry *TBibTeXEntry) string {
 	}
 	if !IsValidYear(year) {
 		l.ReportEntryWarning(entry.Key, WarningCannotDeriveAliasNoYear)
-		return ""
+		return "", ""
 	}
 
-	return surname + year
+	return surname, year
 }
 
 // derivePreferredAlias returns the first non-colliding <surname><year><keyword>
@@ -613,7 +625,14 @@ func (l *TBibTeXLibrary) deriveAliasBase(entry *TBibTeXEntry) string {
 // If all keyword combinations are exhausted (or the title has no usable keywords),
 // the last segment of the DBLP key is tried as a final fallback.
 // Returns "" if base data is missing (silent) or all approaches are exhausted (warns).
+// With an alias template configured, the template decides instead.
 func (l *TBibTeXLibrary) derivePreferredAlias(entry *TBibTeXEntry) string {
+	if aliasTemplate != "" {
+		return l.deriveTemplateAlias(entry, aliasTemplate, aliasSuffix, func(candidate string) bool {
+			return l.aliasTakenByOther(entry.Key, candidate)
+		})
+	}
+
 	base := l.deriveAliasBase(entry)
 	if base == "" {
 		return ""
@@ -695,6 +714,22 @@ func (l *TBibTeXLibrary) CheckAndEnforcePreferredAlias(entry *TBibTeXEntry) {
 			l.AddKeyHint(alias, entry.Key)
 		}
 
+		if aliasTemplate != "" {
+			// Aliases set by hand or by a script are kept, as are those the
+			// current template yields.
+			if l.aliasFitsTemplate(entry, alias, aliasTemplate, aliasSuffix) || !l.preferredAliasGenerated(entry.Key, alias) {
+				return
+			}
+			// The alias stems from another template (or none): derive one from the
+			// current template, keeping the old alias so existing papers still resolve.
+			if derived := l.derivePreferredAlias(entry); derived != "" && derived != alias {
+				l.setGeneratedPreferredAlias(entry, derived)
+				l.keepReplacedAlias(alias, entry.Key)
+				l.Progress(ProgressReplacedPreferredAlias, alias, derived, entry.Key)
+			}
+			return
+		}
+
 		if validPreferredKeyAlias.MatchString(alias) {
 			return
 		}
@@ -703,14 +738,14 @@ func (l *TBibTeXLibrary) CheckAndEnforcePreferredAlias(entry *TBibTeXEntry) {
 		l.ReportEntryWarning(entry.Key, WarningInvalidPreferredKeyAlias, alias)
 		if derived := l.derivePreferredAlias(entry); derived != "" {
 			// Keep old non-compliant alias as a hint; set the derived one.
-			l.setPreferredAlias(entry, derived)
+			l.setGeneratedPreferredAlias(entry, derived)
 			l.Progress(ProgressGeneratedPreferredAlias, derived, entry.Key)
 		}
 		return
 	}
 
 	if derived := l.derivePreferredAlias(entry); derived != "" {
-		l.setPreferredAlias(entry, derived)
+		l.setGeneratedPreferredAlias(entry, derived)
 		l.Progress(ProgressGeneratedPreferredAlias, derived, entry.Key)
 	}
 }
@@ -1726,7 +1761,7 @@ func (l *TBibTeXLibrary) CheckDBLP(keyRAW string) {
 						childOptions := TStringSetNew()
 						childOptions.Add("k", "y", "n", "s", "S")
 						for {
-							answer := l.WarningQuestion(QuestionNoDblpKeyForChildAction, childOptions, "")
+							answer := l.WarningQuestion(QuestionNoDblpKeyForChildAction, childOptions, "", childKey)
 							if answer == "s" {
 								l.displayDblpChildrenToC(entryDBLP, key)
 								continue
@@ -1740,7 +1775,7 @@ func (l *TBibTeXLibrary) CheckDBLP(keyRAW string) {
 							}
 							switch answer {
 							case "k":
-								if typed, err := Reporting.AskForInput("DBLP key"); err == nil && typed != "" {
+								if typed, err := Reporting.AskForInput("DBLP key", childKey); err == nil && typed != "" {
 									if dblpKey := l.resolveTypedDblpKey(childKey, typed); dblpKey != "" {
 										l.AssociateDblpKey(childKey, dblpKey)
 										sessionManualDblpAssignments++
@@ -1834,7 +1869,7 @@ func (l *TBibTeXLibrary) displayDblpEntry(dblpKey string) {
 // displayKey is passed through to resolveTypedDblpKey for context if the typed
 // key needs typo recovery (see resolveTypedDblpKey).
 func (l *TBibTeXLibrary) askAndDisplayDblpEntry(displayKey string) {
-	typed, err := l.AskForInput("DBLP key to look up")
+	typed, err := l.AskForInput("DBLP key to look up", displayKey)
 	if err != nil || typed == "" {
 		return
 	}
@@ -1910,6 +1945,8 @@ func (l *TBibTeXLibrary) CheckEntry(entry *TBibTeXEntry) {
 			l.CheckURLDate(entry)
 			l.CheckWithdrawn(entry)
 			l.CheckGarbledContributors(entry)
+			l.CheckLintRules(entry)
+			l.CheckEntryVenue(entry)
 		}
 	}
 }
@@ -2194,9 +2231,9 @@ func (l *TBibTeXLibrary) CheckLoneProceedings() {
 		l.printWarningLine(WarningLoneProceedings, key)
 		fmt.Fprint(os.Stderr, l.entryDisplayString(key))
 
-		switch l.WarningQuestion(QuestionLoneProceedings, validAnswers, "") {
+		switch l.WarningQuestion(QuestionLoneProceedings, validAnswers, "", key) {
 		case "k":
-			if typed, err := Reporting.AskForInput("DBLP key"); err == nil && typed != "" {
+			if typed, err := Reporting.AskForInput("DBLP key", key); err == nil && typed != "" {
 				if dblpKey := l.resolveTypedDblpKey(key, typed); dblpKey != "" {
 					Library.AssociateDblpKey(key, dblpKey)
 					sessionManualDblpAssignments++
@@ -314,6 +314,8 @@ func loadBibTeXSettings() {
 		}
 	}
 	defaultLangID = GetConfig(DefaultLangIDConfigKey, DefaultLangIDFallback)
+	aliasTemplate = GetConfig(AliasTemplateConfigKey, "")
+	aliasSuffix = GetConfig(AliasSuffixConfigKey, AliasSuffixLetters)
 	SetConfig("version", AppVersion)
 }
 
@@ -827,6 +829,24 @@ func contributorEntryKeys(id string) []string {
 	return keys
 }
 
+// contributorRoleIDs returns the contributor IDs linked to entry key in role
+// ("author" or "editor").
+func contributorRoleIDs(key, role string) []string {
+	rows, err := bibQuery(`SELECT DISTINCT contributor_id FROM contributor_roles WHERE entry_key = ? AND role = ?`, key, role)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // entryExistsWithDOI reports whether any library entry already carries the given
 // DOI value (case-insensitive, after stripping any "https://doi.org/" prefix).
 // normalizeDOI returns the canonical lower-case DOI without any https?://doi.org/ prefix.
@@ -2439,13 +2459,28 @@ func LookupDblpCanonical(dblpKey string) string {
 // --- entry_flags → entry_metadata (v23.4 merge) ---
 //
 // entry_flags is now stored inside entry_metadata with value = 'true'.
-// knownEntryFlags lists every flag property name so load/save can target only
-// those rows and leave unrelated metadata rows untouched.
+// knownEntryFlags lists every fixed flag property name, and lint waivers share
+// FlagLintWaivedPrefix, so load/save can target only those rows and leave
+// unrelated metadata rows untouched.
 
 func knownEntryFlags() []string {
 	return []string{EntryFlagNoDBLPChildren, FlagLoneProceedingsWaived}
 }
 
+// entryFlagPropertySQL returns a WHERE condition on entry_metadata.property that
+// selects the flag rows, together with its arguments.
+func entryFlagPropertySQL() (string, []any) {
+	flags := knownEntryFlags()
+	placeholders := strings.Repeat("?,", len(flags))
+	placeholders = placeholders[:len(placeholders)-1]
+	args := make([]any, len(flags), len(flags)+1)
+	for i, f := range flags {
+		args[i] = f
+	}
+	args = append(args, FlagLintWaivedPrefix+"%")
+	return `(property IN (` + placeholders + `) OR property LIKE ?)`, args
+}
+
 // maybeConsolidateEntryFlags migrates the legacy entry_flags table into
 // entry_metadata (value = 'true'), then drops the old table.
 func maybeConsolidateEntryFlags() {
@@ -2471,15 +2506,9 @@ func maybeConsolidateEntryFlags() {
 }
 
 func loadEntryFlagsFromDb(l *TBibTeXLibrary) {
-	flags := knownEntryFlags()
-	placeholders := strings.Repeat("?,", len(flags))
-	placeholders = placeholders[:len(placeholders)-1]
-	args := make([]any, len(flags))
-	for i, f := range flags {
-		args[i] = f
-	}
+	flagCond, args := entryFlagPropertySQL()
 	rows, err := db.Query(
-		`SELECT entry_key, property FROM entry_metadata WHERE value = 'true' AND property IN (`+placeholders+`)`,
+		`SELECT entry_key, property FROM entry_metadata WHERE value = 'true' AND `+flagCond,
 		args...)
 	if err != nil {
 		dbInteraction.Warning("Could not query entry_flags from entry_metadata: %s", err)
@@ -2499,14 +2528,8 @@ func loadEntryFlagsFromDb(l *TBibTeXLibrary) {
 }
 
 func saveEntryFlagsToDb(l *TBibTeXLibrary) {
-	flags := knownEntryFlags()
-	placeholders := strings.Repeat("?,", len(flags))
-	placeholders = placeholders[:len(placeholders)-1]
-	args := make([]any, len(flags))
-	for i, f := range flags {
-		args[i] = f
-	}
-	db.Exec(`DELETE FROM entry_metadata WHERE value = 'true' AND property IN (`+placeholders+`)`, args...)
+	flagCond, args := entryFlagPropertySQL()
+	db.Exec(`DELETE FROM entry_metadata WHERE value = 'true' AND `+flagCond, args...)
 	upsert := `INSERT OR IGNORE INTO entry_metadata (entry_key, property, value) VALUES (?, ?, 'true')`
 	for key, flagSet := range l.EntryFlags {
 		for flag := range flagSet.Elements() {
@@ -2873,9 +2896,11 @@ func ensureEntryLineageTableExists() {
 		  value      TEXT NOT NULL DEFAULT '',
 		  source     TEXT NOT NULL DEFAULT '',
 		  edited     INTEGER NOT NULL DEFAULT 0,
+		  policy     TEXT NOT NULL DEFAULT '',
 		  PRIMARY KEY (entry_key, field)
 		);`)
 	maybeMigrateEntryLineageValue()
+	db.Exec(`ALTER TABLE entry_lineage ADD COLUMN policy TEXT NOT NULL DEFAULT ''`) //nolint:errcheck
 }
 
 // maybeMigrateEntryLineageValue adds the value column to entry_lineage for databases
@@ -2925,23 +2950,23 @@ func ensureSourceContributorSignaturesTableExists() {
 }
 
 func loadEntryLineageFromDb(l *TBibTeXLibrary) {
-	rows, err := db.Query(`SELECT entry_key, field, value, source, edited FROM entry_lineage`)
+	rows, err := db.Query(`SELECT entry_key, field, value, source, edited, policy FROM entry_lineage`)
 	if err != nil {
 		dbInteraction.Warning("Could not query entry_lineage: %s", err)
 		return
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var key, field, value, source string
+		var key, field, value, source, policy string
 		var edited int
-		if err := rows.Scan(&key, &field, &value, &source, &edited); err != nil {
+		if err := rows.Scan(&key, &field, &value, &source, &edited, &policy); err != nil {
 			dbInteraction.Warning("Could not scan entry_lineage row: %s", err)
 			continue
 		}
 		if _, ok := l.LineageMap[key]; !ok {
 			l.LineageMap[key] = map[string]TLineageRecord{}
 		}
-		l.LineageMap[key][field] = TLineageRecord{Value: value, Source: source, Edited: edited != 0}
+		l.LineageMap[key][field] = TLineageRecord{Value: value, Source: source, Edited: edited != 0, Policy: policy}
 	}
 }
 
@@ -3027,15 +3052,23 @@ func ensureEntryWarningsTableExists() {
 		);`)
 }
 
+// touchEntryWarnings records that entry_warnings changed, so that ETags over it
+// (-serve) change as well.
+func touchEntryWarnings() {
+	setTableDate("entry_warnings", time.Now().UnixMicro())
+}
+
 // clearEntryWarnings deletes all rows — called once at the start of each normal check run.
 func clearEntryWarnings() {
 	dbExecSave("clearEntryWarnings", `DELETE FROM entry_warnings`)
+	touchEntryWarnings()
 }
 
 // deleteEntryWarning removes a specific (key, warning) row, e.g. when a warning
 // is subsequently waived and should not appear in repair.bib or warnings; selects.
 func deleteEntryWarning(key, warning string) {
 	dbExecSave("deleteEntryWarning", `DELETE FROM entry_warnings WHERE key = ? AND warning = ?`, key, warning)
+	touchEntryWarnings()
 }
 
 // insertEntryWarning records key+warning, silently ignoring exact duplicates.
@@ -3052,6 +3085,7 @@ func insertEntryWarning(key, warning string) {
 		return
 	}
 	dbExecSave("insertEntryWarning", `INSERT OR IGNORE INTO entry_warnings (key, warning) VALUES (?, ?)`, key, warning)
+	touchEntryWarnings()
 }
 
 // entryWarningTexts returns all non-empty warning strings for key, sorted alphabetically.
@@ -3276,6 +3310,7 @@ func beginBibTransaction() {
 	if txDepth > 1 {
 		return
 	}
+	hookQueueAtTx = len(hookQueue)
 	var err error
 	activeTx, err = db.Begin()
 	if err != nil {
@@ -3299,6 +3334,8 @@ func commitBibTransaction() {
 		}
 		activeTx = nil
 	}
+	// Events queued while the transaction was open can now be dispatched.
+	Library.flushHooksIfSafe()
 }
 
 func rollbackBibTransaction() {
@@ -3307,6 +3344,8 @@ func rollbackBibTransaction() {
 		activeTx.Rollback()
 		activeTx = nil
 	}
+	// The changes behind the events queued in the transaction are gone.
+	discardHooksSince(hookQueueAtTx)
 }
 
 // forceCommitBibTransaction commits and clears any open bib transaction regardless of
@@ -3372,6 +3411,7 @@ func closeEntry(entry *TBibTeXEntry) bool {
 				delete(entry.Fields, field)
 				continue
 			}
+			Library.fireFieldChanged(entry.Key, field, snapshot[field], value)
 			if contributorRolesActive && (field == "author" || field == "editor") {
 				if value != "" {
 					// Same reasoning as upsertBibEntryField's matching guard: this
@@ -3403,6 +3443,7 @@ func closeEntry(entry *TBibTeXEntry) bool {
 					dbInteraction.Warning("bib_entries write failed for %s.%s: %s", entry.Key, field, err)
 					markDbWriteFailed(fmt.Sprintf("closeEntry: bib_entries write failed (key=%s, field=%s, value=%s): %s", entry.Key, field, value, err))
 				}
+				updateEntrySearchIndex(entry.Key, field, value)
 				// upsertBibEntryField is the normal write path for a single field and
 				// keeps dblp_canonical in sync; this per-field loop bypasses it entirely
 				// (it batches every changed field from one open/closeEntry span), so it
@@ -3431,6 +3472,7 @@ func closeEntry(entry *TBibTeXEntry) bool {
 	for field := range snapshot {
 		if _, exists := entry.Fields[field]; !exists {
 			changed = true
+			Library.fireFieldChanged(entry.Key, field, snapshot[field], "")
 			if contributorRolesActive && (field == "author" || field == "editor") {
 				upsertContributorRolesForField(&Library, entry.Key, field, "")
 			} else {
@@ -3438,6 +3480,7 @@ func closeEntry(entry *TBibTeXEntry) bool {
 					dbInteraction.Warning("bib_entries delete failed for %s.%s: %s", entry.Key, field, err)
 					markDbWriteFailed(fmt.Sprintf("closeEntry: bib_entries delete failed (key=%s, field=%s): %s", entry.Key, field, err))
 				}
+				updateEntrySearchIndex(entry.Key, field, "")
 				if field == DBLPField {
 					deleteDblpCanonicalByCanonicalKey(entry.Key)
 					Library.DeleteMetadata(entry.Key, MetaPropDblpKeyMissing)
@@ -3460,12 +3503,14 @@ func closeEntry(entry *TBibTeXEntry) bool {
 	return changed
 }
 
-// clearBibTables removes all rows from the three bib tables without dropping them.
+// clearBibTables removes all rows from the three bib tables, and from the search
+// index that mirrors bib_entries, without dropping them.
 func clearBibTables() {
 	for _, stmt := range []string{
 		`DELETE FROM bib_entries;`,
 		`DELETE FROM bib_groups;`,
 		`DELETE FROM bib_comments;`,
+		`DELETE FROM bib_entries_fts;`,
 	} {
 		if _, err := db.Exec(stmt); err != nil {
 			dbInteraction.Warning("Could not clear bib table: %s", err)
@@ -3910,6 +3955,8 @@ func upsertBibEntryField(key, field, value string) {
 	if err != nil {
 		dbInteraction.Warning("bib_entries write failed for %s.%s: %s", key, field, err)
 		markDbWriteFailed(fmt.Sprintf("upsertBibEntryField: bib_entries write failed (key=%s, field=%s, value=%s): %s", key, field, value, err))
+	} else {
+		updateEntrySearchIndex(key, field, value)
 	}
 	if entryCache != nil {
 		if value == "" {
@@ -3953,6 +4000,7 @@ func deleteBibEntryField(key, field string) {
 		if err := bibExec(`DELETE FROM bib_entries WHERE entry_key = ? AND field = ?`, key, field); err != nil {
 			dbInteraction.Warning("bib_entries delete failed for %s.%s: %s", key, field, err)
 		}
+		updateEntrySearchIndex(key, field, "")
 		// Mirror upsertBibEntryField's empty-value branch: clearing the dblp field
 		// must also drop the dblp_canonical row, or the old dblp key keeps resolving
 		// to this entry (via LookupDblpCanonical/buildKeyAliasesFromDb) even though
@@ -4012,6 +4060,7 @@ func deleteBibEntry(key string) {
 	dbExecSave("deleteBibEntry: entry_lineage", `DELETE FROM entry_lineage WHERE entry_key = ?`, key)
 	dbExecSave("deleteBibEntry: source_field_signatures", `DELETE FROM source_field_signatures WHERE entry_key = ?`, key)
 	dbExecSave("deleteBibEntry: source_contributor_signatures", `DELETE FROM source_contributor_signatures WHERE entry_key = ?`, key)
+	deleteEntrySearchIndex(key)
 	delete(Library.LineageMap, key)
 	delete(Library.SourceSignatures, key)
 	if entryCache != nil {
@@ -4248,17 +4297,23 @@ func findBibEntriesByGroup(groupFilter string) []TBibFieldMatch {
 
 // addBibGroupEntry adds entryKey to groupName in bib_groups; no-op if already present.
 func addBibGroupEntry(groupName, entryKey string) error {
-	_, err := db.Exec(
+	result, err := db.Exec(
 		`INSERT OR IGNORE INTO bib_groups (group_name, entry_key) VALUES (?, ?)`,
 		groupName, entryKey)
+	if err == nil {
+		fireGroupChanged(result, groupName, entryKey, true)
+	}
 	return err
 }
 
 // removeBibGroupEntry removes entryKey from groupName in bib_groups; no-op if not present.
 func removeBibGroupEntry(groupName, entryKey string) error {
-	_, err := db.Exec(
+	result, err := db.Exec(
 		`DELETE FROM bib_groups WHERE group_name = ? AND entry_key = ?`,
 		groupName, entryKey)
+	if err == nil {
+		fireGroupChanged(result, groupName, entryKey, false)
+	}
 	return err
 }
 
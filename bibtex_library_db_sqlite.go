@@ -119,6 +119,8 @@ func connectToDatabase() {
 	ensureIgnoreTitlesTableExists()
 	ensureEntryMetadataTableExists()
 	ensureEntryLineageTableExists()
+	ensurePolicyDecisionsTableExists()
+	ensureEntryVenuesTableExists()
 	ensureSourceFieldSignaturesTableExists()
 	ensureSourceContributorSignaturesTableExists()
 	ensureSupersededFieldValuesTableExists()
@@ -153,6 +155,9 @@ func connectToDatabase() {
 	ensureShortenMappingsTableExists()
 	ensureBibEntryKeysTableExists()
 	ensureBibTablesExist()
+	ensureEntrySearchIndexExists()
+	ensureSessionJournalExists()
+	clearSessionJournalState()
 	contributorRolesActive = tableModTime("contributor_roles") > 0
 }
 
@@ -332,6 +337,8 @@ func reopenDb(path string) {
 	ensureIgnoreTitlesTableExists()
 	ensureEntryMetadataTableExists()
 	ensureEntryLineageTableExists()
+	ensurePolicyDecisionsTableExists()
+	ensureEntryVenuesTableExists()
 	ensureSourceFieldSignaturesTableExists()
 	ensureSourceContributorSignaturesTableExists()
 	ensureSupersededFieldValuesTableExists()
@@ -340,6 +347,8 @@ func reopenDb(path string) {
 	ensureShortenMappingsTableExists()
 	ensureBibEntryKeysTableExists()
 	ensureBibTablesExist()
+	ensureEntrySearchIndexExists()
+	ensureSessionJournalExists()
 	contributorRolesActive = tableModTime("contributor_roles") > 0
 }
 
@@ -445,6 +454,9 @@ func dbIsolationActive() bool {
 // crash and offers the user a chance to restore. Returns false on setup failure.
 func prepareWorkingDatabase() bool {
 	if !dbIsolationActive() {
+		if !dbWriteSessionActive {
+			beginSessionJournal()
+		}
 		dbWriteSessionActive = true
 		return true
 	}
@@ -1027,6 +1039,7 @@ func markWriteSessionOpen() {
 	now := time.Now().UnixMicro()
 	setTableDate("write_session_open", now)
 	setTableDate("write_session_closed", 0)
+	beginSessionJournal()
 }
 
 func markWriteSessionClosed() {
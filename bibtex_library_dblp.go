@@ -367,6 +367,11 @@ func (l *TBibTeXLibrary) MaybeAddDBLPEntry(DBLPKey string) string {
 		return existing
 	}
 
+	// Hold entry_created hooks until this entry (and, for bookish entries, its
+	// children) is complete; they then run straight away when no transaction is open.
+	holdHooks()
+	defer l.releaseHooks()
+
 	key := l.NewKey()
 
 	// Register the DBLP alias in memory BEFORE the merge, not after. A bookish
@@ -445,6 +450,8 @@ func (l *TBibTeXLibrary) MaybeAddDBLPEntry(DBLPKey string) string {
 		}
 	}
 
+	l.fireHook(THookEvent{Event: HookEntryCreated, Keys: []string{key}, Source: "dblp"})
+
 	return key
 }
 
@@ -562,10 +569,13 @@ func (l *TBibTeXLibrary) AskCandidateDblpKey(key string, candidates []string) st
 	for i := range candidates {
 		options.Add(fmt.Sprintf("%d", i+1))
 	}
+	// The candidates are part of the question id, so that a replayed number
+	// only picks from the same list.
+	askingAbout(candidates...)
 	answer := l.WarningQuestion(QuestionExtendDblpCoverageChoose, options,
 		WarningExtendDblpCandidatesFound, key, len(candidates))
 	if answer == "k" {
-		if dblpKey, err := Reporting.AskForInput("DBLP key"); err == nil && dblpKey != "" {
+		if dblpKey, err := Reporting.AskForInput("DBLP key", key); err == nil && dblpKey != "" {
 			return l.resolveTypedDblpKey(key, dblpKey)
 		}
 		return ""
@@ -637,6 +647,10 @@ func (l *TBibTeXLibrary) maybeFixDBLPEntry(key string) bool {
 }
 
 func (l *TBibTeXLibrary) MaybeAddDBLPChildEntry(DBLPKey, crossref string) string {
+	// The child is only complete once its crossref is set.
+	holdHooks()
+	defer l.releaseHooks()
+
 	if key := l.MaybeAddDBLPEntry(DBLPKey); key != "" && crossref != "" {
 		splitCrossref := l.CheckNeedToSplitBookishEntry(key)
 		if splitCrossref != "" {
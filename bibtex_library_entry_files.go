@@ -0,0 +1,757 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_entry_files
+ *
+ * Git-friendly textual export / import of the whole library: one canonical text
+ * file per entry and one per contributor, in <basename>.entries/ (or a folder
+ * given on the command line):
+ *
+ *   entries/<key>.entry            fields, lineage, flags, metadata, groups, aliases
+ *   contributors/<id>.contributor  name, ORCIDs, DBLP key, name variants, absorbed IDs
+ *
+ * Every file is a list of "name = value" lines in a fixed section order, sorted
+ * within each section, with backslash, newline and carriage return escaped so a
+ * value always fits on one line. A change to one entry therefore touches exactly
+ * one file, and two people curating on separate machines can merge with plain git.
+ *
+ * Export only rewrites files whose content changed, and removes files for entries
+ * and contributors no longer in the library. Import is an update, not a replace:
+ * each file is compared with what an export would write for the current DB, and
+ * only files that differ are applied. Entries present in the DB but without a file
+ * are deleted after confirmation; contributors without a file are left alone (the
+ * orphan clean-up removes them once no entry refers to them any more).
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	entryFilesEntriesFolder      = "entries"
+	entryFilesContributorsFolder = "contributors"
+	entryFileExtension           = ".entry"
+	contributorFileExtension     = ".contributor"
+
+	entryFileHeader       = "% bibtex_check entry"
+	contributorFileHeader = "% bibtex_check contributor"
+
+	entryFileLineageEdited = " (edited)"
+)
+
+// TEntryFileLine is one "name = value" line of an entry or contributor file.
+type TEntryFileLine struct {
+	Name  string
+	Value string
+}
+
+// entryFilesFolder returns the export folder: folder when given, else <basename>.entries/.
+func entryFilesFolder(folder string) string {
+	if folder == "" {
+		folder = bibTeXFolder + bibTeXBaseName + entriesFolderSuffix
+	}
+	return strings.TrimSuffix(folder, "/") + "/"
+}
+
+// entryFileName maps a key or contributor ID to a file name that is safe on every
+// platform. The key itself is also recorded inside the file, so the name is only
+// used to find the file, never to interpret it.
+func entryFileName(id, extension string) string {
+	return strings.ReplaceAll(url.PathEscape(id), ":", "%3A") + extension
+}
+
+// escapeEntryFileValue keeps a value on a single line.
+func escapeEntryFileValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, "\r", `\r`)
+}
+
+// unescapeEntryFileValue reverses escapeEntryFileValue.
+func unescapeEntryFileValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// formatEntryFile renders header plus lines as file content.
+func formatEntryFile(header string, lines []TEntryFileLine) string {
+	var b strings.Builder
+	b.WriteString(header + "\n")
+	for _, line := range lines {
+		b.WriteString(line.Name + " = " + escapeEntryFileValue(line.Value) + "\n")
+	}
+	return b.String()
+}
+
+// readEntryFile parses an entry or contributor file. Lines starting with % and
+// blank lines are ignored. Returns false when the file cannot be read or a line
+// is not of the form "name = value".
+func readEntryFile(path string) ([]TEntryFileLine, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		dbInteraction.Warning("Could not open %s: %s", path, err)
+		return nil, false
+	}
+	defer f.Close()
+	var lines []TEntryFileLine
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "%") {
+			continue
+		}
+		name, value, ok := strings.Cut(text, " = ")
+		if !ok {
+			// "name =" with an empty value loses its trailing space in some editors.
+			if strings.HasSuffix(text, " =") {
+				name, value, ok = strings.TrimSuffix(text, " ="), "", true
+			}
+		}
+		if !ok || strings.TrimSpace(name) == "" {
+			dbInteraction.Warning("%s:%d: expected \"name = value\", found %q", path, lineNo, text)
+			return nil, false
+		}
+		lines = append(lines, TEntryFileLine{strings.TrimSpace(name), unescapeEntryFileValue(value)})
+	}
+	if err := scanner.Err(); err != nil {
+		dbInteraction.Warning("Could not read %s: %s", path, err)
+		return nil, false
+	}
+	return lines, true
+}
+
+// entryFileValue returns the value of the first line called name, or "".
+func entryFileValue(lines []TEntryFileLine, name string) string {
+	for _, line := range lines {
+		if line.Name == name {
+			return line.Value
+		}
+	}
+	return ""
+}
+
+// writeEntryFileIfChanged writes content to path unless the file already holds
+// exactly that content. Returns true when the file was (re)written.
+func writeEntryFileIfChanged(path, content string) bool {
+	if old, err := os.ReadFile(path); err == nil && string(old) == content {
+		return false
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		dbInteraction.Warning("Could not write %s: %s", path, err)
+		return false
+	}
+	return true
+}
+
+// removeStaleEntryFiles deletes files with extension in folder that are not in keep.
+func removeStaleEntryFiles(folder, extension string, keep map[string]bool) int {
+	des, err := os.ReadDir(folder)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, de := range des {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), extension) || keep[de.Name()] {
+			continue
+		}
+		if err := os.Remove(folder + de.Name()); err != nil {
+			dbInteraction.Warning("Could not remove %s: %s", folder+de.Name(), err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// listEntryFiles returns the sorted paths of all files with extension in folder.
+func listEntryFiles(folder, extension string) []string {
+	des, err := os.ReadDir(folder)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, de := range des {
+		if !de.IsDir() && strings.HasSuffix(de.Name(), extension) {
+			paths = append(paths, folder+de.Name())
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ── Entry files ───────────────────────────────────────────────────────────────
+
+// isEntryFlagProperty reports whether prop is an entry flag stored in entry_metadata.
+func isEntryFlagProperty(prop string) bool {
+	if strings.HasPrefix(prop, FlagLintWaivedPrefix) {
+		return true
+	}
+	for _, flag := range knownEntryFlags() {
+		if prop == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// entryGroups returns the sorted static groups key belongs to.
+func (l *TBibTeXLibrary) entryGroups(key string) []string {
+	var groups []string
+	for group, members := range l.GroupEntries {
+		if members.Set().Contains(key) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// entryKeyHints returns the sorted key hints that resolve to key.
+func (l *TBibTeXLibrary) entryKeyHints(key string) []string {
+	var hints []string
+	l.HintToKey.ForEach(func(hint, target string) {
+		if l.MapEntryKey(target) == key {
+			hints = append(hints, hint)
+		}
+	})
+	sort.Strings(hints)
+	return hints
+}
+
+// entryKeyAliases returns the sorted persistent key aliases of key.
+func (l *TBibTeXLibrary) entryKeyAliases(key string) []string {
+	var aliases []string
+	l.KeyOldies.EachAlias(key, func(alias string) {
+		aliases = append(aliases, alias)
+	})
+	sort.Strings(aliases)
+	return aliases
+}
+
+// entryDoiAliases returns the sorted DOIs registered as aliases of key.
+func entryDoiAliases(key string) []string {
+	rows, err := bibQuery(`SELECT doi FROM entry_doi_aliases WHERE entry_key = ? ORDER BY doi`, key)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var dois []string
+	for rows.Next() {
+		var doi string
+		if rows.Scan(&doi) == nil {
+			dois = append(dois, doi)
+		}
+	}
+	return dois
+}
+
+// entryFileLines returns the canonical lines describing key. Sections appear in a
+// fixed order (key, field, lineage, flag, meta, group, alias, hint, doi_alias),
+// each sorted, so the same DB state always yields the same file.
+func (l *TBibTeXLibrary) entryFileLines(key string) []TEntryFileLine {
+	lines := []TEntryFileLine{{"key", key}}
+	entry := loadEntryFromDb(key)
+
+	fields := make([]string, 0, len(entry.Fields))
+	for field, value := range entry.Fields {
+		if value != "" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		lines = append(lines, TEntryFileLine{"field." + field, entry.Fields[field]})
+	}
+
+	// Only lineage that still describes the current value is worth keeping;
+	// getLineage treats anything else as unknown anyway.
+	for _, field := range fields {
+		if rec, ok := l.LineageMap[key][field]; ok && rec.Value == entry.Fields[field] {
+			source := rec.Source
+			if rec.Edited {
+				source += entryFileLineageEdited
+			}
+			lines = append(lines, TEntryFileLine{"lineage." + field, source})
+		}
+	}
+
+	if flags, ok := l.EntryFlags[key]; ok {
+		for _, flag := range flags.Set().ElementsSorted() {
+			lines = append(lines, TEntryFileLine{"flag", flag})
+		}
+	}
+
+	props := make([]string, 0, len(l.Metadata[key]))
+	for prop := range l.Metadata[key] {
+		if !isEntryFlagProperty(prop) {
+			props = append(props, prop)
+		}
+	}
+	sort.Strings(props)
+	for _, prop := range props {
+		lines = append(lines, TEntryFileLine{"meta." + prop, l.Metadata[key][prop]})
+	}
+
+	for _, group := range l.entryGroups(key) {
+		lines = append(lines, TEntryFileLine{"group", group})
+	}
+	for _, alias := range l.entryKeyAliases(key) {
+		lines = append(lines, TEntryFileLine{"alias", alias})
+	}
+	for _, hint := range l.entryKeyHints(key) {
+		lines = append(lines, TEntryFileLine{"hint", hint})
+	}
+	for _, doi := range entryDoiAliases(key) {
+		lines = append(lines, TEntryFileLine{"doi_alias", doi})
+	}
+	return lines
+}
+
+// ── Contributor files ─────────────────────────────────────────────────────────
+
+// contributorFileLines returns the canonical lines describing contributor id, or
+// nil when id is not in the contributors table.
+func contributorFileLines(id string) []TEntryFileLine {
+	var name, orcid, dblpKey string
+	var garbled int
+	if err := bibQueryRow(
+		`SELECT name, COALESCE(orcid, ''), COALESCE(dblp_key, ''), COALESCE(garbled, 0) FROM contributors WHERE id = ?`,
+		id).Scan(&name, &orcid, &dblpKey, &garbled); err != nil {
+		return nil
+	}
+	lines := []TEntryFileLine{{"id", id}, {"name", name}}
+	if orcid != "" {
+		lines = append(lines, TEntryFileLine{"orcid", orcid})
+	}
+	if dblpKey != "" {
+		lines = append(lines, TEntryFileLine{"dblp_key", dblpKey})
+	}
+	if garbled != 0 {
+		lines = append(lines, TEntryFileLine{"garbled", "true"})
+	}
+
+	queryColumn := func(query string) []string {
+		rows, err := bibQuery(query, id)
+		if err != nil {
+			return nil
+		}
+		defer rows.Close()
+		var values []string
+		for rows.Next() {
+			var v string
+			if rows.Scan(&v) == nil {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+	for _, variant := range queryColumn(`SELECT name FROM contributor_names WHERE id = ? ORDER BY name`) {
+		lines = append(lines, TEntryFileLine{"name_variant", variant})
+	}
+	for _, extra := range queryColumn(`SELECT orcid FROM contributor_orcids WHERE contributor_id = ? ORDER BY orcid`) {
+		if extra != orcid {
+			lines = append(lines, TEntryFileLine{"other_orcid", extra})
+		}
+	}
+	for _, absorbed := range queryColumn(`SELECT absorbed_id FROM contributor_id_oldies WHERE canonical_id = ? ORDER BY absorbed_id`) {
+		lines = append(lines, TEntryFileLine{"absorbed_id", absorbed})
+	}
+	return lines
+}
+
+// allContributorIDs returns every contributor ID, sorted.
+func allContributorIDs() []string {
+	rows, err := bibQuery(`SELECT id FROM contributors ORDER BY id`)
+	if err != nil {
+		dbInteraction.Warning("Could not query contributors: %s", err)
+		return nil
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ── Export ────────────────────────────────────────────────────────────────────
+
+// ExportEntryFiles writes the whole library to folder (or <basename>.entries/) as
+// one file per entry and per contributor. Unchanged files are left untouched.
+func (l *TBibTeXLibrary) ExportEntryFiles(folder string) {
+	folder = entryFilesFolder(folder)
+	entriesFolder := folder + entryFilesEntriesFolder + "/"
+	contributorsFolder := folder + entryFilesContributorsFolder + "/"
+	for _, dir := range []string{entriesFolder, contributorsFolder} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			dbInteraction.Warning("Could not create %s: %s", dir, err)
+			return
+		}
+	}
+
+	written := 0
+	keep := map[string]bool{}
+	ticker := l.NewProgressTicker("Exporting entries", countBibEntries())
+	forEachBibEntryKey(func(key string) bool {
+		ticker.Step()
+		name := entryFileName(key, entryFileExtension)
+		keep[name] = true
+		if writeEntryFileIfChanged(entriesFolder+name, formatEntryFile(entryFileHeader, l.entryFileLines(key))) {
+			written++
+		}
+		return true
+	})
+	ticker.Done()
+	removed := removeStaleEntryFiles(entriesFolder, entryFileExtension, keep)
+
+	contributorsWritten := 0
+	keep = map[string]bool{}
+	for _, id := range allContributorIDs() {
+		name := entryFileName(id, contributorFileExtension)
+		keep[name] = true
+		if writeEntryFileIfChanged(contributorsFolder+name, formatEntryFile(contributorFileHeader, contributorFileLines(id))) {
+			contributorsWritten++
+		}
+	}
+	contributorsRemoved := removeStaleEntryFiles(contributorsFolder, contributorFileExtension, keep)
+
+	dbInteraction.Progress("Exported library to %s: %d entry file(s) written, %d removed; %d contributor file(s) written, %d removed",
+		folder, written, removed, contributorsWritten, contributorsRemoved)
+}
+
+// ── Import ────────────────────────────────────────────────────────────────────
+
+// ImportEntryFiles applies folder (or <basename>.entries/) to the library as an
+// update. Contributors are applied first so that author/editor names in entry
+// files resolve to the right contributor IDs. Returns true when anything changed.
+func (l *TBibTeXLibrary) ImportEntryFiles(folder string) bool {
+	folder = entryFilesFolder(folder)
+	entriesFolder := folder + entryFilesEntriesFolder + "/"
+	contributorsFolder := folder + entryFilesContributorsFolder + "/"
+	if _, err := os.Stat(entriesFolder); err != nil {
+		dbInteraction.Warning("No entries folder found at %s", entriesFolder)
+		return false
+	}
+
+	// Parse everything before touching the DB, so a broken file aborts cleanly.
+	type tParsedFile struct {
+		path  string
+		id    string
+		lines []TEntryFileLine
+	}
+	parseAll := func(paths []string, idName string) ([]tParsedFile, bool) {
+		var parsed []tParsedFile
+		ok := true
+		for _, path := range paths {
+			lines, valid := readEntryFile(path)
+			id := entryFileValue(lines, idName)
+			if valid && id == "" {
+				dbInteraction.Warning("%s: missing %q line", path, idName)
+				valid = false
+			}
+			if !valid {
+				ok = false
+				continue
+			}
+			parsed = append(parsed, tParsedFile{path, id, lines})
+		}
+		return parsed, ok
+	}
+	contributorFiles, contributorsOk := parseAll(listEntryFiles(contributorsFolder, contributorFileExtension), "id")
+	entryFiles, entriesOk := parseAll(listEntryFiles(entriesFolder, entryFileExtension), "key")
+	if !contributorsOk || !entriesOk {
+		dbInteraction.Warning("Import aborted: invalid files in %s — library unchanged", folder)
+		return false
+	}
+
+	contributorsChanged := 0
+	for _, f := range contributorFiles {
+		if formatEntryFile(contributorFileHeader, contributorFileLines(f.id)) == formatEntryFile(contributorFileHeader, f.lines) {
+			continue
+		}
+		applyContributorFile(f.id, f.lines)
+		contributorsChanged++
+	}
+	if contributorsChanged > 0 {
+		loadContributorsFromDb(l)
+	}
+
+	entriesChanged := 0
+	inFolder := map[string]bool{}
+	ticker := l.NewProgressTicker("Importing entries", len(entryFiles))
+	for _, f := range entryFiles {
+		ticker.Step()
+		inFolder[f.id] = true
+		if formatEntryFile(entryFileHeader, l.entryFileLines(f.id)) == formatEntryFile(entryFileHeader, f.lines) {
+			continue
+		}
+		l.applyEntryFile(f.id, f.lines)
+		entriesChanged++
+	}
+	ticker.Done()
+
+	var missing []string
+	forEachBibEntryKey(func(key string) bool {
+		if !inFolder[key] {
+			missing = append(missing, key)
+		}
+		return true
+	})
+	entriesDeleted := 0
+	if len(missing) > 0 && dbInteraction.WarningYesNoQuestion(
+		"Delete these entries from the library?",
+		"%d entr(y/ies) in the library have no file in %s (e.g. %s)",
+		len(missing), entriesFolder, missing[0]) {
+		for _, key := range missing {
+			l.DeleteEntry(key)
+			entriesDeleted++
+		}
+	}
+
+	dbInteraction.Progress("Imported %s: %d contributor(s) updated, %d entr(y/ies) updated, %d deleted",
+		folder, contributorsChanged, entriesChanged, entriesDeleted)
+	return contributorsChanged+entriesChanged+entriesDeleted > 0
+}
+
+// entryFileSection collects the values of all lines called name, or — when name
+// ends in "." — a map from the remainder of each matching line name to its value.
+func entryFileSection(lines []TEntryFileLine, name string) ([]string, map[string]string) {
+	var values []string
+	named := map[string]string{}
+	for _, line := range lines {
+		if strings.HasSuffix(name, ".") {
+			if rest, ok := strings.CutPrefix(line.Name, name); ok {
+				named[rest] = line.Value
+			}
+		} else if line.Name == name {
+			values = append(values, line.Value)
+		}
+	}
+	return values, named
+}
+
+// applyEntryFile makes entry key match lines.
+func (l *TBibTeXLibrary) applyEntryFile(key string, lines []TEntryFileLine) {
+	_, fields := entryFileSection(lines, "field.")
+	_, lineage := entryFileSection(lines, "lineage.")
+	_, metadata := entryFileSection(lines, "meta.")
+	flags, _ := entryFileSection(lines, "flag")
+	groups, _ := entryFileSection(lines, "group")
+	aliases, _ := entryFileSection(lines, "alias")
+	hints, _ := entryFileSection(lines, "hint")
+	dois, _ := entryFileSection(lines, "doi_alias")
+
+	// Fields — entry type first, so a brand-new entry is anchored before the rest.
+	entry := loadEntryFromDb(key)
+	if entryType := fields[EntryTypeField]; entryType != "" && entry.Fields[EntryTypeField] != entryType {
+		l.setEntryField(entry, EntryTypeField, entryType)
+	}
+	for field, value := range fields {
+		if field != EntryTypeField && entry.Fields[field] != value {
+			l.setEntryField(entry, field, value)
+		}
+	}
+	for field := range loadEntryFromDb(key).Fields {
+		if _, keep := fields[field]; !keep {
+			l.deleteEntryField(entry, field)
+		}
+	}
+
+	// Lineage.
+	for field, source := range lineage {
+		source, edited := strings.CutSuffix(source, entryFileLineageEdited)
+		l.setLineage(key, field, fields[field], source, edited)
+	}
+	for field := range l.LineageMap[key] {
+		if _, keep := lineage[field]; !keep {
+			delete(l.LineageMap[key], field)
+			dbExecSave("applyEntryFile: lineage", `DELETE FROM entry_lineage WHERE entry_key = ? AND field = ?`, key, field)
+		}
+	}
+
+	// Flags.
+	wantFlags := TStringSetNew()
+	wantFlags.Set().Add(flags...)
+	for _, flag := range flags {
+		l.SetEntryFlag(key, flag)
+	}
+	if current, ok := l.EntryFlags[key]; ok {
+		for _, flag := range current.Set().ElementsSorted() {
+			if !wantFlags.Set().Contains(flag) {
+				l.ClearEntryFlag(key, flag)
+			}
+		}
+	}
+
+	// Metadata (flags excluded; they are handled above).
+	for prop, value := range metadata {
+		if l.GetMetadata(key, prop) != value {
+			l.SetMetadata(key, prop, value)
+		}
+	}
+	for prop := range l.Metadata[key] {
+		if _, keep := metadata[prop]; !keep && !isEntryFlagProperty(prop) {
+			l.DeleteMetadata(key, prop)
+		}
+	}
+
+	// Groups.
+	wantGroups := TStringSetNew()
+	wantGroups.Set().Add(groups...)
+	for _, group := range groups {
+		if members, ok := l.GroupEntries[group]; !ok || !members.Set().Contains(key) {
+			if err := addBibGroupEntry(group, key); err != nil {
+				dbInteraction.Warning("Could not add %s to group %s: %s", key, group, err)
+				continue
+			}
+			l.GroupEntries.AddValueToStringSetMap(group, key)
+		}
+	}
+	for _, group := range l.entryGroups(key) {
+		if !wantGroups.Set().Contains(group) {
+			if err := removeBibGroupEntry(group, key); err != nil {
+				dbInteraction.Warning("Could not remove %s from group %s: %s", key, group, err)
+				continue
+			}
+			l.GroupEntries.DeleteValueFromStringSetMap(group, key)
+		}
+	}
+
+	// Key aliases and hints.
+	wantAliases := TStringSetNew()
+	wantAliases.Set().Add(aliases...)
+	for _, alias := range l.entryKeyAliases(key) {
+		if !wantAliases.Set().Contains(alias) {
+			l.KeyOldies.Delete(alias)
+		}
+	}
+	for _, alias := range aliases {
+		l.AddKeyAlias(alias, key)
+	}
+	wantHints := TStringSetNew()
+	wantHints.Set().Add(hints...)
+	for _, hint := range l.entryKeyHints(key) {
+		if !wantHints.Set().Contains(hint) {
+			l.HintToKey.Delete(hint)
+		}
+	}
+	for _, hint := range hints {
+		l.AddKeyHint(hint, key)
+	}
+
+	// DOI aliases.
+	wantDois := TStringSetNew()
+	wantDois.Set().Add(dois...)
+	for _, doi := range entryDoiAliases(key) {
+		if !wantDois.Set().Contains(doi) {
+			dbExecSave("applyEntryFile: doi alias", `DELETE FROM entry_doi_aliases WHERE doi = ?`, doi)
+		}
+	}
+	for _, doi := range dois {
+		addEntryDoiAlias(key, doi)
+	}
+}
+
+// applyContributorFile makes contributor id match lines.
+func applyContributorFile(id string, lines []TEntryFileLine) {
+	garbled := 0
+	if entryFileValue(lines, "garbled") == "true" {
+		garbled = 1
+	}
+	dbExecSave("applyContributorFile: contributor",
+		`INSERT INTO contributors (id, name, orcid, dblp_key, garbled) VALUES (?, ?, ?, NULLIF(?, ''), ?)
+		 ON CONFLICT(id) DO UPDATE SET name = excluded.name, orcid = excluded.orcid,
+		   dblp_key = excluded.dblp_key, garbled = excluded.garbled`,
+		id, entryFileValue(lines, "name"), entryFileValue(lines, "orcid"), entryFileValue(lines, "dblp_key"), garbled)
+
+	// syncColumn makes the rows of a one-column side table for id match want.
+	syncColumn := func(current, want []string, add, remove func(string)) {
+		wanted := TStringSetNew()
+		wanted.Set().Add(want...)
+		for _, v := range current {
+			if !wanted.Set().Contains(v) {
+				remove(v)
+			}
+		}
+		have := TStringSetNew()
+		have.Set().Add(current...)
+		for _, v := range want {
+			if !have.Set().Contains(v) {
+				add(v)
+			}
+		}
+	}
+	current, _ := entryFileSection(contributorFileLines(id), "name_variant")
+	want, _ := entryFileSection(lines, "name_variant")
+	syncColumn(current, want,
+		func(name string) { upsertContributorNameToDB(id, name) },
+		func(name string) { deleteContributorNameFromDB(id, name) })
+
+	orcids, _ := entryFileSection(lines, "other_orcid")
+	if orcid := entryFileValue(lines, "orcid"); orcid != "" {
+		orcids = append(orcids, orcid)
+	}
+	syncColumn(contributorORCIDs(id), orcids,
+		func(orcid string) { upsertContributorORCIDToDB(id, orcid, orcid == entryFileValue(lines, "orcid")) },
+		func(orcid string) {
+			dbExecSave("applyContributorFile: orcid", `DELETE FROM contributor_orcids WHERE orcid = ? AND contributor_id = ?`, orcid, id)
+		})
+
+	current, _ = entryFileSection(contributorFileLines(id), "absorbed_id")
+	want, _ = entryFileSection(lines, "absorbed_id")
+	syncColumn(current, want,
+		func(absorbed string) { upsertContributorIDOldie(absorbed, id) },
+		func(absorbed string) {
+			dbExecSave("applyContributorFile: absorbed id", `DELETE FROM contributor_id_oldies WHERE absorbed_id = ?`, absorbed)
+		})
+}
+
+// entryFilesFolderFromArgs returns the folder argument of -export_entries /
+// -import_entries, or "" for the default <basename>.entries/.
+func entryFilesFolderFromArgs(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if abs, err := filepath.Abs(args[0]); err == nil {
+		return abs
+	}
+	return args[0]
+}
@@ -536,15 +536,9 @@ func importDblpWaivedFromCSV(replace bool) {
 func ExportEntryFlags() {
 	ensureTablesDir()
 	path := tablesFilePath(EntryFlagsFilePath)
-	flags := knownEntryFlags()
-	placeholders := strings.Repeat("?,", len(flags))
-	placeholders = placeholders[:len(placeholders)-1]
-	args := make([]any, len(flags))
-	for i, f := range flags {
-		args[i] = f
-	}
+	flagCond, args := entryFlagPropertySQL()
 	rows, err := db.Query(
-		`SELECT entry_key, property FROM entry_metadata WHERE value = 'true' AND property IN (`+placeholders+`) ORDER BY entry_key, property`,
+		`SELECT entry_key, property FROM entry_metadata WHERE value = 'true' AND `+flagCond+` ORDER BY entry_key, property`,
 		args...)
 	if err != nil {
 		dbInteraction.Warning("Could not query entry_flags from entry_metadata: %s", err)
@@ -560,15 +554,9 @@ func importEntryFlagsFromCSV(replace bool) {
 	validate := func(f []string) bool { return len(f) >= 2 && f[0] != "" && f[1] != "" }
 	var clearFn func()
 	if replace {
-		flags := knownEntryFlags()
-		placeholders := strings.Repeat("?,", len(flags))
-		placeholders = placeholders[:len(placeholders)-1]
-		args := make([]any, len(flags))
-		for i, f := range flags {
-			args[i] = f
-		}
+		flagCond, args := entryFlagPropertySQL()
 		clearFn = func() {
-			db.Exec(`DELETE FROM entry_metadata WHERE value = 'true' AND property IN (`+placeholders+`)`, args...)
+			db.Exec(`DELETE FROM entry_metadata WHERE value = 'true' AND `+flagCond, args...)
 		}
 	}
 	n, ok := importTwoPhase(path, validate, clearFn, func(tx *sql.Tx, f []string) {
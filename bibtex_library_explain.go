@@ -0,0 +1,235 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_explain
+ *
+ * Per-entry provenance report (-explain <key> [field]).
+ *
+ * For every field of an entry (or just the one asked for) this shows the
+ * current value, the source that delivered it according to the lineage
+ * (with its priority) and whether it was hand-edited, what each known source
+ * last delivered for it, the superseded values with where they came from, the
+ * generic and cross-field mappings that lead to the current value, and the
+ * recent history of the field as recorded in the session journal.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TFieldHistoryStep is one journaled change of an entry field.
+type TFieldHistoryStep struct {
+	Session string
+	Before  string
+	After   string
+}
+
+// fieldHistory returns the journaled changes of (key, field), oldest first.
+func fieldHistory(key, field string) []TFieldHistoryStep {
+	rows, err := db.Query(`
+		SELECT session,
+		       COALESCE(json_extract(before_values, '$.value'), ''),
+		       COALESCE(json_extract(after_values, '$.value'), '')
+		  FROM session_journal
+		 WHERE table_name = 'bib_entries'
+		   AND json_extract(COALESCE(after_values, before_values), '$.entry_key') = ?
+		   AND json_extract(COALESCE(after_values, before_values), '$.field') = ?
+		 ORDER BY id`, key, field)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var steps []TFieldHistoryStep
+	for rows.Next() {
+		var step TFieldHistoryStep
+		if rows.Scan(&step.Session, &step.Before, &step.After) == nil {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// TSupersededValue is one superseded_field_values row of an entry field.
+type TSupersededValue struct {
+	Value        string
+	Winner       string
+	TriageStatus string
+}
+
+func supersededFieldValues(key, field string) []TSupersededValue {
+	rows, err := db.Query(`
+		SELECT value, COALESCE(winner, ''), COALESCE(triage_status, '')
+		  FROM superseded_field_values WHERE entry_key = ? AND field = ? ORDER BY value`, key, field)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var values []TSupersededValue
+	for rows.Next() {
+		var v TSupersededValue
+		if rows.Scan(&v.Value, &v.Winner, &v.TriageStatus) == nil {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// supersededValueOrigin describes where a superseded value came from: a source
+// whose last delivery it is, or the session in which it was overwritten.
+func (l *TBibTeXLibrary) supersededValueOrigin(key, field, value string, history []TFieldHistoryStep) string {
+	var origins []string
+	for _, source := range sortedMapKeys(l.SourceSignatures[key][field]) {
+		if l.SourceSignatures[key][field][source] == value {
+			origins = append(origins, "delivered by "+lineageSourceDisplay(source))
+		}
+	}
+	for _, step := range history {
+		if step.Before == value && step.After != value {
+			origins = append(origins, "replaced in session "+step.Session)
+			break
+		}
+	}
+	if len(origins) == 0 {
+		return "origin unknown"
+	}
+	return strings.Join(origins, "; ")
+}
+
+// fieldMappingsInto lists the mappings that lead to value for field of key:
+// generic aliases of the value, and cross-field mappings whose source field
+// and value the entry currently holds.
+func (l *TBibTeXLibrary) fieldMappingsInto(entry *TBibTeXEntry, field, value string) []string {
+	var mappings []string
+	for _, alias := range sortedMapKeys(l.GenericFieldSourceToTarget[field]) {
+		if alias != value && l.GenericFieldSourceToTarget[field][alias] == value {
+			mappings = append(mappings, fmt.Sprintf("%q → %q (generic %s mapping)", alias, value, field))
+		}
+	}
+	for _, sourceField := range sortedMapKeys(l.FieldMappings) {
+		sourceValue := entry.FieldValue(sourceField)
+		if target, ok := l.FieldMappings[sourceField][sourceValue][field]; ok && sourceValue != "" && target == value {
+			mappings = append(mappings, fmt.Sprintf("%s = %q → %s = %q (cross-field mapping)", sourceField, sourceValue, field, value))
+		}
+	}
+	return mappings
+}
+
+// sortedMapKeys returns the keys of m in sorted order.
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// explainField renders the provenance block of one field.
+func (l *TBibTeXLibrary) explainField(entry *TBibTeXEntry, field string) string {
+	key := entry.Key
+	value := entry.FieldValue(field)
+	history := fieldHistory(key, field)
+	superseded := supersededFieldValues(key, field)
+
+	var b strings.Builder
+	if value == "" {
+		fmt.Fprintf(&b, "  %s: (empty)\n", field)
+	} else {
+		fmt.Fprintf(&b, "  %s = {%s}\n", field, value)
+		if mapped := l.MapEntryFieldValue(key, field, value); mapped != value {
+			fmt.Fprintf(&b, "    rendered as: {%s}\n", mapped)
+		}
+	}
+
+	if rec, ok := l.LineageMap[key][field]; ok && rec.Value != value {
+		fmt.Fprintf(&b, "    source:      unknown (lineage describes an earlier value {%s} from %s)\n", rec.Value, lineageSourceDisplay(rec.Source))
+	} else if value != "" {
+		rec := l.getLineage(key, field)
+		edited := ""
+		if rec.Edited {
+			edited = ", hand-edited"
+		}
+		if rec.Policy != "" {
+			edited += ", chosen by conflict policy rule " + rec.Policy
+		}
+		fmt.Fprintf(&b, "    source:      %s%s\n", lineageSourceDisplay(rec.Source), edited)
+	}
+
+	for _, source := range sortedMapKeys(l.SourceSignatures[key][field]) {
+		delivered := l.SourceSignatures[key][field][source]
+		switch {
+		case delivered == value:
+			fmt.Fprintf(&b, "    delivered:   %s delivers this value\n", source)
+		case delivered == "":
+			fmt.Fprintf(&b, "    delivered:   %s delivers no value\n", source)
+		default:
+			fmt.Fprintf(&b, "    delivered:   %s delivers {%s}\n", source, delivered)
+		}
+	}
+
+	for _, s := range superseded {
+		notes := []string{l.supersededValueOrigin(key, field, s.Value, history)}
+		if s.Winner != "" && s.Winner != value {
+			notes = append(notes, fmt.Sprintf("decided in favour of {%s}", s.Winner))
+		}
+		if s.TriageStatus != "" {
+			notes = append(notes, "triage: "+s.TriageStatus)
+		}
+		fmt.Fprintf(&b, "    superseded:  {%s} (%s)\n", s.Value, strings.Join(notes, ", "))
+	}
+
+	if value != "" {
+		for _, mapping := range l.fieldMappingsInto(entry, field, value) {
+			fmt.Fprintf(&b, "    mapped:      %s\n", mapping)
+		}
+	}
+
+	for _, step := range history {
+		switch {
+		case step.Before == "":
+			fmt.Fprintf(&b, "    history:     %s set to {%s}\n", step.Session, step.After)
+		case step.After == "":
+			fmt.Fprintf(&b, "    history:     %s removed {%s}\n", step.Session, step.Before)
+		default:
+			fmt.Fprintf(&b, "    history:     %s changed {%s} to {%s}\n", step.Session, step.Before, step.After)
+		}
+	}
+	return b.String()
+}
+
+// ExplainEntry renders the provenance report of key, for all its fields or
+// only for field when that is non-empty. Returns "" when key is not an entry.
+func (l *TBibTeXLibrary) ExplainEntry(key, field string) string {
+	entry := loadEntryFromDb(key)
+	if !entry.Exists() {
+		return ""
+	}
+
+	fields := []string{field}
+	if field == "" {
+		fields = []string{EntryTypeField}
+		for _, f := range sortedMapKeys(entry.Fields) {
+			if f != EntryTypeField {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", key)
+	for _, f := range fields {
+		b.WriteString(l.explainField(entry, f))
+	}
+	return b.String()
+}
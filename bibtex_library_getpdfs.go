@@ -6,10 +6,12 @@
  *   - bibtex_library_getpdfs
  *
  * Implements -get_pdfs: downloads missing PDF files for library entries that
- * have a direct-download URL (url field ending in ".pdf").
+ * have a direct-download URL (url field ending in ".pdf"), or for which one of
+ * the configured open-access providers yields one (see
+ * bibtex_library_pdf_providers.go).
  *
  * URLs listed in urls_ignore.csv (or the legacy urls.ignore) are skipped.
- * Failed downloads are written to urls_failed.csv at FilesRoot level.
+ * Attempts and failures are recorded per entry (pdf_fetch_attempts metadata).
  *
  * Creator: Henderik A. Proper (erikproper@gmail.com)
  *
@@ -20,14 +22,11 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 	"time"
 )
 
@@ -106,33 +105,16 @@ func downloadPDF(url, destPath string) error {
 	return os.Rename(tmp, destPath)
 }
 
-// writeURLsFailedFile writes a sorted list of CSV lines to path.
-func writeURLsFailedFile(path string, lines []string) {
-	sort.Strings(lines)
-	f, err := os.Create(path)
-	if err != nil {
-		return
-	}
-	defer f.Close()
-	w := bufio.NewWriter(f)
-	for _, line := range lines {
-		w.WriteString(line + "\n")
-	}
-	w.Flush()
-}
-
-// GetPDFs downloads missing PDFs for all library entries with a direct-download
-// URL (url field ending in ".pdf"), subject to urls_ignore.
-// Each failed download is recorded in urls_failed.csv (url; reason; date).
-// The file is rewritten on every run so it reflects the current state.
+// GetPDFs downloads missing PDFs for all library entries, trying the entry's own
+// url when it ends in ".pdf", followed by the configured open-access providers
+// (see bibtex_library_pdf_providers.go), subject to urls_ignore.
+// Each attempt is recorded in the entry's metadata (pdf_fetch_attempts). Offline,
+// only local mirror providers are used.
 func (l *TBibTeXLibrary) GetPDFs() {
-	if !Online {
+	if !Online && !pdfMirrorConfigured() {
 		return
 	}
 	filesDir := l.FilesRoot + l.FilesFolder
-	failedPath := l.FilesRoot + URLsFailedFilePath
-
-	var failedLines []string
 
 	forEachBibEntryKey(func(key string) bool {
 		filePath := filesDir + key + ".pdf"
@@ -140,40 +122,27 @@ func (l *TBibTeXLibrary) GetPDFs() {
 			return true
 		}
 
-		url := l.EntryFieldValueity(key, "url")
-		if url != "" && strings.HasSuffix(strings.ToLower(url), ".pdf") {
-			if !l.URLsIgnore.Contains(url) {
-				needsURLDate := l.EntryFieldValueity(key, "doi") == "" &&
-					l.EntryFieldValueity(key, DBLPField) == "" &&
-					l.EntryFieldValueity(key, "isbn") == "" &&
-					l.EntryFieldValueity(key, "issn") == ""
-				missingURLDate := needsURLDate && l.EntryFieldValueity(key, "urldate") == ""
-
-				l.Progress(ProgressDownloadingPDF, key, url)
-				if err := downloadPDF(url, filePath); err != nil {
-					l.Warning(WarningPDFDownloadFailed, key, url, err)
-					failedLines = append(failedLines,
-						csvLine(url, err.Error(), time.Now().Format("2006-01-02")))
-					if missingURLDate {
-						if d, err2 := Reporting.AskForInput(
-							"No urldate for " + key + " (download failed). Enter a date (YYYY-MM-DD) or leave blank to skip"); err2 == nil && IsValidDate(d) {
-							l.SetEntryFieldValue(key, "urldate", d)
-						}
-					}
-				} else {
-					l.Progress(ProgressPDFDownloaded, key, filePath)
-					if missingURLDate {
-						l.SetEntryFieldValue(key, "urldate", time.Now().Format("2006-01-02"))
-					}
-					flushWorkingDbToHome()
-				}
+		needsURLDate := l.EntryFieldValueity(key, "doi") == "" &&
+			l.EntryFieldValueity(key, DBLPField) == "" &&
+			l.EntryFieldValueity(key, "isbn") == "" &&
+			l.EntryFieldValueity(key, "issn") == ""
+		missingURLDate := needsURLDate && l.EntryFieldValueity(key, "urldate") == ""
+
+		fetched, entryURLFailed := l.fetchPDF(key, filePath)
+		if fetched != nil {
+			l.Progress(ProgressPDFDownloaded, key, filePath)
+			l.PDFFiles[key] = true
+			if missingURLDate && fetched.Provider == pdfProviderEntryURL {
+				l.SetEntryFieldValue(key, "urldate", time.Now().Format("2006-01-02"))
+			}
+			flushWorkingDbToHome()
+		} else if missingURLDate && entryURLFailed {
+			if d, err := Reporting.AskForInput(
+				"No urldate for " + key + " (download failed). Enter a date (YYYY-MM-DD) or leave blank to skip"); err == nil && IsValidDate(d) {
+				l.SetEntryFieldValue(key, "urldate", d)
 			}
 		}
 
 		return true
 	})
-
-	if len(failedLines) > 0 {
-		writeURLsFailedFile(failedPath, failedLines)
-	}
 }
@@ -258,20 +258,27 @@ func reorderHarvestCrossrefsFirst(entries []TBibTeXEntry) []TBibTeXEntry {
 // --- Entry matching pipeline ---
 
 // harvestKeyMatch returns the canonical library key matching e's source key via
-// KeyToKey aliases or HintToKey. Returns "" when no real library entry is found.
+// KeyToKey aliases or HintToKey, or else the key embedded (by -embed_pdf_metadata)
+// in the PDF e refers to. Returns "" when no real library entry is found.
 // MapEntryKey returns the key unchanged when not in KeyToKey, so EntryExists guards
 // against false positives from key-hint-only keys.
 func (l *TBibTeXLibrary) harvestKeyMatch(e TBibTeXEntry) string {
-	if e.Key == "" || harvestIgnoreKeys.Contains(strings.ToLower(e.Key)) {
-		return ""
-	}
-	if canon := l.MapEntryKey(e.Key); l.EntryExists(canon) {
-		return canon
-	}
-	if hint := l.HintToKey.GetValue(e.Key); hint != "" {
-		if canon := l.MapEntryKey(hint); l.EntryExists(canon) {
+	if e.Key != "" && !harvestIgnoreKeys.Contains(strings.ToLower(e.Key)) {
+		if canon := l.MapEntryKey(e.Key); l.EntryExists(canon) {
 			return canon
 		}
+		if hint := l.HintToKey.GetValue(e.Key); hint != "" {
+			if canon := l.MapEntryKey(hint); l.EntryExists(canon) {
+				return canon
+			}
+		}
+	}
+	if srcPath := l.harvestPDFSource(e); srcPath != "" {
+		if key := pdfEmbeddedKey(srcPath); key != "" {
+			if canon := l.MapEntryKey(key); l.EntryExists(canon) {
+				return canon
+			}
+		}
 	}
 	return ""
 }
@@ -299,15 +306,15 @@ func (l *TBibTeXLibrary) harvestTitleMatches(e TBibTeXEntry) []string {
 	return result
 }
 
-// askHarvestLibraryChoice asks the user to pick one of n numbered library candidates.
-// Returns the 1-based index, or 0 for none.
-func (l *TBibTeXLibrary) askHarvestLibraryChoice(n int) int {
+// askHarvestLibraryChoice asks the user to pick one of n numbered library candidates
+// for the source entry key. Returns the 1-based index, or 0 for none.
+func (l *TBibTeXLibrary) askHarvestLibraryChoice(key string, n int) int {
 	options := TStringSetNew()
 	options.Add("0")
 	for i := 1; i <= n; i++ {
 		options.Add(fmt.Sprintf("%d", i))
 	}
-	answer := l.WarningQuestion(QuestionHarvestLibraryChoice, options, "")
+	answer := l.WarningQuestion(QuestionHarvestLibraryChoice, options, "", key)
 	result := 0
 	fmt.Sscanf(answer, "%d", &result)
 	return result
@@ -373,10 +380,11 @@ func (l *TBibTeXLibrary) harvestFindDblpCandidates(e TBibTeXEntry, excludeDblpKe
 	for i := range candidates {
 		options.Add(fmt.Sprintf("%d", i+1))
 	}
+	askingAbout(append([]string{e.Key}, candidates...)...)
 	answer := l.WarningQuestion(QuestionHarvestDblpChoose, options,
 		WarningHarvestDblpCandidatesFound, e.Fields[TitleField], len(candidates))
 	if answer == "k" {
-		if typed, err := Reporting.AskForInput("DBLP key"); err == nil && typed != "" {
+		if typed, err := Reporting.AskForInput("DBLP key", e.Key); err == nil && typed != "" {
 			return l.resolveTypedDblpKey(e.Key, typed)
 		}
 		return ""
@@ -454,13 +462,17 @@ func maybeCollectKeyHint(l *TBibTeXLibrary, sourceKey, finalKey string) {
 
 // --- PDF harvesting ---
 
-// jabrefPDFPath extracts the path to the first PDF-typed file from a JabRef file
-// field value. Returns "" when no PDF entry is found. Format:
+// jabrefFileRef is one description:path:type triple of a JabRef file field.
+type jabrefFileRef struct {
+	description, path, fileType string
+}
+
+// jabrefFileRefs splits a JabRef file field value into its triples. Format:
 //
 //	description:path:type[;description:path:type…]
 //
 // with ':', ';', and '\' each escaped by '\'.
-func jabrefPDFPath(ref string) string {
+func jabrefFileRefs(ref string) []jabrefFileRef {
 	// Temporarily replace escape sequences so we can split on bare delimiters.
 	safe := strings.ReplaceAll(ref, `\\`, "\x01")  // protect escaped backslash
 	safe = strings.ReplaceAll(safe, `\:`, "\x02")  // protect escaped colon
@@ -473,31 +485,40 @@ func jabrefPDFPath(ref string) string {
 		return s
 	}
 
+	var refs []jabrefFileRef
 	for _, entry := range strings.Split(safe, ";") {
 		parts := strings.SplitN(entry, ":", 3)
 		if len(parts) < 3 {
 			continue
 		}
-		if !strings.EqualFold(restore(parts[2]), "pdf") {
+		refs = append(refs, jabrefFileRef{restore(parts[0]), restore(parts[1]), restore(parts[2])})
+	}
+	return refs
+}
+
+// jabrefPDFPath extracts the path to the first PDF-typed file from a JabRef file
+// field value that is not described as another attachment role (slides, preprint,
+// …; see jabrefFileValue). Returns "" when no such entry is found.
+func jabrefPDFPath(ref string) string {
+	for _, r := range jabrefFileRefs(ref) {
+		if !strings.EqualFold(r.fileType, "pdf") {
+			continue
+		}
+		if role := strings.ToLower(r.description); isAttachmentRole(role) && role != AttachmentRolePaper {
 			continue
 		}
-		if p := restore(parts[1]); p != "" {
-			return p
+		if r.path != "" {
+			return r.path
 		}
 	}
 	return ""
 }
 
-// maybeHarvestPDF copies a PDF referenced by a harvested entry into the library files
-// folder under canonicalKey.pdf. Handles both JabRef (file = {:path:PDF}) and
-// BibDesk (local-url = {/abs/path}) formats. Relative JabRef paths are resolved
+// harvestPDFSource returns the path of the PDF referenced by a harvested entry, or
+// "" when it references none that exists. Handles both JabRef (file = {:path:PDF})
+// and BibDesk (local-url = {/abs/path}) formats. Relative JabRef paths are resolved
 // against l.harvestSourceDir (set during parseHarvestBib, kept through the loop).
-func (l *TBibTeXLibrary) maybeHarvestPDF(e TBibTeXEntry, canonicalKey string) {
-	if l.PDFFiles[canonicalKey] {
-		return // library already has a PDF for this entry
-	}
-	destPath := l.FilesRoot + l.FilesFolder + canonicalKey + ".pdf"
-
+func (l *TBibTeXLibrary) harvestPDFSource(e TBibTeXEntry) string {
 	var srcPath string
 	if ref := e.Fields[JabrefFileField]; ref != "" {
 		if raw := jabrefPDFPath(ref); raw != "" {
@@ -511,6 +532,52 @@ func (l *TBibTeXLibrary) maybeHarvestPDF(e TBibTeXEntry, canonicalKey string) {
 	}
 
 	if srcPath == "" || !FileExists(srcPath) {
+		return ""
+	}
+	return srcPath
+}
+
+// maybeHarvestAttachments adds the files of a harvested entry's JabRef file field
+// that are described by an attachment role other than paper (slides, supplement,
+// …) as attachments of canonicalKey. Files whose content is already attached are
+// skipped (see AddAttachment).
+func (l *TBibTeXLibrary) maybeHarvestAttachments(e TBibTeXEntry, canonicalKey string) {
+	for _, r := range jabrefFileRefs(e.Fields[JabrefFileField]) {
+		role := strings.ToLower(r.description)
+		if !isAttachmentRole(role) || role == AttachmentRolePaper || r.path == "" {
+			continue
+		}
+		srcPath := r.path
+		if !filepath.IsAbs(srcPath) && l.harvestSourceDir != "" {
+			srcPath = filepath.Join(l.harvestSourceDir, srcPath)
+		}
+		if !FileExists(srcPath) {
+			continue
+		}
+		known := len(l.Attachments[canonicalKey])
+		a, err := l.AddAttachment(canonicalKey, role, srcPath)
+		if err != nil {
+			l.Warning(WarningAttachmentNotAdded, srcPath, canonicalKey, err)
+			continue
+		}
+		if len(l.Attachments[canonicalKey]) > known {
+			l.Progress("Harvested attachment: %s → %s", filepath.Base(srcPath), a.Name)
+		}
+	}
+}
+
+// maybeHarvestPDF copies a PDF referenced by a harvested entry into the library files
+// folder under canonicalKey.pdf (see harvestPDFSource), and its other attachments
+// (see maybeHarvestAttachments).
+func (l *TBibTeXLibrary) maybeHarvestPDF(e TBibTeXEntry, canonicalKey string) {
+	l.maybeHarvestAttachments(e, canonicalKey)
+	if l.PDFFiles[canonicalKey] {
+		return // library already has a PDF for this entry
+	}
+	destPath := l.FilesRoot + l.FilesFolder + canonicalKey + ".pdf"
+
+	srcPath := l.harvestPDFSource(e)
+	if srcPath == "" {
 		return
 	}
 	if err := os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
@@ -849,7 +916,7 @@ func (l *TBibTeXLibrary) runHarvestEntry(e TBibTeXEntry, syncState *TSyncState)
 			fmt.Fprintf(os.Stderr, "[%d]\n", i+1)
 			fmt.Fprint(os.Stderr, l.entryDisplayString(k))
 		}
-		pick := l.askHarvestLibraryChoice(len(titleMatches))
+		pick := l.askHarvestLibraryChoice(e.Key, len(titleMatches))
 		if l.QuitWasRequested() {
 			return "", true
 		}
@@ -889,6 +956,7 @@ func (l *TBibTeXLibrary) runHarvestEntry(e TBibTeXEntry, syncState *TSyncState)
 			maybeCollectKeyHint(l, e.Key, finalKey)
 			l.maybeHarvestPDF(e, finalKey)
 			addToHarvestGroup(l, finalKey)
+			l.fireHook(THookEvent{Event: HookEntryCreated, Keys: []string{finalKey}, Source: "harvest"})
 			transferHarvestKey(e.Key, finalKey)
 			recordStatus(finalKey)
 			return finish(finalKey)
@@ -903,11 +971,11 @@ func (l *TBibTeXLibrary) runHarvestEntry(e TBibTeXEntry, syncState *TSyncState)
 	// follow bib via the .sync DB weave table.
 	validActions := TStringSetNew()
 	validActions.Add("a").Add("k").Add("m").Add("s").Add("i").Add("q")
-	switch l.WarningQuestion(QuestionHarvestAction, validActions, "") {
+	switch l.WarningQuestion(QuestionHarvestAction, validActions, "", e.Key) {
 	case "q":
 		return "", true
 	case "k":
-		typed, err := Reporting.AskForInput("DBLP key")
+		typed, err := Reporting.AskForInput("DBLP key", e.Key)
 		if err == nil && typed != "" {
 			if dblpKey := l.resolveTypedDblpKey(e.Key, typed); dblpKey != "" {
 				newKey := addHarvestEntry(l, e)
@@ -920,6 +988,7 @@ func (l *TBibTeXLibrary) runHarvestEntry(e TBibTeXEntry, syncState *TSyncState)
 				maybeCollectKeyHint(l, e.Key, finalKey)
 				l.maybeHarvestPDF(e, finalKey)
 				addToHarvestGroup(l, finalKey)
+				l.fireHook(THookEvent{Event: HookEntryCreated, Keys: []string{finalKey}, Source: "harvest"})
 				transferHarvestKey(e.Key, finalKey)
 				recordStatus(finalKey)
 				return finish(finalKey)
@@ -929,7 +998,10 @@ func (l *TBibTeXLibrary) runHarvestEntry(e TBibTeXEntry, syncState *TSyncState)
 	case "m":
 		for {
 			fmt.Fprint(os.Stderr, "Merge into EP key: ")
-			targetKey := readStdinLine()
+			targetKey := answerLine("Merge into EP key", []any{e.Key}, nil, "")
+			if targetKey == "" {
+				return "", false
+			}
 			canon := l.MapEntryKey(targetKey)
 			if !l.EntryExists(canon) {
 				fmt.Fprintf(os.Stderr, "  Entry %q not found in library — try again.\n", targetKey)
@@ -961,6 +1033,7 @@ func (l *TBibTeXLibrary) runHarvestEntry(e TBibTeXEntry, syncState *TSyncState)
 		maybeCollectKeyHint(l, e.Key, finalKey)
 		l.maybeHarvestPDF(e, finalKey)
 		addToHarvestGroup(l, finalKey)
+		l.fireHook(THookEvent{Event: HookEntryCreated, Keys: []string{finalKey}, Source: "harvest"})
 		transferHarvestKey(e.Key, finalKey)
 		recordStatus(finalKey)
 		return finish(finalKey)
@@ -0,0 +1,401 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_harvest_pdfs
+ *
+ * Harvests entries directly from a folder of PDF files (-harvest_pdfs <dir>).
+ *
+ * Each PDF is turned into a harvest candidate, which then runs through the
+ * normal runHarvestEntry flow; the candidate's local-url refers to the PDF, so
+ * maybeHarvestPDF files it as <key>.pdf once the entry is resolved. Candidates
+ * are built, in order of preference, from:
+ *   - the library entry whose key is embedded in the PDF (-embed_pdf_metadata),
+ *     or which has the PDF's DOI or ISBN;
+ *   - the local DBLP store's record with the PDF's DOI (or arXiv DOI);
+ *   - a skeleton of the title and authors found in the PDF's XMP metadata, Info
+ *     dictionary or first page. A title that the DBLP title index knows is
+ *     preferred, so that runHarvestEntry can offer the DBLP candidates for it.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"html"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	harvestPDFTitleLines     = 25 // first-page lines considered as (part of) the title
+	harvestPDFTitleMaxLength = 250
+)
+
+var (
+	pdfArxivPattern    = regexp.MustCompile(`(?i)arxiv(?::\s*|\.org/(?:abs|pdf)/)(\d{4}\.\d{4,5}|[a-z\-]+(?:\.[a-z]{2})?/\d{7})`)
+	pdfISBNPattern     = regexp.MustCompile(`(?i)ISBN(?:-1[03])?:?\s*([0-9][0-9\- ]{8,16}[0-9X])`)
+	pdfXmpTitlePattern = regexp.MustCompile(`(?s)<dc:title>.*?<rdf:li[^>]*>(.*?)</rdf:li>`)
+	pdfXmpCreators     = regexp.MustCompile(`(?s)<dc:creator>(.*?)</dc:creator>`)
+	pdfXmpListItem     = regexp.MustCompile(`(?s)<rdf:li[^>]*>(.*?)</rdf:li>`)
+
+	// Words that mark a first-page line as something other than the title.
+	harvestPDFNonTitleWords = []string{
+		"http", "www.", "@", "doi", "©", "copyright", "arxiv", "journal", "proceedings",
+		"vol.", "volume", "issn", "isbn", "received", "accepted", "published", "preprint",
+		"university", "abstract", "licen", "conference", "springer", "elsevier", "ieee", "acm"}
+)
+
+// TPDFHarvestInfo is what could be read from a PDF to identify its publication.
+type TPDFHarvestInfo struct {
+	EmbeddedKey string
+	DOI         string
+	Arxiv       string
+	ISBN        string
+	Title       string   // from the XMP metadata or Info dictionary
+	Authors     []string // idem
+	TextTitles  []string // candidate titles from the first page
+}
+
+// pdfInfoText decodes a text string from an Info dictionary.
+func pdfInfoText(v any) string {
+	s, _ := v.(string)
+	if strings.HasPrefix(s, "\xFE\xFF") {
+		return pdfUTF16(s[2:])
+	}
+	return s
+}
+
+// validISBN returns the digits of an ISBN-10 or ISBN-13 with a valid check digit,
+// or "" when raw is not one.
+func validISBN(raw string) string {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(raw))
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, c := range digits {
+			d := int(c - '0')
+			if c == 'X' && i == 9 {
+				d = 10
+			} else if c < '0' || c > '9' {
+				return ""
+			}
+			sum += (10 - i) * d
+		}
+		if sum%11 == 0 {
+			return digits
+		}
+	case 13:
+		sum := 0
+		for i, c := range digits {
+			if c < '0' || c > '9' {
+				return ""
+			}
+			if i%2 == 0 {
+				sum += int(c - '0')
+			} else {
+				sum += 3 * int(c-'0')
+			}
+		}
+		if sum%10 == 0 {
+			return digits
+		}
+	}
+	return ""
+}
+
+// plausibleMetadataTitle rejects the titles producers put in by default.
+func plausibleMetadataTitle(title string) bool {
+	lower := strings.ToLower(strings.TrimSpace(title))
+	if len(strings.Fields(lower)) < 2 {
+		return false
+	}
+	for _, junk := range []string{"microsoft word", "untitled", ".pdf", ".doc", ".dvi", ".tex", ".indd"} {
+		if strings.Contains(lower, junk) {
+			return false
+		}
+	}
+	return true
+}
+
+// plausibleTitleLine reports whether a first-page line may be (part of) a title.
+func plausibleTitleLine(line string) bool {
+	if len(line) < 10 || len(line) > harvestPDFTitleMaxLength || len(strings.Fields(line)) < 2 {
+		return false
+	}
+	lower := strings.ToLower(line)
+	for _, word := range harvestPDFNonTitleWords {
+		if strings.Contains(lower, word) {
+			return false
+		}
+	}
+	letters := 0
+	for _, r := range line {
+		if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r > 0x7F {
+			letters++
+		}
+	}
+	return letters*3 >= len(line)*2
+}
+
+// pdfTitleCandidates returns the lines at the top of the first page that may be
+// the title, also joined with the next line, for titles set over two lines.
+func pdfTitleCandidates(raw string) []string {
+	firstPage, _, _ := strings.Cut(raw, "\f")
+	var lines []string
+	for _, line := range strings.Split(firstPage, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) == harvestPDFTitleLines {
+			break
+		}
+	}
+	var candidates []string
+	for i, line := range lines {
+		if !plausibleTitleLine(line) {
+			continue
+		}
+		candidates = append(candidates, line)
+		if i+1 < len(lines) && plausibleTitleLine(lines[i+1]) {
+			candidates = append(candidates, line+" "+lines[i+1])
+		}
+	}
+	return candidates
+}
+
+// readPDFHarvestInfo gathers the identifying information of the PDF at path.
+func readPDFHarvestInfo(path string) TPDFHarvestInfo {
+	var info TPDFHarvestInfo
+
+	if _, d, t, catalog, err := readPDFForUpdate(path); err == nil {
+		xmp, _ := d.streamOf(catalog["Metadata"])
+		if match := pdfXmpKeyPattern.FindSubmatch(xmp); match != nil {
+			info.EmbeddedKey = html.UnescapeString(string(match[1]))
+		}
+		if match := pdfXmpTitlePattern.FindSubmatch(xmp); match != nil {
+			info.Title = html.UnescapeString(strings.TrimSpace(string(match[1])))
+		}
+		if match := pdfXmpCreators.FindSubmatch(xmp); match != nil {
+			for _, item := range pdfXmpListItem.FindAllSubmatch(match[1], -1) {
+				if name := html.UnescapeString(strings.TrimSpace(string(item[1]))); name != "" {
+					info.Authors = append(info.Authors, name)
+				}
+			}
+		}
+		if dois := pdfDOIPattern.FindAllString(strings.ToLower(string(xmp)), 1); len(dois) > 0 {
+			info.DOI = strings.TrimRight(dois[0], ".,;:)]'")
+		}
+
+		infoDict := d.dict(t.dict["Info"])
+		if info.Title == "" {
+			info.Title = strings.TrimSpace(pdfInfoText(infoDict["Title"]))
+		}
+		if len(info.Authors) == 0 {
+			for _, name := range strings.FieldsFunc(pdfInfoText(infoDict["Author"]), func(r rune) bool { return r == ';' }) {
+				for _, part := range strings.Split(name, " and ") {
+					if part = strings.TrimSpace(part); part != "" {
+						info.Authors = append(info.Authors, part)
+					}
+				}
+			}
+		}
+		if info.DOI == "" {
+			for _, name := range []string{"doi", "DOI"} {
+				if doi := normalizeDOI(strings.TrimSpace(pdfInfoText(infoDict[name]))); pdfDOIPattern.MatchString(doi) {
+					info.DOI = doi
+					break
+				}
+			}
+		}
+	}
+	if !plausibleMetadataTitle(info.Title) {
+		info.Title = ""
+	}
+
+	if raw, err := pdfFirstPagesText(path, pdfVerifyPages); err == nil {
+		text := newPDFText(raw)
+		if info.DOI == "" && len(text.dois) > 0 {
+			info.DOI = text.dois[0]
+		}
+		if match := pdfArxivPattern.FindStringSubmatch(raw); match != nil {
+			info.Arxiv = match[1]
+		}
+		for _, match := range pdfISBNPattern.FindAllStringSubmatch(raw, -1) {
+			if isbn := validISBN(match[1]); isbn != "" {
+				info.ISBN = isbn
+				break
+			}
+		}
+		info.TextTitles = pdfTitleCandidates(pdfTextLigatures.Replace(raw))
+	}
+	return info
+}
+
+// libraryEntryWithField returns the canonical key of the entry whose field has
+// value (compared case-insensitively, ignoring hyphens), or "".
+func (l *TBibTeXLibrary) libraryEntryWithField(field, value string) string {
+	var key string
+	if bibQueryRow(`SELECT entry_key FROM bib_entries WHERE field = ? AND replace(lower(value), '-', '') = ? LIMIT 1`,
+		field, strings.ReplaceAll(strings.ToLower(value), "-", "")).Scan(&key) != nil {
+		return ""
+	}
+	if canon := l.MapEntryKey(key); l.EntryExists(canon) {
+		return canon
+	}
+	return ""
+}
+
+// harvestPDFCandidate builds the harvest candidate for the PDF at path, and says
+// how the PDF was identified.
+func (l *TBibTeXLibrary) harvestPDFCandidate(path string) (TBibTeXEntry, string) {
+	info := readPDFHarvestInfo(path)
+	e := TBibTeXEntry{Fields: map[string]string{}}
+
+	// Already in the library: present the library entry itself, under its own key,
+	// so that runHarvestEntry's key match takes it (and files the PDF) without
+	// further questions.
+	fromLibrary := func(key, how string) (TBibTeXEntry, string) {
+		e.Key = key
+		for field, value := range loadEntryFromDb(key).Fields {
+			e.Fields[field] = value
+		}
+		e.Fields[LocalURLField] = path
+		return e, how
+	}
+	if info.EmbeddedKey != "" {
+		if canon := l.MapEntryKey(info.EmbeddedKey); l.EntryExists(canon) {
+			return fromLibrary(canon, "embedded key "+info.EmbeddedKey)
+		}
+	}
+	if info.DOI != "" {
+		if key := l.libraryEntryWithField("doi", info.DOI); key != "" {
+			return fromLibrary(key, "DOI "+info.DOI+" in library")
+		}
+	}
+	if info.ISBN != "" {
+		if key := l.libraryEntryWithField("isbn", info.ISBN); key != "" {
+			return fromLibrary(key, "ISBN "+info.ISBN+" in library")
+		}
+	}
+
+	// A DBLP record with the DOI (arXiv papers have DOIs of their own).
+	dois := []string{}
+	if info.DOI != "" {
+		dois = append(dois, info.DOI)
+	}
+	if info.Arxiv != "" && !strings.Contains(info.Arxiv, "/") {
+		dois = append(dois, "10.48550/arxiv."+strings.ToLower(info.Arxiv))
+	}
+	for _, doi := range dois {
+		for _, dblpKey := range readDblpDOILinks(doi) {
+			if record := dblpEntryFromFile(dblpKey); record != nil {
+				for field, value := range record.Fields {
+					e.Fields[field] = value
+				}
+				e.Fields[DBLPField] = dblpKey
+				e.Fields[LocalURLField] = path
+				return e, "DOI " + doi + " in DBLP (" + dblpKey + ")"
+			}
+		}
+	}
+
+	// A skeleton entry.
+	e.Fields[EntryTypeField] = "misc"
+	how := "skeleton from PDF text"
+	titles := append([]string{}, info.TextTitles...)
+	if info.Title != "" {
+		titles = append([]string{info.Title}, titles...)
+		how = "skeleton from PDF metadata"
+	}
+	for _, title := range titles {
+		if len(readDblpTitleLinks(libraryTitleHash(title))) > 0 {
+			e.Fields[TitleField] = title
+			how = "title known to DBLP"
+			break
+		}
+	}
+	if e.Fields[TitleField] == "" {
+		if len(titles) > 0 {
+			e.Fields[TitleField] = titles[0]
+		} else {
+			e.Fields[TitleField] = strings.NewReplacer("_", " ", "-", " ").Replace(
+				strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+			how = "file name only"
+		}
+	}
+	if len(info.Authors) > 0 {
+		e.Fields["author"] = strings.Join(info.Authors, " and ")
+	}
+	if info.DOI != "" {
+		e.Fields["doi"] = info.DOI
+	}
+	if info.ISBN != "" {
+		e.Fields[EntryTypeField] = "book"
+		e.Fields["isbn"] = info.ISBN
+	}
+	if info.Arxiv != "" {
+		e.Fields["url"] = "https://arxiv.org/abs/" + info.Arxiv
+	}
+	e.Fields[LocalURLField] = path
+	return e, how
+}
+
+// harvestPDFCandidates returns a harvest candidate for each PDF in (the folders
+// below) dir.
+func (l *TBibTeXLibrary) harvestPDFCandidates(dir string) []TBibTeXEntry {
+	var paths []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error { //nolint:errcheck
+		if err == nil && !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".pdf") {
+			if abs, err := filepath.Abs(path); err == nil {
+				paths = append(paths, abs)
+			}
+		}
+		return nil
+	})
+
+	var entries []TBibTeXEntry
+	ticker := l.NewProgressTicker(ProgressHarvestPDFsReading, len(paths))
+	for _, path := range paths {
+		if ticker.Step() {
+			break
+		}
+		if !isValidPDF(path) {
+			l.Warning(WarningHarvestPDFInvalid, path)
+			continue
+		}
+		e, how := l.harvestPDFCandidate(path)
+		l.Progress(ProgressHarvestPDFIdentified, filepath.Base(path), how)
+		entries = append(entries, e)
+	}
+	ticker.Done()
+	return entries
+}
+
+// doHarvestPDFs opens the library and runs the interactive harvest loop over the
+// PDFs in dir.
+func doHarvestPDFs(dir string) {
+	if !openLibraryToUpdate() {
+		return
+	}
+	Library.ReadKeyNonDoublesFile()
+
+	entries := Library.harvestPDFCandidates(dir)
+	if len(entries) == 0 {
+		Library.Progress(ProgressHarvestPDFsNone, dir)
+		return
+	}
+	plural := "ies"
+	if len(entries) == 1 {
+		plural = "y"
+	}
+	Library.Progress(ProgressHarvestParsed, len(entries), plural, dir)
+	Library.runHarvestLoop(entries, nil)
+}
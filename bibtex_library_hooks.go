@@ -0,0 +1,324 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_hooks
+ *
+ * Event hooks. Hooks are configured in the .settings file:
+ *
+ *	"hooks": [
+ *	  {"event": "entry_created", "actions": true},
+ *	  {"event": "after_sync", "command": "~/bin/bib-synced.sh"}
+ *	]
+ *
+ * Events: entry_created (DBLP import, harvest, watch), entry_merged,
+ * field_changed, group_changed and after_sync (once the DB side of -sync is done).
+ *
+ * With "actions", the entry_actions rules are run on the keys affected by the
+ * event. With "command", the shell command receives the pending events as a JSON
+ * array on stdin, and may print a THookResult on stdout whose changes are applied
+ * to the library with "hook" as the lineage source.
+ *
+ * Events are queued and dispatched at a safe point: right away when no bib
+ * transaction or DBLP import is in progress, otherwise once it has finished.
+ * Field and group changes only wait for the next such point (at the latest the
+ * end of the session). Changes made by hooks do not trigger hooks themselves.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Hook events.
+const (
+	HookEntryCreated = "entry_created"
+	HookEntryMerged  = "entry_merged"
+	HookFieldChanged = "field_changed"
+	HookGroupChanged = "group_changed"
+	HookAfterSync    = "after_sync"
+
+	// hookLineageSource is the lineage source of changes made by hook commands.
+	hookLineageSource = "hook"
+)
+
+// THook is one hook of the hooks list in the .settings file.
+type THook struct {
+	Event   string `json:"event"`
+	Actions bool   `json:"actions,omitempty"` // run the entry_actions rules on the affected keys
+	Command string `json:"command,omitempty"` // shell command; receives the events as JSON on stdin
+}
+
+// THookEvent describes one event, as passed to hook commands.
+type THookEvent struct {
+	Event      string   `json:"event"`
+	Keys       []string `json:"keys,omitempty"`
+	Source     string   `json:"source,omitempty"`      // entry_created: "dblp", "harvest" or "watch"
+	MergedFrom string   `json:"merged_from,omitempty"` // entry_merged: the key merged into Keys[0]
+	Field      string   `json:"field,omitempty"`       // field_changed
+	Old        string   `json:"old,omitempty"`         // field_changed
+	New        string   `json:"new,omitempty"`         // field_changed
+	Group      string   `json:"group,omitempty"`       // group_changed
+	Added      bool     `json:"added,omitempty"`       // group_changed: added to (true) or removed from Group
+	Files      []string `json:"files,omitempty"`       // after_sync: the synced files
+}
+
+// THookResult is what a hook command may print on stdout.
+type THookResult struct {
+	Fields []struct {
+		Key   string `json:"key"`
+		Field string `json:"field"`
+		Value string `json:"value"` // "" clears the field
+	} `json:"fields,omitempty"`
+	Groups []struct {
+		Key   string `json:"key"`
+		Group string `json:"group"`
+		Add   bool   `json:"add"` // false removes the entry from the group
+	} `json:"groups,omitempty"`
+}
+
+var (
+	configuredHooks []THook // from the .settings file (see loadBibTeXFolders)
+
+	hookQueue       []THookEvent
+	hookQueueAtTx   int  // len(hookQueue) when the outermost bib transaction began
+	hookHoldDepth   int  // > 0 while an operation that must not be interrupted by hooks runs
+	hookDispatching bool // true while hooks run, so their own changes do not fire hooks
+)
+
+// knownHookEvents lists the valid event names, for checking the .settings file.
+func knownHookEvents() []string {
+	return []string{HookEntryCreated, HookEntryMerged, HookFieldChanged, HookGroupChanged, HookAfterSync}
+}
+
+// validateHooks warns about hooks with an unknown event or nothing to do.
+func validateHooks(hooks []THook) {
+	for _, h := range hooks {
+		known := false
+		for _, event := range knownHookEvents() {
+			known = known || h.Event == event
+		}
+		switch {
+		case !known:
+			Reporting.Warning(WarningHookUnknownEvent, h.Event, strings.Join(knownHookEvents(), ", "))
+		case !h.Actions && h.Command == "":
+			Reporting.Warning(WarningHookWithoutAction, h.Event)
+		}
+	}
+}
+
+// hooksFor reports whether any hook is configured for event.
+func hooksFor(event string) bool {
+	for _, h := range configuredHooks {
+		if h.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// fireHook queues ev for the hooks configured for its event. Entry creation,
+// merge and sync events are dispatched at once when it is safe to do so.
+func (l *TBibTeXLibrary) fireHook(ev THookEvent) {
+	if hookDispatching || !hooksFor(ev.Event) {
+		return
+	}
+	hookQueue = append(hookQueue, ev)
+	if ev.Event != HookFieldChanged && ev.Event != HookGroupChanged {
+		l.flushHooksIfSafe()
+	}
+}
+
+// fieldChangeHooked reports whether field changes must be reported, i.e. whether
+// a write should first read the old value.
+func fieldChangeHooked() bool {
+	return !hookDispatching && hooksFor(HookFieldChanged)
+}
+
+// fireFieldChanged queues a field_changed event when the value actually changed.
+func (l *TBibTeXLibrary) fireFieldChanged(key, field, oldValue, newValue string) {
+	if oldValue != newValue {
+		l.fireHook(THookEvent{Event: HookFieldChanged, Keys: []string{key}, Field: field, Old: oldValue, New: newValue})
+	}
+}
+
+// fireGroupChanged queues a group_changed event when result shows that the
+// membership of key in group actually changed.
+func fireGroupChanged(result sql.Result, group, key string, added bool) {
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		Library.fireHook(THookEvent{Event: HookGroupChanged, Keys: []string{key}, Group: group, Added: added})
+	}
+}
+
+// holdHooks postpones dispatching until the matching releaseHooks.
+func holdHooks() {
+	hookHoldDepth++
+}
+
+// releaseHooks ends a holdHooks and dispatches the queued events when safe.
+func (l *TBibTeXLibrary) releaseHooks() {
+	hookHoldDepth--
+	l.flushHooksIfSafe()
+}
+
+// flushHooksIfSafe dispatches the queued events unless a bib transaction or a
+// held operation is in progress.
+func (l *TBibTeXLibrary) flushHooksIfSafe() {
+	if activeTx == nil && hookHoldDepth == 0 {
+		l.flushHooks()
+	}
+}
+
+// discardHooksSince drops the events queued after the first n, e.g. when the
+// transaction they were queued in is rolled back. Events queued before it began
+// describe changes that are already committed, so they are kept.
+func discardHooksSince(n int) {
+	if n < len(hookQueue) {
+		hookQueue = hookQueue[:n]
+	}
+}
+
+// flushHooks dispatches all queued events to their hooks.
+func (l *TBibTeXLibrary) flushHooks() {
+	if hookDispatching || len(hookQueue) == 0 {
+		return
+	}
+	hookDispatching = true
+	defer func() { hookDispatching = false }()
+
+	events := hookQueue
+	hookQueue = nil
+	for _, h := range configuredHooks {
+		var matching []THookEvent
+		for _, ev := range events {
+			if ev.Event == h.Event {
+				matching = append(matching, ev)
+			}
+		}
+		if len(matching) == 0 {
+			continue
+		}
+		if h.Actions {
+			l.runHookActions(h.Event, matching)
+		}
+		if h.Command != "" {
+			l.runHookCommand(h, matching)
+		}
+	}
+}
+
+// runHookActions runs the entry_actions rules on the keys affected by events.
+// An event without keys (after_sync) affects the whole library.
+func (l *TBibTeXLibrary) runHookActions(event string, events []THookEvent) {
+	prog := l.entryActions()
+	if prog == nil || len(prog.rules) == 0 {
+		return
+	}
+	keys := TStringSetNew()
+	whole := false
+	for _, ev := range events {
+		whole = whole || len(ev.Keys) == 0
+		for _, key := range ev.Keys {
+			if key = l.MapEntryKey(key); l.EntryExists(key) {
+				keys.Add(key)
+			}
+		}
+	}
+	prog.changes = 0
+	if whole {
+		forEachBibEntryKey(func(key string) bool {
+			l.applyScriptRules(prog, key)
+			return !l.QuitWasRequested()
+		})
+	} else {
+		for _, key := range keys.ElementsSorted() {
+			l.applyScriptRules(prog, key)
+		}
+	}
+	if prog.changes > 0 {
+		l.Progress(ProgressHookActions, event, prog.changes)
+	}
+}
+
+// runHookCommand runs h.Command with events as JSON on stdin and applies the
+// THookResult it prints, if any.
+func (l *TBibTeXLibrary) runHookCommand(h THook, events []THookEvent) {
+	input, err := json.Marshal(events)
+	if err != nil {
+		l.Warning(WarningHookCommandFailed, h.Command, h.Event, err)
+		return
+	}
+	cmd := exec.Command("sh", "-c", expandHome(h.Command))
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		l.Warning(WarningHookCommandFailed, h.Command, h.Event, err)
+		return
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return
+	}
+	var result THookResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		l.Warning(WarningHookCommandOutput, h.Command, h.Event, err)
+		return
+	}
+	l.applyHookResult(h, result)
+}
+
+// applyHookResult applies the changes requested by a hook command.
+func (l *TBibTeXLibrary) applyHookResult(h THook, result THookResult) {
+	changes := 0
+	for _, f := range result.Fields {
+		key := l.MapEntryKey(f.Key)
+		if !l.EntryExists(key) || f.Field == "" || scriptProtectedFields.Contains(f.Field) {
+			l.Warning(WarningHookChangeRejected, h.Command, f.Key, f.Field)
+			continue
+		}
+		if l.EntryFieldValueity(key, f.Field) == f.Value {
+			continue
+		}
+		l.SetEntryFieldValue(key, f.Field, f.Value)
+		l.setLineage(key, f.Field, f.Value, hookLineageSource, false)
+		bibEntriesModified = true
+		changes++
+	}
+	for _, g := range result.Groups {
+		key := l.MapEntryKey(g.Key)
+		if !l.EntryExists(key) || g.Group == "" {
+			l.Warning(WarningHookChangeRejected, h.Command, g.Key, g.Group)
+			continue
+		}
+		member := l.GroupEntries[g.Group].Set().Contains(key)
+		switch {
+		case g.Add && !member:
+			if addBibGroupEntry(g.Group, key) == nil {
+				l.GroupEntries.AddValueToStringSetMap(g.Group, key)
+				bibEntriesModified = true
+				changes++
+			}
+		case !g.Add && member:
+			if removeBibGroupEntry(g.Group, key) == nil {
+				l.GroupEntries.DeleteValueFromStringSetMap(g.Group, key)
+				bibEntriesModified = true
+				changes++
+			}
+		}
+	}
+	if changes > 0 {
+		l.Progress(ProgressHookCommand, h.Command, h.Event, changes)
+	}
+}
@@ -39,8 +39,9 @@ import (
 // the field's actual current value self-invalidates the record.
 type TLineageRecord struct {
 	Value  string // the field value this record was recorded against
-	Source string // "dblp", "orcid", "" (unknown / manually set)
+	Source string // "dblp", "script", "orcid", "" (unknown / manually set)
 	Edited bool   // true when current value diverges from source's latest provision
+	Policy string // the conflict policy rule that chose this value, "" when not decided by policy
 }
 
 // TSourceFieldData captures a pre-computed snapshot of one source's field delivery.
@@ -56,8 +57,10 @@ type TSourceFieldData struct {
 // Higher value = higher authority. No two entries share the same value.
 // The zero value "" (unknown/manual) has the lowest priority.
 var lineagePriority = map[string]int{
-	"":     0,   // unknown or manually set without a confirmed source
-	"dblp": 100, // DBLP XML import
+	"":       0,   // unknown or manually set without a confirmed source
+	"hook":   40,  // result of an external hook command
+	"script": 50,  // entry_actions script rule
+	"dblp":   100, // DBLP XML import
 	// "orcid": 150, (future)
 }
 
@@ -137,25 +140,35 @@ func (l *TBibTeXLibrary) getLineage(key, field string) TLineageRecord {
 // since "we know this has no authoritative source" is itself meaningful and must
 // not be silently indistinguishable from "nothing was ever recorded".
 func (l *TBibTeXLibrary) setLineage(key, field, value, source string, edited bool) {
+	l.setLineageRecord(key, field, TLineageRecord{Value: value, Source: source, Edited: edited})
+}
+
+// setPolicyLineage is setLineage for a value chosen by the conflict policy rule
+// policy (see bibtex_library_policy.go).
+func (l *TBibTeXLibrary) setPolicyLineage(key, field, value, source string, edited bool, policy string) {
+	l.setLineageRecord(key, field, TLineageRecord{Value: value, Source: source, Edited: edited, Policy: policy})
+}
+
+func (l *TBibTeXLibrary) setLineageRecord(key, field string, rec TLineageRecord) {
 	var raw TLineageRecord
 	if fm, ok := l.LineageMap[key]; ok {
 		raw = fm[field]
 	}
-	if raw.Value == value && raw.Source == source && raw.Edited == edited {
+	if raw == rec {
 		return
 	}
 	if _, ok := l.LineageMap[key]; !ok {
 		l.LineageMap[key] = map[string]TLineageRecord{}
 	}
-	l.LineageMap[key][field] = TLineageRecord{Value: value, Source: source, Edited: edited}
+	l.LineageMap[key][field] = rec
 	editedInt := 0
-	if edited {
+	if rec.Edited {
 		editedInt = 1
 	}
 	dbExecSave("setLineage",
-		`INSERT INTO entry_lineage (entry_key, field, value, source, edited) VALUES (?, ?, ?, ?, ?)
-		 ON CONFLICT(entry_key, field) DO UPDATE SET value = excluded.value, source = excluded.source, edited = excluded.edited`,
-		key, field, value, source, editedInt)
+		`INSERT INTO entry_lineage (entry_key, field, value, source, edited, policy) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(entry_key, field) DO UPDATE SET value = excluded.value, source = excluded.source, edited = excluded.edited, policy = excluded.policy`,
+		key, field, rec.Value, rec.Source, editedInt, rec.Policy)
 }
 
 // getSourceFieldSignature returns the signature stored for (key, field, source),
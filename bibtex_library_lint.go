@@ -0,0 +1,112 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_lint
+ *
+ * Checking of the lint rules declared in the lint section of the entry_actions
+ * script. Findings are recorded in entry_warnings next to the built-in checks
+ * of CheckEntry, so they show up in "warnings;" selects, repair bibs, homework
+ * and statistics. A finding is waived for one entry by the entry flag
+ * FlagLintWaivedPrefix + rule name.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// lintWarningPrefix is the fixed start of WarningLintRule, used to find (and
+// replace) the lint findings among an entry's warnings.
+const lintWarningPrefix = "Lint "
+
+// lintWalkthrough is set by -fix_entries: each finding is then offered for
+// waiving rather than only recorded.
+var lintWalkthrough bool
+
+// lintRules returns the lint rules of the entry_actions script.
+func (l *TBibTeXLibrary) lintRules() []*scriptLintRule {
+	if prog := l.entryActions(); prog != nil {
+		return prog.lint
+	}
+	return nil
+}
+
+// lintWaiverFlag returns the entry flag that waives rule.
+func lintWaiverFlag(rule string) string {
+	return FlagLintWaivedPrefix + rule
+}
+
+// CheckLintRules evaluates the script's lint rules against entry, replacing
+// the entry's earlier lint findings.
+func (l *TBibTeXLibrary) CheckLintRules(entry *TBibTeXEntry) {
+	rules := l.lintRules()
+	if len(rules) == 0 {
+		return
+	}
+	key := entry.Key
+	deleteLintWarnings(key)
+
+	answers := TStringSetNew()
+	answers.Add("w", "s")
+	for _, rule := range rules {
+		if l.EntryHasFlag(key, lintWaiverFlag(rule.name)) {
+			continue
+		}
+		if !scriptEvalCond(l, key, l.entryActions(), rule.cond) {
+			continue
+		}
+		if !lintWalkthrough || l.InteractionIsOff() {
+			l.ReportEntryWarning(key, WarningLintRule, rule.severity, rule.name, rule.message)
+			continue
+		}
+		fmt.Fprint(os.Stderr, l.entryDisplayString(key))
+		if l.WarningQuestion(QuestionLintRule, answers, "Entry %s: "+WarningLintRule, key, rule.severity, rule.name, rule.message) == "w" {
+			l.SetEntryFlag(key, lintWaiverFlag(rule.name))
+			l.Progress(ProgressLintRuleWaived, rule.name, key)
+			continue
+		}
+		insertEntryWarning(key, fmt.Sprintf(WarningLintRule, rule.severity, rule.name, rule.message))
+		if l.QuitWasRequested() {
+			return
+		}
+	}
+}
+
+// deleteLintWarnings removes the recorded lint findings of key.
+func deleteLintWarnings(key string) {
+	dbExecSave("deleteLintWarnings", `DELETE FROM entry_warnings WHERE key = ? AND warning LIKE ?`, key, lintWarningPrefix+"%")
+	touchEntryWarnings()
+}
+
+// lintFindingKeys returns the sorted keys of the entries with lint findings.
+func lintFindingKeys() []string {
+	rows, err := bibQuery(`SELECT DISTINCT key FROM entry_warnings WHERE warning LIKE ? ORDER BY key`, lintWarningPrefix+"%")
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if rows.Scan(&key) == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// countLintFindings returns the number of entries with lint findings.
+func countLintFindings() int {
+	var n int
+	bibQueryRow(`SELECT COUNT(DISTINCT key) FROM entry_warnings WHERE warning LIKE ?`, lintWarningPrefix+"%").Scan(&n) //nolint:errcheck
+	return n
+}
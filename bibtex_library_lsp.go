@@ -0,0 +1,592 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_lsp
+ *
+ * Language server for citations (-lsp, JSON-RPC over stdio).
+ *
+ * In .tex files it completes the keys of \cite{...} commands (matching on
+ * preferred aliases, keys and old keys, and always inserting the preferred
+ * form), shows the rendered reference on hover, flags unknown and superseded
+ * keys as diagnostics, and offers a code action to replace a key by its
+ * preferred alias. In .bib files it shows the tool's own entry warnings as
+ * diagnostics on the entry keys, and the rendered reference on hover.
+ *
+ * The library is opened read-only, and reloaded when another run changes it.
+ * Only full-document synchronisation is supported.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LSP protocol constants used below.
+const (
+	lspSeverityError       = 1
+	lspSeverityWarning     = 2
+	lspSeverityInformation = 3
+
+	lspCompletionReference = 18 // CompletionItemKind.Reference
+	lspCompletionLimit     = 200
+
+	lspErrorMethodNotFound = -32601
+	lspErrorInvalidParams  = -32602
+)
+
+var (
+	// lspCitePattern matches a citation command up to and including its key
+	// list, e.g. \cite{a,b}, \citep[p.~3]{a} or \textcite*{a}.
+	lspCitePattern = regexp.MustCompile(`\\[A-Za-z]*cite[A-Za-z]*\*?\s*(?:\[[^\]]*\]\s*){0,2}\{([^}]*)\}`)
+	// lspOpenCitePattern matches the text before the open brace of a citation
+	// command whose key list is being typed.
+	lspOpenCitePattern = regexp.MustCompile(`\\[A-Za-z]*cite[A-Za-z]*\*?\s*(?:\[[^\]]*\]\s*){0,2}$`)
+	// lspBibEntryPattern matches the head of a .bib entry, up to its key.
+	lspBibEntryPattern = regexp.MustCompile(`@([A-Za-z]+)\s*[{(]\s*([^,\s]+)\s*,`)
+)
+
+type (
+	TLspPosition struct {
+		Line      int `json:"line"`
+		Character int `json:"character"`
+	}
+
+	TLspRange struct {
+		Start TLspPosition `json:"start"`
+		End   TLspPosition `json:"end"`
+	}
+
+	TLspDiagnostic struct {
+		Range    TLspRange `json:"range"`
+		Severity int       `json:"severity"`
+		Source   string    `json:"source"`
+		Message  string    `json:"message"`
+	}
+
+	TLspTextEdit struct {
+		Range   TLspRange `json:"range"`
+		NewText string    `json:"newText"`
+	}
+
+	// TLspKeyOccurrence is an entry key as written in a document.
+	TLspKeyOccurrence struct {
+		Key   string
+		Range TLspRange
+	}
+
+	// TLspCompletionCandidate is one entry as offered for completion.
+	TLspCompletionCandidate struct {
+		Key       string   // canonical key
+		Preferred string   // text to insert: the preferred alias, or the key
+		Names     []string // preferred alias, key and old keys, matched against what was typed
+		Detail    string   // year and title
+	}
+
+	// TLspServer holds the state of a language server session.
+	TLspServer struct {
+		in          *bufio.Reader
+		out         io.Writer
+		documents   map[string]string // uri → text
+		loadedStamp string            // tablesStamp(serveLibraryTables) when the library was (re)loaded
+		candidates  []TLspCompletionCandidate
+		shutdown    bool
+	}
+
+	lspMessage struct {
+		ID     *json.RawMessage `json:"id,omitempty"`
+		Method string           `json:"method"`
+		Params json.RawMessage  `json:"params"`
+	}
+
+	lspDocumentParams struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+		Position TLspPosition `json:"position"`
+		Range    TLspRange    `json:"range"`
+	}
+)
+
+// utf16Column converts a byte offset in line into an LSP (UTF-16) column.
+func utf16Column(line string, offset int) int {
+	column := 0
+	for _, r := range line[:offset] {
+		if r > 0xFFFF {
+			column += 2
+		} else {
+			column++
+		}
+	}
+	return column
+}
+
+// byteOffset converts an LSP (UTF-16) column in line into a byte offset.
+func byteOffset(line string, column int) int {
+	for offset, r := range line {
+		if column <= 0 {
+			return offset
+		}
+		if r > 0xFFFF {
+			column -= 2
+		} else {
+			column--
+		}
+	}
+	return len(line)
+}
+
+func lspLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+func lspRange(lines []string, line, start, end int) TLspRange {
+	return TLspRange{
+		Start: TLspPosition{line, utf16Column(lines[line], start)},
+		End:   TLspPosition{line, utf16Column(lines[line], end)},
+	}
+}
+
+func lspRangeContains(r TLspRange, p TLspPosition) bool {
+	if p.Line < r.Start.Line || p.Line > r.End.Line {
+		return false
+	}
+	if p.Line == r.Start.Line && p.Character < r.Start.Character {
+		return false
+	}
+	return p.Line != r.End.Line || p.Character <= r.End.Character
+}
+
+func lspRangesOverlap(a, b TLspRange) bool {
+	return lspRangeContains(a, b.Start) || lspRangeContains(a, b.End) ||
+		lspRangeContains(b, a.Start) || lspRangeContains(b, a.End)
+}
+
+// texCiteKeys returns the keys cited in a .tex document.
+func texCiteKeys(lines []string) []TLspKeyOccurrence {
+	var keys []TLspKeyOccurrence
+	for n, line := range lines {
+		if comment := texCommentStart(line); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, match := range lspCitePattern.FindAllStringSubmatchIndex(line, -1) {
+			start := match[2]
+			for _, part := range strings.Split(line[match[2]:match[3]], ",") {
+				trimmed := strings.TrimSpace(part)
+				if trimmed != "" {
+					keyStart := start + strings.Index(part, trimmed)
+					keys = append(keys, TLspKeyOccurrence{trimmed, lspRange(lines, n, keyStart, keyStart+len(trimmed))})
+				}
+				start += len(part) + 1
+			}
+		}
+	}
+	return keys
+}
+
+// texCommentStart returns the offset of the first unescaped % in line, or -1.
+func texCommentStart(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '%':
+			return i
+		}
+	}
+	return -1
+}
+
+// bibEntryKeys returns the entry keys of a .bib document.
+func bibEntryKeys(lines []string) []TLspKeyOccurrence {
+	var keys []TLspKeyOccurrence
+	for n, line := range lines {
+		for _, match := range lspBibEntryPattern.FindAllStringSubmatchIndex(line, -1) {
+			switch strings.ToLower(line[match[2]:match[3]]) {
+			case "string", "comment", "preamble":
+				continue
+			}
+			keys = append(keys, TLspKeyOccurrence{line[match[4]:match[5]], lspRange(lines, n, match[4], match[5])})
+		}
+	}
+	return keys
+}
+
+// citeKeyStatus resolves a key as written to its entry, and returns the form
+// it should preferably be written in.
+func (l *TBibTeXLibrary) citeKeyStatus(written string) (key, preferred string, known bool) {
+	key = resolveInputKey(written)
+	if key == "" || !l.EntryExists(key) {
+		return key, "", false
+	}
+	preferred = l.PreferredKey(key)
+	if preferred == "" {
+		preferred = key
+	}
+	return key, preferred, true
+}
+
+func (s *TLspServer) texDiagnostics(lines []string) []TLspDiagnostic {
+	diagnostics := []TLspDiagnostic{}
+	for _, occurrence := range texCiteKeys(lines) {
+		key, preferred, known := Library.citeKeyStatus(occurrence.Key)
+		switch {
+		case !known:
+			diagnostics = append(diagnostics, TLspDiagnostic{occurrence.Range, lspSeverityError, "bibtex_check",
+				fmt.Sprintf("Unknown citation key %s", occurrence.Key)})
+		case occurrence.Key != key && occurrence.Key != preferred:
+			diagnostics = append(diagnostics, TLspDiagnostic{occurrence.Range, lspSeverityWarning, "bibtex_check",
+				fmt.Sprintf("%s is a superseded key of %s; its preferred alias is %s", occurrence.Key, key, preferred)})
+		case occurrence.Key != preferred:
+			diagnostics = append(diagnostics, TLspDiagnostic{occurrence.Range, lspSeverityInformation, "bibtex_check",
+				fmt.Sprintf("The preferred alias of %s is %s", occurrence.Key, preferred)})
+		}
+	}
+	return diagnostics
+}
+
+func (s *TLspServer) bibDiagnostics(lines []string) []TLspDiagnostic {
+	diagnostics := []TLspDiagnostic{}
+	for _, occurrence := range bibEntryKeys(lines) {
+		key, _, known := Library.citeKeyStatus(occurrence.Key)
+		if !known {
+			continue
+		}
+		for _, warning := range entryWarningTexts(key) {
+			diagnostics = append(diagnostics, TLspDiagnostic{occurrence.Range, lspSeverityWarning, "bibtex_check", warning})
+		}
+	}
+	return diagnostics
+}
+
+// publishDiagnostics sends the diagnostics of the document at uri.
+func (s *TLspServer) publishDiagnostics(uri string) {
+	text, ok := s.documents[uri]
+	if !ok {
+		return
+	}
+	diagnostics := []TLspDiagnostic{}
+	switch {
+	case strings.HasSuffix(uri, ".tex"):
+		diagnostics = s.texDiagnostics(lspLines(text))
+	case strings.HasSuffix(uri, BibFileExtension):
+		diagnostics = s.bibDiagnostics(lspLines(text))
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diagnostics})
+}
+
+// completionCandidates returns (and caches until the next reload) the entries
+// as offered for completion, sorted on the text they insert.
+func (s *TLspServer) completionCandidates() []TLspCompletionCandidate {
+	if s.candidates != nil {
+		return s.candidates
+	}
+	oldKeys := map[string][]string{}
+	Library.KeyOldies.ForEachPersistent(func(alias, canonical string) {
+		oldKeys[canonical] = append(oldKeys[canonical], alias)
+	})
+	candidates := []TLspCompletionCandidate{}
+	forEachBibEntryKey(func(key string) bool {
+		c := TLspCompletionCandidate{Key: key, Preferred: Library.PreferredKey(key)}
+		if c.Preferred == "" {
+			c.Preferred = key
+		}
+		c.Names = append([]string{c.Preferred, key}, oldKeys[key]...)
+		entry := loadEntryFromDb(key)
+		c.Detail = strings.TrimSpace(entry.FieldValue("year") + " " + texToText(entry.FieldValue(TitleField)))
+		candidates = append(candidates, c)
+		return true
+	})
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Preferred < candidates[j].Preferred })
+	s.candidates = candidates
+	return candidates
+}
+
+func (s *TLspServer) completion(params lspDocumentParams) any {
+	empty := map[string]any{"isIncomplete": false, "items": []any{}}
+	text, ok := s.documents[params.TextDocument.URI]
+	if !ok || !strings.HasSuffix(params.TextDocument.URI, ".tex") {
+		return empty
+	}
+	lines := lspLines(text)
+	if params.Position.Line >= len(lines) {
+		return empty
+	}
+	line := lines[params.Position.Line]
+	cursor := byteOffset(line, params.Position.Character)
+	open := strings.LastIndex(line[:cursor], "{")
+	if open < 0 || strings.Contains(line[open:cursor], "}") || !lspOpenCitePattern.MatchString(line[:open]) {
+		return empty
+	}
+	start := open + 1
+	if comma := strings.LastIndex(line[:cursor], ","); comma > open {
+		start = comma + 1
+	}
+	for start < cursor && (line[start] == ' ' || line[start] == '\t') {
+		start++
+	}
+	typed := strings.ToLower(line[start:cursor])
+	replace := lspRange(lines, params.Position.Line, start, cursor)
+
+	items := []map[string]any{}
+	incomplete := false
+	for _, c := range s.completionCandidates() {
+		matched := ""
+		for _, name := range c.Names {
+			if strings.HasPrefix(strings.ToLower(name), typed) {
+				matched = name
+				break
+			}
+		}
+		if matched == "" {
+			continue
+		}
+		if len(items) == lspCompletionLimit {
+			incomplete = true
+			break
+		}
+		items = append(items, map[string]any{
+			"label":      c.Preferred,
+			"kind":       lspCompletionReference,
+			"detail":     c.Detail,
+			"filterText": matched,
+			"textEdit":   TLspTextEdit{replace, c.Preferred},
+			"data":       c.Key,
+		})
+	}
+	return map[string]any{"isIncomplete": incomplete, "items": items}
+}
+
+// resolveCompletionItem adds the entry display string as documentation.
+func (s *TLspServer) resolveCompletionItem(raw json.RawMessage) any {
+	var item map[string]any
+	if json.Unmarshal(raw, &item) != nil {
+		return nil
+	}
+	if key, ok := item["data"].(string); ok && Library.EntryExists(key) {
+		item["documentation"] = map[string]string{"kind": "plaintext", "value": Library.entryDisplayString(key)}
+	}
+	return item
+}
+
+// keyAt returns the key occurrence of the document at the given position.
+func (s *TLspServer) keyAt(uri string, position TLspPosition) (TLspKeyOccurrence, bool) {
+	text, ok := s.documents[uri]
+	if !ok {
+		return TLspKeyOccurrence{}, false
+	}
+	var occurrences []TLspKeyOccurrence
+	switch {
+	case strings.HasSuffix(uri, ".tex"):
+		occurrences = texCiteKeys(lspLines(text))
+	case strings.HasSuffix(uri, BibFileExtension):
+		occurrences = bibEntryKeys(lspLines(text))
+	}
+	for _, occurrence := range occurrences {
+		if lspRangeContains(occurrence.Range, position) {
+			return occurrence, true
+		}
+	}
+	return TLspKeyOccurrence{}, false
+}
+
+func (s *TLspServer) hover(params lspDocumentParams) any {
+	occurrence, ok := s.keyAt(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil
+	}
+	key, _, known := Library.citeKeyStatus(occurrence.Key)
+	if !known {
+		return nil
+	}
+	return map[string]any{
+		"contents": map[string]string{"kind": "plaintext", "value": Library.renderAsText(key)},
+		"range":    occurrence.Range,
+	}
+}
+
+func (s *TLspServer) codeActions(params lspDocumentParams) any {
+	actions := []any{}
+	uri := params.TextDocument.URI
+	text, ok := s.documents[uri]
+	if !ok || !strings.HasSuffix(uri, ".tex") {
+		return actions
+	}
+	for _, occurrence := range texCiteKeys(lspLines(text)) {
+		if !lspRangesOverlap(occurrence.Range, params.Range) {
+			continue
+		}
+		_, preferred, known := Library.citeKeyStatus(occurrence.Key)
+		if !known || occurrence.Key == preferred {
+			continue
+		}
+		actions = append(actions, map[string]any{
+			"title": fmt.Sprintf("Replace %s with preferred alias %s", occurrence.Key, preferred),
+			"kind":  "quickfix",
+			"edit": map[string]any{
+				"changes": map[string][]TLspTextEdit{uri: {{occurrence.Range, preferred}}},
+			},
+		})
+	}
+	return actions
+}
+
+// maybeReload reloads the library when another run has changed it since it
+// was (re)loaded, and then refreshes the diagnostics of the open documents.
+func (s *TLspServer) maybeReload() {
+	stamp := tablesStamp(serveLibraryTables)
+	if stamp == s.loadedStamp || !openLibraryToReport() {
+		return
+	}
+	s.loadedStamp = stamp
+	s.candidates = nil
+	for uri := range s.documents {
+		s.publishDiagnostics(uri)
+	}
+}
+
+// readMessage reads one base-protocol message (headers, blank line, content).
+func (s *TLspServer) readMessage() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("bad Content-Length header: %s", err)
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(s.in, content)
+	return content, err
+}
+
+func (s *TLspServer) write(message map[string]any) {
+	message["jsonrpc"] = "2.0"
+	content, err := json.Marshal(message)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: could not encode a message: %s\n", err)
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+func (s *TLspServer) notify(method string, params any) {
+	s.write(map[string]any{"method": method, "params": params})
+}
+
+func (s *TLspServer) respond(id *json.RawMessage, result any) {
+	s.write(map[string]any{"id": id, "result": result})
+}
+
+func (s *TLspServer) respondError(id *json.RawMessage, code int, message string) {
+	s.write(map[string]any{"id": id, "error": map[string]any{"code": code, "message": message}})
+}
+
+// dispatch handles one request or notification.
+func (s *TLspServer) dispatch(message lspMessage) {
+	var params lspDocumentParams
+	if len(message.Params) > 0 && json.Unmarshal(message.Params, &params) != nil && message.ID != nil {
+		s.respondError(message.ID, lspErrorInvalidParams, "invalid params for "+message.Method)
+		return
+	}
+	switch message.Method {
+	case "initialize":
+		s.respond(message.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // full
+				"completionProvider": map[string]any{"triggerCharacters": []string{"{", ","}, "resolveProvider": true},
+				"hoverProvider":      true,
+				"codeActionProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "bibtex_check"},
+		})
+	case "initialized":
+	case "shutdown":
+		s.shutdown = true
+		s.respond(message.ID, nil)
+	case "exit":
+		if s.shutdown {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	case "textDocument/didOpen":
+		s.documents[params.TextDocument.URI] = params.TextDocument.Text
+		s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		if n := len(params.ContentChanges); n > 0 {
+			s.documents[params.TextDocument.URI] = params.ContentChanges[n-1].Text
+			s.publishDiagnostics(params.TextDocument.URI)
+		}
+	case "textDocument/didSave":
+		s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		delete(s.documents, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", map[string]any{"uri": params.TextDocument.URI, "diagnostics": []any{}})
+	case "textDocument/completion":
+		s.respond(message.ID, s.completion(params))
+	case "completionItem/resolve":
+		s.respond(message.ID, s.resolveCompletionItem(message.Params))
+	case "textDocument/hover":
+		s.respond(message.ID, s.hover(params))
+	case "textDocument/codeAction":
+		s.respond(message.ID, s.codeActions(params))
+	default:
+		if message.ID != nil {
+			s.respondError(message.ID, lspErrorMethodNotFound, "method not supported: "+message.Method)
+		}
+	}
+}
+
+// ServeLanguageServer opens the library read-only and runs a language server
+// on stdin/stdout until the client asks it to exit.
+func ServeLanguageServer() error {
+	if !openLibraryToReport() {
+		return fmt.Errorf("the library could not be opened")
+	}
+	s := &TLspServer{
+		in:          bufio.NewReader(os.Stdin),
+		out:         os.Stdout,
+		documents:   map[string]string{},
+		loadedStamp: tablesStamp(serveLibraryTables),
+	}
+	for {
+		content, err := s.readMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var message lspMessage
+		if err := json.Unmarshal(content, &message); err != nil {
+			fmt.Fprintf(os.Stderr, "lsp: ignoring a malformed message\n")
+			continue
+		}
+		s.maybeReload()
+		s.dispatch(message)
+	}
+}
@@ -0,0 +1,463 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_merge_library
+ *
+ * Merging another bibtex_check library database into ours (-merge_library).
+ *
+ * The other library is opened read-only and taken over in three passes:
+ *   1. Contributors — matched by ORCID, DBLP key, identical ID + name, or an
+ *      unambiguous name; unmatched ones are added (keeping their ID when free).
+ *      Name variants and ORCIDs are united; foreign IDs that differ from ours
+ *      are recorded in contributor_id_oldies.
+ *   2. Entries — matched by key (a shared ancestor, or an earlier merge), DBLP
+ *      key, DOI, and finally title. Key/DBLP/DOI matches are conclusive and go
+ *      straight into MergeEntries; title matches are offered via MaybeMergeEntries.
+ *      Foreign keys are recorded in key_oldies so old citations keep resolving.
+ *   3. Decision tables — non-doubles, DBLP waivers, superseded values, and key
+ *      aliases/hints are united after mapping foreign keys and IDs into ours.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TForeignLibrary is a read-only view of another library's database, together
+// with the mappings from its keys and contributor IDs into ours.
+type TForeignLibrary struct {
+	path           string
+	db             *sql.DB
+	keyMap         map[string]string // foreign entry key → our key
+	contributorMap map[string]string // foreign contributor ID → our contributor ID
+}
+
+// openForeignLibrary opens the home database of the library with basename base.
+// Returns nil (after a warning) when it is missing, unreadable, or our own.
+func openForeignLibrary(base string) *TForeignLibrary {
+	if abs, err := filepath.Abs(base); err == nil {
+		base = abs
+	}
+	path := stripKnownBaseExtension(base) + cacheFileExtension
+	if path == dbHomePath() {
+		dbInteraction.Warning("merge_library: %s is this library's own database", path)
+		return nil
+	}
+	if !FileExists(path) {
+		dbInteraction.Warning("merge_library: no library database found at %s", path)
+		return nil
+	}
+	conn, err := sql.Open(sqliteDatabaseDriver, "file:"+path+"?mode=ro")
+	if err == nil {
+		err = conn.Ping()
+	}
+	if err != nil {
+		dbInteraction.Warning("merge_library: could not open %s: %s", path, err)
+		return nil
+	}
+	return &TForeignLibrary{
+		path:           path,
+		db:             conn,
+		keyMap:         map[string]string{},
+		contributorMap: map[string]string{},
+	}
+}
+
+func (f *TForeignLibrary) close() {
+	f.db.Close()
+}
+
+// rows runs query against the foreign database and returns all rows as strings.
+// Columns must be non-NULL (use COALESCE). A failing query — typically a table
+// the other library's schema version does not have yet — yields no rows.
+func (f *TForeignLibrary) rows(query string, columns int) [][]string {
+	rows, err := f.db.Query(query)
+	if err != nil {
+		dbInteraction.Progress("merge_library: skipped (%s)", err)
+		return nil
+	}
+	defer rows.Close()
+	var result [][]string
+	for rows.Next() {
+		row := make([]string, columns)
+		ptrs := make([]any, columns)
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if rows.Scan(ptrs...) == nil {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// ourKey maps a foreign entry key to our current canonical key, or "".
+func (f *TForeignLibrary) ourKey(l *TBibTeXLibrary, foreignKey string) string {
+	if key, ok := f.keyMap[foreignKey]; ok {
+		if key = l.MapEntryKey(key); l.EntryExists(key) {
+			return key
+		}
+	}
+	return ""
+}
+
+// entries returns the foreign library's entries, with author/editor reconstructed
+// from contributor_roles where the other library keeps them there.
+func (f *TForeignLibrary) entries() []TBibTeXEntry {
+	fields := map[string]map[string]string{}
+	for _, r := range f.rows(`SELECT entry_key, field, value FROM bib_entries ORDER BY entry_key, field`, 3) {
+		if fields[r[0]] == nil {
+			fields[r[0]] = map[string]string{}
+		}
+		fields[r[0]][r[1]] = r[2]
+	}
+	roles := map[string]map[string][]string{}
+	for _, r := range f.rows(
+		`SELECT cr.entry_key, cr.role, c.name FROM contributor_roles cr
+		 JOIN contributors c ON c.id = cr.contributor_id
+		 ORDER BY cr.entry_key, cr.role, cr.position`, 3) {
+		if roles[r[0]] == nil {
+			roles[r[0]] = map[string][]string{}
+		}
+		roles[r[0]][r[1]] = append(roles[r[0]][r[1]], r[2])
+	}
+	var entries []TBibTeXEntry
+	for key, fm := range fields {
+		if fm[EntryTypeField] == "" {
+			continue
+		}
+		for role, names := range roles[key] {
+			if fm[role] == "" {
+				fm[role] = strings.Join(names, " and ")
+			}
+		}
+		entries = append(entries, TBibTeXEntry{Key: key, Fields: fm})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// ── Contributors ──────────────────────────────────────────────────────────────
+
+// matchForeignContributor returns our contributor ID for a foreign contributor, or "".
+func (l *TBibTeXLibrary) matchForeignContributor(id, name, orcid, dblpKey string, garbled bool) string {
+	if orcid != "" {
+		if ours := orcidToContributorID(l, orcid); ours != "" {
+			return l.ResolveContributorID(ours)
+		}
+	}
+	if dblpKey != "" {
+		if ours := l.DblpKeyToContributorID[dblpKey]; ours != "" {
+			return l.ResolveContributorID(ours)
+		}
+	}
+	if ours := l.ResolveContributorID(id); l.ContributorByID[ours] != nil && l.ContributorByID[ours].Name == name {
+		return ours
+	}
+	if !garbled {
+		if candidates := contributorIDCandidates(l, name); len(candidates) == 1 {
+			return candidates[0]
+		}
+	}
+	return ""
+}
+
+// mergeForeignContributors maps every foreign contributor onto one of ours,
+// adding those we do not know yet.
+func (l *TBibTeXLibrary) mergeForeignContributors(f *TForeignLibrary) (matched, added int) {
+	for _, r := range f.rows(
+		`SELECT id, name, COALESCE(orcid, ''), COALESCE(dblp_key, ''), COALESCE(garbled, 0) FROM contributors ORDER BY id`, 5) {
+		id, name, orcid, dblpKey, garbled := r[0], r[1], r[2], r[3], r[4] != "0"
+		ours := l.matchForeignContributor(id, name, orcid, dblpKey, garbled)
+		if ours != "" {
+			matched++
+		} else {
+			ours = id
+			if l.IsKnownKey(ours) {
+				ours = l.NewKey()
+			}
+			if garbled {
+				upsertGarbledContributorToDB(ours, name)
+			} else {
+				upsertContributorToDB(ours, name, orcid)
+			}
+			upsertContributorNameToDB(ours, name)
+			l.ContributorByID[ours] = &TContributor{Name: name, Garbled: garbled}
+			l.NameToContributorID[name] = ours
+			added++
+		}
+		if dblpKey != "" && l.ContributorByID[ours] != nil && l.ContributorByID[ours].DblpKey == "" {
+			if _, taken := l.DblpKeyToContributorID[dblpKey]; !taken {
+				setContributorDblpKey(l, ours, dblpKey)
+			}
+		}
+		f.contributorMap[id] = ours
+	}
+
+	// Name variants and ORCIDs nobody on our side claims yet.
+	for _, r := range f.rows(`SELECT id, name FROM contributor_names ORDER BY id, name`, 2) {
+		if ours := f.contributorMap[r[0]]; ours != "" {
+			if _, known := resolveNameToContributorID(l, r[1]); !known {
+				upsertContributorNameToDB(ours, r[1])
+			}
+		}
+	}
+	for _, r := range f.rows(`SELECT orcid, contributor_id, is_canonical FROM contributor_orcids ORDER BY orcid`, 3) {
+		if ours := f.contributorMap[r[1]]; ours != "" && orcidToContributorID(l, r[0]) == "" {
+			canonical := r[2] != "0" && l.ContributorByID[ours] != nil && l.ContributorByID[ours].ORCID == ""
+			upsertContributorORCIDToDB(ours, r[0], canonical)
+			l.ORCIDToContributorID[r[0]] = ours
+		}
+	}
+
+	// Foreign IDs (and IDs the other library had already absorbed) that differ
+	// from ours become contributor ID oldies, unless that ID is a live one here.
+	recordOldie := func(foreignID, ours string) {
+		if foreignID == ours || l.ContributorByID[foreignID] != nil {
+			return
+		}
+		if _, known := l.ContributorIDOldies[foreignID]; known {
+			return
+		}
+		upsertContributorIDOldie(foreignID, ours)
+	}
+	for foreignID, ours := range f.contributorMap {
+		recordOldie(foreignID, ours)
+	}
+	for _, r := range f.rows(`SELECT absorbed_id, canonical_id FROM contributor_id_oldies`, 2) {
+		if ours := f.contributorMap[r[1]]; ours != "" {
+			recordOldie(r[0], ours)
+		}
+	}
+
+	loadContributorIDOldiesFromDB(l)
+	loadContributorORCIDsFromDB(l)
+	loadContributorsFromDb(l)
+	return matched, added
+}
+
+// ── Entries ───────────────────────────────────────────────────────────────────
+
+// matchForeignEntry returns a conclusive match for e (by key, DBLP key or DOI),
+// or else the candidates sharing e's title.
+func (l *TBibTeXLibrary) matchForeignEntry(e TBibTeXEntry) (string, []string) {
+	if key := l.MapEntryKey(e.Key); l.EntryExists(key) {
+		return key, nil
+	}
+	if dblpKey := e.Fields[DBLPField]; dblpKey != "" {
+		if key := l.MapEntryKey(LookupDblpCanonical(dblpKey)); key != "" && l.EntryExists(key) {
+			return key, nil
+		}
+	}
+	if doi := normalizeDOI(e.Fields["doi"]); doi != "" {
+		for _, m := range findBibEntriesByField("doi", doi) {
+			if normalizeDOI(m.Value) == doi {
+				return l.MapEntryKey(m.Key), nil
+			}
+		}
+		var aliased string
+		bibQueryRow(`SELECT entry_key FROM entry_doi_aliases WHERE doi = ?`, doi).Scan(&aliased) //nolint:errcheck
+		if key := l.MapEntryKey(aliased); key != "" && l.EntryExists(key) {
+			return key, nil
+		}
+	}
+	return "", l.harvestTitleMatches(e)
+}
+
+// recordForeignKey makes foreignKey resolve to ourKey, unless foreignKey is one of
+// our own live entry keys (which must keep meaning our entry).
+func (l *TBibTeXLibrary) recordForeignKey(foreignKey, ourKey string) bool {
+	if foreignKey == "" || foreignKey == ourKey || l.MapEntryKey(foreignKey) == ourKey {
+		return false
+	}
+	if l.EntryExists(foreignKey) {
+		l.Warning(WarningMergeLibraryKeyCollision, foreignKey, ourKey)
+		return false
+	}
+	l.AddKeyAlias(foreignKey, ourKey)
+	return true
+}
+
+// mergeForeignEntries takes over every foreign entry, merging it into a matching
+// entry of ours or adding it as a new one.
+func (l *TBibTeXLibrary) mergeForeignEntries(f *TForeignLibrary) (merged, added int) {
+	groups := map[string][]string{}
+	for _, r := range f.rows(`SELECT entry_key, group_name FROM bib_groups ORDER BY entry_key, group_name`, 2) {
+		groups[r[0]] = append(groups[r[0]], r[1])
+	}
+	metadata := map[string][][2]string{}
+	for _, r := range f.rows(`SELECT entry_key, property, value FROM entry_metadata ORDER BY entry_key, property`, 3) {
+		metadata[r[0]] = append(metadata[r[0]], [2]string{r[1], r[2]})
+	}
+
+	for _, e := range reorderHarvestCrossrefsFirst(f.entries()) {
+		if l.QuitWasRequested() {
+			return merged, added
+		}
+		if parent := f.ourKey(l, e.Fields["crossref"]); parent != "" {
+			e.Fields["crossref"] = parent
+		}
+
+		target, candidates := l.matchForeignEntry(e)
+		if target == "" && len(candidates) > 0 {
+			fmt.Fprintf(os.Stderr, "\nForeign entry %s:\n", e.Key)
+			printEntryFields(e.Fields[EntryTypeField], e.Key, e.Fields)
+		}
+		newKey := addHarvestEntry(l, e)
+		if target != "" {
+			l.MergeEntries(newKey, target)
+		} else {
+			for _, candidate := range candidates {
+				l.MaybeMergeEntries(newKey, candidate)
+			}
+		}
+		finalKey := l.MapEntryKey(newKey)
+		if finalKey != newKey {
+			merged++
+		} else {
+			added++
+		}
+		f.keyMap[e.Key] = finalKey
+		l.recordForeignKey(e.Key, finalKey)
+
+		for _, group := range groups[e.Key] {
+			if members, ok := l.GroupEntries[group]; ok && members.Set().Contains(finalKey) {
+				continue
+			}
+			if err := addBibGroupEntry(group, finalKey); err != nil {
+				l.Warning("Could not add %s to group %s: %s", finalKey, group, err)
+				continue
+			}
+			l.GroupEntries.AddValueToStringSetMap(group, finalKey)
+		}
+		for _, pv := range metadata[e.Key] {
+			switch {
+			case isEntryFlagProperty(pv[0]):
+				l.SetEntryFlag(finalKey, pv[0])
+			case !l.HasMetadata(finalKey, pv[0]):
+				l.SetMetadata(finalKey, pv[0], pv[1])
+			}
+		}
+	}
+	return merged, added
+}
+
+// ── Decision tables ───────────────────────────────────────────────────────────
+
+// mergeForeignDecisions unites the other library's earlier decisions with ours,
+// after mapping its keys and contributor IDs.
+func (l *TBibTeXLibrary) mergeForeignDecisions(f *TForeignLibrary) {
+	nonDoubles, waivers, superseded, aliases := 0, 0, 0, 0
+
+	for _, r := range f.rows(`SELECT key1, key2 FROM non_double_entries`, 2) {
+		a, b := f.ourKey(l, r[0]), f.ourKey(l, r[1])
+		if a != "" && b != "" && a != b && !l.NonDoubleEntries[a].Set().Contains(b) {
+			l.AddNonDoubleEntries(a, b)
+			nonDoubles++
+		}
+	}
+	for _, r := range f.rows(`SELECT contributor_id_a, contributor_id_b FROM non_double_contributors`, 2) {
+		a, b := f.contributorMap[r[0]], f.contributorMap[r[1]]
+		if a != "" && b != "" && a != b {
+			addNonDoubleContributorPair(a, b)
+		}
+	}
+	for _, r := range f.rows(`SELECT name1, name2 FROM non_double_contributor_names`, 2) {
+		if !isNonDoubleContributorNamePair(l, r[0], r[1]) {
+			addNonDoubleContributorNamePair(l, r[0], r[1])
+		}
+	}
+
+	for _, r := range f.rows(`SELECT key FROM dblp_waived`, 1) {
+		if key := f.ourKey(l, r[0]); key != "" && !l.DblpWaived.Contains(key) {
+			l.DblpWaived.Set(key, true)
+			waivers++
+		}
+	}
+
+	// A superseded value only carries over when its winner is still what we hold;
+	// otherwise the other library decided about a value we do not have.
+	for _, r := range f.rows(`SELECT entry_key, field, value, COALESCE(winner, '') FROM superseded_field_values`, 4) {
+		key, field, value, winner := f.ourKey(l, r[0]), r[1], r[2], r[3]
+		if key == "" || winner == "" || value == winner || l.EntryFieldValueity(key, field) != winner {
+			continue
+		}
+		if l.EntryFieldAliasHasTarget(key, field, value, winner) {
+			continue
+		}
+		l.AddEntryFieldAlias(key, field, value, winner, true)
+		superseded++
+	}
+
+	for _, r := range f.rows(`SELECT alias, key FROM key_oldies`, 2) {
+		if key := f.ourKey(l, r[1]); key != "" && l.recordForeignKey(r[0], key) {
+			aliases++
+		}
+	}
+	for _, r := range f.rows(`SELECT hint, key FROM key_hints`, 2) {
+		target := f.ourKey(l, r[1])
+		if target == "" {
+			target = f.ourKey(l, l.MapEntryKey(r[1]))
+		}
+		if target != "" && l.HintToKey.GetValue(r[0]) == "" {
+			l.AddKeyHint(r[0], target)
+			aliases++
+		}
+	}
+
+	l.Progress(ProgressMergeLibraryDecisions, nonDoubles, waivers, superseded, aliases)
+}
+
+// MergeLibrary takes the other library f over into ours: contributors first (so
+// names in foreign entries resolve to the right IDs), then entries, then decisions.
+func (l *TBibTeXLibrary) MergeLibrary(f *TForeignLibrary) {
+	matched, added := l.mergeForeignContributors(f)
+	l.Progress(ProgressMergeLibraryContributors, matched, added)
+
+	merged, added := l.mergeForeignEntries(f)
+	l.Progress(ProgressMergeLibraryEntries, merged, added)
+	if l.QuitWasRequested() {
+		return
+	}
+
+	l.mergeForeignDecisions(f)
+}
+
+// doMergeLibrary is the -merge_library entry point.
+func doMergeLibrary(otherBase string) {
+	f := openForeignLibrary(otherBase)
+	if f == nil {
+		return
+	}
+	defer f.close()
+
+	var entryCount, contributorCount int
+	f.db.QueryRow(`SELECT COUNT(DISTINCT entry_key) FROM bib_entries WHERE field = ?`, EntryTypeField).Scan(&entryCount) //nolint:errcheck
+	f.db.QueryRow(`SELECT COUNT(*) FROM contributors`).Scan(&contributorCount)                                           //nolint:errcheck
+
+	if !openLibraryToUpdate() {
+		return
+	}
+	Library.ReadKeyNonDoublesFile()
+	plural := "ies"
+	if entryCount == 1 {
+		plural = "y"
+	}
+	Library.Progress(ProgressMergeLibraryOpened, entryCount, plural, contributorCount, f.path)
+
+	Library.MergeLibrary(f)
+	bibEntriesModified = true
+}
@@ -21,6 +21,7 @@ package main
 import (
 	"encoding/json"
 	"os"
+	"strings"
 )
 
 // TEntryMetadata maps entry keys to per-entry property maps.
@@ -39,6 +40,10 @@ const (
 	MetaPropUrlCheckDate      = "url_check_date"   // ISO date of last URL plausibility check
 	MetaPropUrlCheckStatus    = "url_check_status" // "ok" or "dead"
 	MetaPropWaivedDoublePdf   = "waived_double_pdf" // MD5 of shared PDF content — waives duplicate-PDF warning
+	MetaPropPdfContentOk      = "pdf_content_ok"    // MD5 of a PDF confirmed to match its entry despite a low content score
+	MetaPropPdfMetadataFingerprint = "pdf_metadata_fingerprint" // fingerprint of the metadata embedded in the PDF (-embed_pdf_metadata)
+	MetaPropPdfFetchAttempts  = "pdf_fetch_attempts" // JSON list of attempts to fetch a PDF (-get_pdfs)
+	MetaPropReplacedAliases   = "replaced_aliases"   // space-separated former preferred aliases kept as key_oldies (alias templates)
 )
 
 // GetMetadata returns the value of property prop for entry key, or "" if absent.
@@ -89,12 +94,17 @@ func (l *TBibTeXLibrary) transferMetadata(source, target string) {
 		MetaPropPdfConfirmedOk,
 		MetaPropAlignVolumeWaived, MetaPropAlignEditionWaived, MetaPropAlignCountryWaived,
 		MetaPropUrlCheckDate, MetaPropUrlCheckStatus,
-		MetaPropWaivedDoublePdf,
+		MetaPropWaivedDoublePdf, MetaPropPdfContentOk, MetaPropPdfMetadataFingerprint,
+		MetaPropPdfFetchAttempts,
 	} {
 		if val := l.GetMetadata(source, prop); val != "" && l.GetMetadata(target, prop) == "" {
 			l.SetMetadata(target, prop, val)
 		}
 	}
+	// Replaced preferred aliases of both entries now resolve to target.
+	if val := l.GetMetadata(source, MetaPropReplacedAliases); val != "" {
+		l.SetMetadata(target, MetaPropReplacedAliases, strings.TrimSpace(l.GetMetadata(target, MetaPropReplacedAliases)+" "+val))
+	}
 	// Wipe all remaining metadata for source (any untransferred props).
 	if props, ok := l.Metadata[source]; ok {
 		for prop := range props {
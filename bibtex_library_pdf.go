@@ -105,16 +105,26 @@ func pdfHasContent(path string) (hasContent, determined bool) {
 }
 
 // LoadPDFFiles scans FilesRoot+FilesFolder and populates PDFFiles with the key stem of
-// every <key>.pdf file found. Called once at library open time; must be refreshed whenever
-// files are added or removed during a run (e.g. after ScanOrphanPDFs moves a file).
+// every <key>.pdf file found, and Attachments with the other attachments of entries
+// (see bibtex_library_attachments.go). Called once at library open time; must be
+// refreshed whenever files are added or removed during a run (e.g. after
+// ScanOrphanPDFs moves a file).
 func (l *TBibTeXLibrary) LoadPDFFiles() {
 	l.PDFFiles = map[string]bool{}
+	l.Attachments = map[string][]*TAttachment{}
 	entries, err := os.ReadDir(l.FilesRoot + l.FilesFolder)
 	if err != nil {
 		return
 	}
 	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".pdf") {
+		if e.IsDir() {
+			continue
+		}
+		if a, isAttachment := parseAttachmentName(e.Name()); isAttachment {
+			l.addLoadedAttachment(a)
+			continue
+		}
+		if !strings.HasSuffix(strings.ToLower(e.Name()), ".pdf") {
 			continue
 		}
 		l.PDFFiles[strings.TrimSuffix(e.Name(), ".pdf")] = true
@@ -183,24 +193,30 @@ func openBothPDFs(path1, path2 string) {
 //   - Global newer than local (interactive) → show ages+sizes, ask user.
 //   - Local newer than global → always ask; offer to open both in viewer first.
 //   - Local PDF no longer in output pairs → move to trashDir.
+//
+// Attachments are synchronised alike, named after the local key
+// (<localKey>.<role>[-n].<ext>).
 func (l *TBibTeXLibrary) syncLocalPDFs(localFilesDir, trashDir string, pairs []TBibGetPair, trusted bool) {
 	if err := os.MkdirAll(localFilesDir, 0755); err != nil {
 		l.Warning("Cannot create local files dir %s: %s", localFilesDir, err)
 		return
 	}
 
-	// Build expected set of local PDF names from pairs that have a global PDF.
+	// Build expected set of local file names from pairs that have a global PDF or attachments.
 	expected := map[string]bool{}
 	for _, p := range pairs {
-		if l.PDFFiles[p.canonicalKey] {
-			expected[p.localKey+".pdf"] = true
+		for _, a := range l.EntryAttachments(p.canonicalKey) {
+			expected[attachmentFileName(p.localKey, a.Role, a.Index, a.Type)] = true
 		}
 	}
 
-	// Move orphaned local PDFs (present locally, no longer in output) to trash.
+	// Move orphaned local files (present locally, no longer in output) to trash.
 	if entries, err := os.ReadDir(localFilesDir); err == nil {
 		for _, entry := range entries {
-			if entry.IsDir() || filepath.Ext(entry.Name()) != ".pdf" {
+			if entry.IsDir() {
+				continue
+			}
+			if _, isAttachment := parseAttachmentName(entry.Name()); !isAttachment && filepath.Ext(entry.Name()) != ".pdf" {
 				continue
 			}
 			if !expected[entry.Name()] {
@@ -217,69 +233,77 @@ func (l *TBibTeXLibrary) syncLocalPDFs(localFilesDir, trashDir string, pairs []T
 	options.Add("l", "g", "o", "s")
 
 	for _, p := range pairs {
-		if !l.PDFFiles[p.canonicalKey] {
-			continue
-		}
-		globalPath := l.FilesRoot + l.FilesFolder + p.canonicalKey + ".pdf"
-		localPath := localFilesDir + p.localKey + ".pdf"
-
-		if !FileExists(localPath) {
-			if err := copyFile(globalPath, localPath); err != nil {
-				l.Warning("Cannot copy PDF %s → %s: %s", p.canonicalKey+".pdf", p.localKey+".pdf", err)
+		for _, a := range l.EntryAttachments(p.canonicalKey) {
+			label := p.canonicalKey
+			if a.Role != AttachmentRolePaper {
+				label += " (" + a.Name + ")"
 			}
-			continue
-		}
-		if MD5ForFile(globalPath) == MD5ForFile(localPath) {
-			continue
+			l.syncLocalFile(label, l.AttachmentPath(a), localFilesDir+attachmentFileName(p.localKey, a.Role, a.Index, a.Type), trusted, options)
 		}
+	}
+}
 
-		globalInfo, globalErr := os.Stat(globalPath)
-		localInfo, localErr := os.Stat(localPath)
-		if globalErr != nil || localErr != nil {
-			continue
+// syncLocalFile synchronises one global file with its local copy (see syncLocalPDFs);
+// label names the file in messages.
+func (l *TBibTeXLibrary) syncLocalFile(label, globalPath, localPath string, trusted bool, options TStringSet) {
+	if !FileExists(localPath) {
+		if err := copyFile(globalPath, localPath); err != nil {
+			l.Warning("Cannot copy PDF %s → %s: %s", filepath.Base(globalPath), filepath.Base(localPath), err)
 		}
-		globalNewer := globalInfo.ModTime().After(localInfo.ModTime())
+		return
+	}
+	// Compare the content before any embedded metadata, so that embedding into
+	// the global copy does not make every local copy look changed.
+	if pdfContentMD5(globalPath) == pdfContentMD5(localPath) {
+		return
+	}
 
-		if globalNewer {
-			if trusted {
-				if err := copyFile(globalPath, localPath); err != nil {
-					l.Warning("Cannot overwrite local PDF %s: %s", p.localKey+".pdf", err)
-				}
+	globalInfo, globalErr := os.Stat(globalPath)
+	localInfo, localErr := os.Stat(localPath)
+	if globalErr != nil || localErr != nil {
+		return
+	}
+	globalNewer := globalInfo.ModTime().After(localInfo.ModTime())
+
+	if globalNewer {
+		if trusted {
+			if err := copyFile(globalPath, localPath); err != nil {
+				l.Warning("Cannot overwrite local PDF %s: %s", filepath.Base(localPath), err)
+			}
+			return
+		}
+		// Non-trusted, global newer: show info and ask.
+		warning := "PDF differs for %s:\n  global: %s\n  local : %s"
+		for {
+			answer := l.WarningQuestion(QuestionLocalPDFConflict, options, warning,
+				label, pdfFileInfo(globalPath), pdfFileInfo(localPath))
+			if answer == "o" {
+				openBothPDFs(globalPath, localPath)
 				continue
 			}
-			// Non-trusted, global newer: show info and ask.
-			warning := "PDF differs for %s:\n  global: %s\n  local : %s"
-			for {
-				answer := l.WarningQuestion(QuestionLocalPDFConflict, options, warning,
-					p.canonicalKey, pdfFileInfo(globalPath), pdfFileInfo(localPath))
-				if answer == "o" {
-					openBothPDFs(globalPath, localPath)
-					continue
-				}
-				if answer == "g" {
-					copyFile(globalPath, localPath) //nolint:errcheck
-				} else if answer == "l" {
-					copyFile(localPath, globalPath) //nolint:errcheck
-				}
-				break
+			if answer == "g" {
+				copyFile(globalPath, localPath) //nolint:errcheck
+			} else if answer == "l" {
+				copyFile(localPath, globalPath) //nolint:errcheck
 			}
-		} else {
-			// Local newer: always ask with open-both offer.
-			warning := "Local PDF is newer than global for %s:\n  global: %s\n  local : %s"
-			for {
-				answer := l.WarningQuestion(QuestionLocalPDFConflict, options, warning,
-					p.canonicalKey, pdfFileInfo(globalPath), pdfFileInfo(localPath))
-				if answer == "o" {
-					openBothPDFs(globalPath, localPath)
-					continue
-				}
-				if answer == "l" {
-					copyFile(localPath, globalPath) //nolint:errcheck
-				} else if answer == "g" {
-					copyFile(globalPath, localPath) //nolint:errcheck
-				}
-				break
+			break
+		}
+	} else {
+		// Local newer: always ask with open-both offer.
+		warning := "Local PDF is newer than global for %s:\n  global: %s\n  local : %s"
+		for {
+			answer := l.WarningQuestion(QuestionLocalPDFConflict, options, warning,
+				label, pdfFileInfo(globalPath), pdfFileInfo(localPath))
+			if answer == "o" {
+				openBothPDFs(globalPath, localPath)
+				continue
+			}
+			if answer == "l" {
+				copyFile(localPath, globalPath) //nolint:errcheck
+			} else if answer == "g" {
+				copyFile(globalPath, localPath) //nolint:errcheck
 			}
+			break
 		}
 	}
 }
@@ -344,6 +368,9 @@ func (l *TBibTeXLibrary) mergePDFFile(sourceKey, targetKey string) {
 //   - If key is an alias for a canonical entry → rename to <canonical>.pdf.
 //     If <canonical>.pdf already exists, the old file is moved to trash instead.
 //   - Otherwise → no library entry at all → moved to library trash.
+// Attachments (<key>.<role>[-n].<ext>) are handled alike, except that an alias's
+// attachment is renumbered rather than trashed when the canonical entry already has
+// one with the same role; only identical content is trashed (see scanOrphanAttachment).
 // After the scan, PDFFiles is refreshed from disk.
 // Intended for the normal check run and sync writes (lightweight — no PDF parsing).
 func (l *TBibTeXLibrary) ScanOrphanPDFs() {
@@ -366,8 +393,26 @@ func (l *TBibTeXLibrary) ScanOrphanPDFs() {
 	trashName := l.BaseName + ".trash"
 	moved := 0
 	renamed := 0
+	logOrphan := func(name string) {
+		if orphanLog != nil {
+			fmt.Fprintf(orphanLog, "Orphaned file (no library entry): %s — moving to %s\n", name, trashName)
+		}
+	}
 	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(strings.ToLower(e.Name()), ".pdf") {
+		if e.IsDir() {
+			continue
+		}
+		if a, isAttachment := parseAttachmentName(e.Name()); isAttachment {
+			wasRenamed, wasMoved := l.scanOrphanAttachment(a, logOrphan)
+			if wasRenamed {
+				renamed++
+			}
+			if wasMoved {
+				moved++
+			}
+			continue
+		}
+		if !strings.HasSuffix(strings.ToLower(e.Name()), ".pdf") {
 			continue
 		}
 		key := strings.TrimSuffix(e.Name(), ".pdf")
@@ -400,9 +445,7 @@ func (l *TBibTeXLibrary) ScanOrphanPDFs() {
 		}
 
 		// No entry, no alias — genuine orphan. Log to file rather than interrupting output.
-		if orphanLog != nil {
-			fmt.Fprintf(orphanLog, "Orphaned PDF (no library entry): %s — moving to %s\n", e.Name(), trashName)
-		}
+		logOrphan(e.Name())
 		if !l.moveToLibraryTrash(fullPath) {
 			l.Warning("Could not move %s to %s", e.Name(), trashName)
 		} else {
@@ -410,7 +453,7 @@ func (l *TBibTeXLibrary) ScanOrphanPDFs() {
 		}
 	}
 	if moved > 0 {
-		l.Progress("  Orphaned PDFs moved to trash: %d (see orphaned_pdfs.log)", moved)
+		l.Progress("  Orphaned PDFs and attachments moved to trash: %d (see orphaned_pdfs.log)", moved)
 	}
 	if renamed > 0 || moved > 0 {
 		l.LoadPDFFiles() // refresh after renames/moves
@@ -524,7 +567,7 @@ func (l *TBibTeXLibrary) checkParentChildSharedPDF(childKey, parentKey string) {
 	filesDir := l.FilesRoot + l.FilesFolder
 	childPath := filesDir + childKey + ".pdf"
 	parentPath := filesDir + parentKey + ".pdf"
-	parentMD5 := MD5ForFile(parentPath)
+	parentMD5 := pdfContentMD5(parentPath)
 
 	if !trashPDF(childPath) {
 		l.Warning("Could not trash shared child PDF for %s.", childKey)
@@ -578,6 +621,9 @@ func (l *TBibTeXLibrary) checkParentChildSharedPDF(childKey, parentKey string) {
 //   - Valid PDF with no content (pdftotext + OCR both empty) → interactive prompt.
 //   - File passes check → any stale confirmed-OK entry is removed.
 //   - File fails check but is already confirmed-OK → silently skipped.
+//   - Healthy file whose text does not match the entry (DOI, title words, first
+//     author) → interactive prompt, offering to move or swap it when it turns out
+//     to be the PDF of another entry (see VerifyPDFContents).
 func (l *TBibTeXLibrary) CheckPDFHealth() {
 	filesDir := l.FilesRoot + l.FilesFolder
 	l.Progress(ProgressCheckingPDFHealth, filesDir)
@@ -589,6 +635,9 @@ func (l *TBibTeXLibrary) CheckPDFHealth() {
 
 	var pdfFiles []string
 	for _, e := range files {
+		if _, isAttachment := parseAttachmentName(e.Name()); isAttachment {
+			continue
+		}
 		if !e.IsDir() && strings.HasSuffix(strings.ToLower(e.Name()), ".pdf") {
 			pdfFiles = append(pdfFiles, e.Name())
 		}
@@ -596,6 +645,7 @@ func (l *TBibTeXLibrary) CheckPDFHealth() {
 
 	total := len(pdfFiles)
 	md5Index := TStringSetMap{}
+	var healthyKeys []string
 	ticker := l.NewProgressTicker(fmt.Sprintf(ProgressCheckingPDFHealth, filesDir), total)
 
 	for _, fileName := range pdfFiles {
@@ -610,13 +660,16 @@ func (l *TBibTeXLibrary) CheckPDFHealth() {
 			continue
 		}
 
-		md5Index.AddValueToStringSetMap(MD5ForFile(fullPath), key)
+		md5Index.AddValueToStringSetMap(pdfContentMD5(fullPath), key)
 
 		reason := l.checkOnePDF(key, fullPath)
 		if reason == "" {
 			if l.HasMetadata(key, MetaPropPdfConfirmedOk) {
 				l.DeleteMetadata(key, MetaPropPdfConfirmedOk)
 			}
+			if FileExists(fullPath) {
+				healthyKeys = append(healthyKeys, key)
+			}
 			continue
 		}
 		if l.HasMetadata(key, MetaPropPdfConfirmedOk) {
@@ -629,6 +682,11 @@ func (l *TBibTeXLibrary) CheckPDFHealth() {
 	}
 	ticker.Done()
 
+	// Healthy PDFs may still be the wrong paper.
+	if l.VerifyPDFContents(healthyKeys, false) {
+		return
+	}
+
 	validAnswers := TStringSetNew()
 	validAnswers.Add("w", "m", "s")
 
@@ -673,7 +731,7 @@ func (l *TBibTeXLibrary) CheckPDFHealth() {
 		}
 
 		l.Warning(WarningDuplicateFileContent, remaining.String())
-		switch l.WarningQuestion(QuestionDoublePdfWaive, validAnswers, "") {
+		switch l.WarningQuestion(QuestionDoublePdfWaive, validAnswers, "", remaining.String()) {
 		case "w":
 			for key := range remaining.Elements() {
 				l.SetMetadata(key, MetaPropWaivedDoublePdf, md5hash)
@@ -0,0 +1,368 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_pdf_providers
+ *
+ * Open-access PDF source providers for -get_pdfs.
+ *
+ * Providers are configured in the .settings file as a chain of URL templates:
+ *
+ *	"pdf_providers": [
+ *	  {"name": "arxiv", "url": "https://arxiv.org/pdf/{arxiv}"},
+ *	  {"name": "acl", "url": "https://aclanthology.org/{acl}.pdf"},
+ *	  {"name": "ceur", "url": "https://ceur-ws.org/Vol-{ceur}/{ceur_file}"},
+ *	  {"name": "acm", "url": "https://dl.acm.org/doi/pdf/{doi}", "doi_prefix": "10.1145/"},
+ *	  {"name": "mirror", "dir": "~/Mirror/papers", "url": "{doi_file}.pdf"}
+ *	]
+ *
+ * A provider applies to an entry when every placeholder in its template has a
+ * value for the entry (and its DOI starts with doi_prefix, when given). With dir
+ * set, the template names a file in a local (e.g. institutional) mirror folder;
+ * mirrors are also used offline. In URL templates, placeholder values are path
+ * escaped (per "/"-separated segment, so a DOI keeps its slash). The entry's own
+ * url, when it ends in ".pdf", always comes first.
+ *
+ * Placeholders: {key}, {doi}, {doi_file} (the DOI with "/" replaced by "_"),
+ * {arxiv}, {acl}, {ceur} (volume number) and {ceur_file} (file name within the
+ * volume).
+ *
+ * Downloads are recorded in the entry's metadata (pdf_fetch_attempts); a
+ * download that failed is not tried again for pdfFetchRetryDays. Mirror misses
+ * and failures are not recorded: a mirror is checked on every run.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// pdfFetchRetryDays is the number of days before a failed candidate is tried again.
+	pdfFetchRetryDays = 30
+	// pdfFetchAttemptsKept is the number of attempts kept per entry.
+	pdfFetchAttemptsKept = 20
+
+	// pdfProviderEntryURL names the entry's own url as a provider.
+	pdfProviderEntryURL = "url"
+)
+
+// TPDFProvider is one provider of the pdf_providers chain in the .settings file.
+type TPDFProvider struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`                  // URL template; a file name template when Dir is set
+	DOIPrefix string `json:"doi_prefix,omitempty"` // only for entries whose DOI starts with this prefix
+	Dir       string `json:"dir,omitempty"`        // local mirror folder
+}
+
+// TPDFCandidate is a location from which a PDF for an entry may be fetched.
+type TPDFCandidate struct {
+	Provider string
+	URL      string // http(s) URL, or file:// URL for mirror providers
+}
+
+// TPDFFetchAttempt records one attempt to fetch a PDF for an entry.
+type TPDFFetchAttempt struct {
+	Date     string `json:"date"`
+	Provider string `json:"provider"`
+	URL      string `json:"url"`
+	Outcome  string `json:"outcome"` // "ok", or the reason of the failure
+}
+
+// pdfProviders is the provider chain from the .settings file (see loadBibTeXFolders).
+var pdfProviders []TPDFProvider
+
+var (
+	pdfProviderPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+	arxivIDPattern         = regexp.MustCompile(`^(?:\d{4}\.\d{4,5}|[a-z\-]+(?:\.[A-Z]{2})?/\d{7})(?:v\d+)?$`)
+	aclURLPattern          = regexp.MustCompile(`(?i)(?:aclanthology\.org|aclweb\.org/anthology)/([A-Z]\d{2}-\d{4}|\d{4}\.[a-z0-9\-]+\.\d+)`)
+	ceurURLPattern         = regexp.MustCompile(`(?i)ceur-ws\.org/Vol-(\d+)(?:/([^/?#]+\.pdf))?`)
+)
+
+// aclDOIPrefix is the DOI prefix of the ACL Anthology.
+const aclDOIPrefix = "10.18653/v1/"
+
+// entryArxivID returns the arXiv identifier of an entry: from its eprint field,
+// its arXiv DOI (10.48550/arXiv.<id>) or its url.
+func (l *TBibTeXLibrary) entryArxivID(key string) string {
+	eprint := strings.TrimSpace(l.EntryFieldValueity(key, "eprint"))
+	eprint = strings.TrimPrefix(strings.TrimPrefix(eprint, "arXiv:"), "arxiv:")
+	if arxivIDPattern.MatchString(eprint) {
+		return eprint
+	}
+	if doi := strings.ToLower(l.EntryFieldValueity(key, "doi")); strings.HasPrefix(doi, "10.48550/arxiv.") {
+		return l.EntryFieldValueity(key, "doi")[len("10.48550/arxiv."):]
+	}
+	if match := pdfArxivPattern.FindStringSubmatch(l.EntryFieldValueity(key, "url")); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// entryACLID returns the ACL Anthology identifier of an entry, from its DOI or url.
+func (l *TBibTeXLibrary) entryACLID(key string) string {
+	if doi := l.EntryFieldValueity(key, "doi"); strings.HasPrefix(strings.ToLower(doi), aclDOIPrefix) {
+		return doi[len(aclDOIPrefix):]
+	}
+	if match := aclURLPattern.FindStringSubmatch(l.EntryFieldValueity(key, "url")); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// entryCEURVolume returns the CEUR-WS volume number of an entry and, when known,
+// the file name of the paper within the volume. The volume is taken from the
+// url, or from the volume field of entries in (or crossref'ing) the CEUR
+// Workshop Proceedings series.
+func (l *TBibTeXLibrary) entryCEURVolume(key string) (volume, file string) {
+	if match := ceurURLPattern.FindStringSubmatch(l.EntryFieldValueity(key, "url")); match != nil {
+		return match[1], match[2]
+	}
+	source := key
+	if l.EntryFieldValueity(key, "volume") == "" {
+		if crossref := l.EntryFieldValueity(key, "crossref"); crossref != "" {
+			source = l.MapEntryKey(crossref)
+		}
+	}
+	series := strings.ToLower(l.EntryFieldValueity(source, "series") + " " + l.EntryFieldValueity(source, "booktitle"))
+	if strings.Contains(series, "ceur") {
+		if volume := strings.TrimSpace(l.EntryFieldValueity(source, "volume")); volume != "" && strings.Trim(volume, "0123456789") == "" {
+			return volume, ""
+		}
+	}
+	return "", ""
+}
+
+// pdfProviderValues returns the placeholder values of an entry; placeholders
+// without a value are absent.
+func (l *TBibTeXLibrary) pdfProviderValues(key string) map[string]string {
+	values := map[string]string{"key": key}
+	if doi := l.EntryFieldValueity(key, "doi"); doi != "" {
+		values["doi"] = doi
+		values["doi_file"] = strings.ReplaceAll(doi, "/", "_")
+	}
+	if id := l.entryArxivID(key); id != "" {
+		values["arxiv"] = id
+	}
+	if id := l.entryACLID(key); id != "" {
+		values["acl"] = id
+	}
+	if volume, file := l.entryCEURVolume(key); volume != "" {
+		values["ceur"] = volume
+		if file != "" {
+			values["ceur_file"] = file
+		}
+	}
+	return values
+}
+
+// expandPDFProviderTemplate fills in the placeholders of template; ok is false
+// when one of them has no value.
+func expandPDFProviderTemplate(template string, values map[string]string) (result string, ok bool) {
+	ok = true
+	result = pdfProviderPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, known := values[placeholder[1:len(placeholder)-1]]
+		if !known {
+			ok = false
+		}
+		return value
+	})
+	return result, ok
+}
+
+// pdfCandidates returns the candidate locations of a PDF for key, in the order
+// in which they are to be tried: the entry's own url when it ends in ".pdf"
+// (and is not ignored), followed by those of the applicable providers.
+func (l *TBibTeXLibrary) pdfCandidates(key string) []TPDFCandidate {
+	var candidates []TPDFCandidate
+	seen := TStringSetNew()
+	add := func(provider, location string) {
+		if !seen.Contains(location) && !l.URLsIgnore.Contains(location) {
+			seen.Add(location)
+			candidates = append(candidates, TPDFCandidate{provider, location})
+		}
+	}
+
+	if entryURL := l.EntryFieldValueity(key, "url"); strings.HasSuffix(strings.ToLower(entryURL), ".pdf") {
+		add(pdfProviderEntryURL, entryURL)
+	}
+
+	values := l.pdfProviderValues(key)
+	for _, provider := range pdfProviders {
+		if location, ok := pdfProviderLocation(provider, values); ok {
+			add(provider.Name, location)
+		}
+	}
+	return candidates
+}
+
+// escapeURLPath path-escapes each "/"-separated segment of s.
+func escapeURLPath(s string) string {
+	segments := strings.Split(s, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// pdfProviderLocation returns the location provider yields for an entry with the
+// given placeholder values: a file:// URL for mirror providers, and otherwise the
+// URL template with path-escaped values. ok is false when the provider does not
+// apply to the entry.
+func pdfProviderLocation(provider TPDFProvider, values map[string]string) (location string, ok bool) {
+	if provider.DOIPrefix != "" && !strings.HasPrefix(strings.ToLower(values["doi"]), strings.ToLower(provider.DOIPrefix)) {
+		return "", false
+	}
+	if provider.Dir != "" {
+		location, ok = expandPDFProviderTemplate(provider.URL, values)
+		if !ok || location == "" {
+			return "", false
+		}
+		return (&url.URL{Scheme: "file", Path: filepath.Join(expandHome(provider.Dir), location)}).String(), true
+	}
+	escaped := make(map[string]string, len(values))
+	for name, value := range values {
+		escaped[name] = escapeURLPath(value)
+	}
+	location, ok = expandPDFProviderTemplate(provider.URL, escaped)
+	return location, ok && location != ""
+}
+
+// pdfMirrorConfigured reports whether a provider is a local mirror, which can be
+// used offline.
+func pdfMirrorConfigured() bool {
+	for _, provider := range pdfProviders {
+		if provider.Dir != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// pdfMirrorFile returns the local path of a mirror location (a file:// URL), and
+// whether the mirror holds that file.
+func pdfMirrorFile(location string) (string, bool) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "", false
+	}
+	return parsed.Path, FileExists(parsed.Path)
+}
+
+// fetchPDFCandidate fetches the PDF at location (an http(s) or file:// URL) to
+// destPath. The result must be a valid PDF with content (see pdfHasContent).
+func fetchPDFCandidate(location, destPath string) error {
+	tmp := destPath + ".fetch.tmp"
+	defer os.Remove(tmp)
+
+	if strings.HasPrefix(location, "file://") {
+		path, found := pdfMirrorFile(location)
+		if !found {
+			return fmt.Errorf("not in mirror")
+		}
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return err
+		}
+		if err := copyFile(path, tmp); err != nil {
+			return err
+		}
+		if !isValidPDF(tmp) {
+			return fmt.Errorf("not a PDF file")
+		}
+	} else if err := downloadPDF(location, tmp); err != nil {
+		return err
+	}
+
+	if hasContent, determined := pdfHasContent(tmp); determined && !hasContent {
+		return fmt.Errorf("PDF without content")
+	}
+	return os.Rename(tmp, destPath)
+}
+
+// PDFFetchAttempts returns the recorded attempts to fetch a PDF for key, oldest first.
+func (l *TBibTeXLibrary) PDFFetchAttempts(key string) []TPDFFetchAttempt {
+	var attempts []TPDFFetchAttempt
+	if raw := l.GetMetadata(key, MetaPropPdfFetchAttempts); raw != "" {
+		json.Unmarshal([]byte(raw), &attempts) //nolint:errcheck
+	}
+	return attempts
+}
+
+// recordPDFFetchAttempt adds an attempt to the record of key, keeping the last
+// pdfFetchAttemptsKept attempts.
+func (l *TBibTeXLibrary) recordPDFFetchAttempt(key string, attempt TPDFFetchAttempt) {
+	attempts := append(l.PDFFetchAttempts(key), attempt)
+	if len(attempts) > pdfFetchAttemptsKept {
+		attempts = attempts[len(attempts)-pdfFetchAttemptsKept:]
+	}
+	if data, err := json.Marshal(attempts); err == nil {
+		l.SetMetadata(key, MetaPropPdfFetchAttempts, string(data))
+	}
+}
+
+// pdfFetchRecentlyFailed reports whether fetching location for key failed less
+// than pdfFetchRetryDays ago.
+func (l *TBibTeXLibrary) pdfFetchRecentlyFailed(key, location string) bool {
+	cutoff := time.Now().AddDate(0, 0, -pdfFetchRetryDays).Format("2006-01-02")
+	for _, attempt := range l.PDFFetchAttempts(key) {
+		if attempt.URL == location && attempt.Outcome != "ok" && attempt.Date > cutoff {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchPDF tries the candidates of key in order until one yields a PDF, which is
+// stored at destPath. Offline, only mirror candidates are tried. It returns the
+// candidate that did, and whether the entry's own url was tried and failed.
+// Mirror candidates are local and cheap to check: a file missing from the mirror
+// is skipped, and failed mirror attempts are neither recorded nor held back, so
+// a paper added to the mirror later is picked up by the next run.
+func (l *TBibTeXLibrary) fetchPDF(key, destPath string) (fetched *TPDFCandidate, entryURLFailed bool) {
+	for _, candidate := range l.pdfCandidates(key) {
+		mirror := strings.HasPrefix(candidate.URL, "file://")
+		if mirror {
+			if _, found := pdfMirrorFile(candidate.URL); !found {
+				continue
+			}
+		} else if !Online || l.pdfFetchRecentlyFailed(key, candidate.URL) {
+			continue
+		}
+		l.Progress(ProgressDownloadingPDFFrom, key, candidate.Provider, candidate.URL)
+		attempt := TPDFFetchAttempt{
+			Date:     time.Now().Format("2006-01-02"),
+			Provider: candidate.Provider,
+			URL:      candidate.URL,
+			Outcome:  "ok",
+		}
+		err := fetchPDFCandidate(candidate.URL, destPath)
+		if err != nil {
+			attempt.Outcome = err.Error()
+		}
+		if err == nil || !mirror {
+			l.recordPDFFetchAttempt(key, attempt)
+		}
+		if err == nil {
+			return &candidate, entryURLFailed
+		}
+		l.Warning(WarningPDFDownloadFailed, key, candidate.URL, err)
+		if candidate.Provider == pdfProviderEntryURL {
+			entryURLFailed = true
+		}
+	}
+	return nil, entryURLFailed
+}
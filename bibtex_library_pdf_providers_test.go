@@ -0,0 +1,108 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_pdf_providers_test
+ *
+ * Checks of the PDF provider chain against a local HTTP stand-in and a mirror
+ * folder, so that no network access is needed.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPDF returns a minimal one-page PDF with the given text, so that
+// pdfHasContent accepts it where pdftotext is installed.
+func testPDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// TestPDFProvidersAgainstStandIn fetches PDFs from a local HTTP stand-in for an
+// open-access provider, and from a local mirror folder.
+func TestPDFProvidersAgainstStandIn(t *testing.T) {
+	pdf := testPDF("Open access paper")
+	const doi = "10.1000/a b#c"
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pdf/"+doi {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdf) //nolint:errcheck
+	}))
+	defer standIn.Close()
+
+	values := map[string]string{"key": "EP-2020-1", "doi": doi, "doi_file": "10.1000_a b#c"}
+	dir := t.TempDir()
+
+	provider := TPDFProvider{Name: "stand-in", URL: standIn.URL + "/pdf/{doi}", DOIPrefix: "10.1000/"}
+	location, ok := pdfProviderLocation(provider, values)
+	if want := standIn.URL + "/pdf/10.1000/a%20b%23c"; !ok || location != want {
+		t.Fatalf("location = %q, %v; want %q", location, ok, want)
+	}
+	dest := filepath.Join(dir, "fetched.pdf")
+	if err := fetchPDFCandidate(location, dest); err != nil {
+		t.Fatalf("fetch from stand-in: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, pdf) {
+		t.Errorf("fetched PDF differs from the served one")
+	}
+	if err := fetchPDFCandidate(standIn.URL+"/pdf/10.1000/missing", filepath.Join(dir, "missing.pdf")); err == nil {
+		t.Errorf("fetching a missing PDF succeeded")
+	}
+
+	if _, ok := pdfProviderLocation(TPDFProvider{Name: "acm", URL: standIn.URL + "/{doi}", DOIPrefix: "10.1145/"}, values); ok {
+		t.Errorf("provider applied to an entry outside its doi_prefix")
+	}
+	if _, ok := pdfProviderLocation(TPDFProvider{Name: "arxiv", URL: standIn.URL + "/{arxiv}"}, values); ok {
+		t.Errorf("provider applied to an entry without a value for its placeholder")
+	}
+
+	mirror := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mirror, "10.1000_a b#c.pdf"), pdf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	location, ok = pdfProviderLocation(TPDFProvider{Name: "mirror", Dir: mirror, URL: "{doi_file}.pdf"}, values)
+	if !ok {
+		t.Fatalf("mirror provider did not apply")
+	}
+	if err := fetchPDFCandidate(location, filepath.Join(dir, "mirrored.pdf")); err != nil {
+		t.Fatalf("fetch from mirror: %v", err)
+	}
+}
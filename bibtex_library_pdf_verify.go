@@ -0,0 +1,461 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_pdf_verify
+ *
+ * Verifies that the PDF filed under an entry key is actually the paper of that
+ * entry, by looking for the entry's DOI, title words and first-author surname in
+ * the text of the PDF's first pages (see pdf_text.go).
+ *
+ * A DOI found on the pages settles the match. Otherwise the score is a weighted
+ * combination of the fraction of significant title words found and whether the
+ * first author's surname occurs. A low score on a PDF with enough readable text
+ * marks it as a likely mismatch; the library is then searched (by the DOIs in
+ * the text, and by title) for the entry the PDF does belong to, so that it can
+ * be moved there, or swapped with that entry's PDF.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	pdfVerifyPages       = 3    // pages of text extracted per PDF
+	pdfVerifyMinText     = 300  // characters of text needed for a verdict
+	pdfMismatchScore     = 0.4  // below this a PDF is a likely mismatch
+	pdfOwnerTitleRatio   = 0.85 // fraction of title words needed to claim another entry's PDF
+	pdfOwnerMinWords     = 3    // titles with fewer significant words are too generic to claim a PDF
+	pdfTitleWeight       = 0.7
+	pdfAuthorWeight      = 0.3
+	pdfSignificantLength = 4 // title words of fewer letters are ignored
+)
+
+var (
+	pdfDOIPattern = regexp.MustCompile(`10\.\d{4,9}/[^\s"<>{}]+`)
+
+	pdfTextLigatures = strings.NewReplacer(
+		"ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st",
+		"’", "'", "‘", "'", "–", "-", "—", "-", "­", "")
+)
+
+type (
+	// TPDFText is the text of the first pages of a PDF, in the forms used for matching.
+	TPDFText struct {
+		words   TStringSet // lower-case words
+		compact string     // lower-case, without any white space (for DOIs)
+		dois    []string   // DOIs occurring in the text
+		length  int
+	}
+
+	// TPDFMatch scores how well a PDF's text matches an entry.
+	TPDFMatch struct {
+		Score       float64
+		TitleRatio  float64 // fraction of significant title words found; -1 without a title
+		HasAuthor   bool
+		AuthorFound bool
+		HasDOI      bool
+		DOIFound    bool
+	}
+)
+
+// pdfWords splits text into lower-case words of letters and digits.
+func pdfWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// newPDFText prepares extracted text for matching; words hyphenated across a
+// line break are joined again.
+func newPDFText(raw string) TPDFText {
+	text := pdfTextLigatures.Replace(raw)
+	text = strings.NewReplacer("-\n", "", "-\r\n", "").Replace(text)
+	result := TPDFText{words: TStringSetNew(), length: len(strings.TrimSpace(text))}
+	for _, word := range pdfWords(text) {
+		result.words.Add(word)
+	}
+	result.compact = strings.Join(strings.Fields(strings.ToLower(text)), "")
+	seen := TStringSetNew()
+	for _, doi := range pdfDOIPattern.FindAllString(strings.ToLower(text), -1) {
+		doi = strings.TrimRight(doi, ".,;:)]'")
+		if !seen.Contains(doi) {
+			seen.Add(doi)
+			result.dois = append(result.dois, doi)
+		}
+	}
+	return result
+}
+
+// readPDFText extracts the text of the first pages of the PDF at path.
+func readPDFText(path string) (TPDFText, error) {
+	raw, err := pdfFirstPagesText(path, pdfVerifyPages)
+	if err != nil {
+		return TPDFText{}, err
+	}
+	return newPDFText(raw), nil
+}
+
+// significantTitleWords returns the distinct words of title that carry meaning.
+func significantTitleWords(title string) []string {
+	var words []string
+	seen := TStringSetNew()
+	for _, word := range pdfWords(texToText(title)) {
+		if len([]rune(word)) >= pdfSignificantLength && !seen.Contains(word) {
+			seen.Add(word)
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// titleWordRatio returns the fraction of the significant words of title found in
+// text, or -1 when the title has no significant words.
+func titleWordRatio(title string, text TPDFText) float64 {
+	words := significantTitleWords(title)
+	if len(words) == 0 {
+		return -1
+	}
+	found := 0
+	for _, word := range words {
+		if text.words.Contains(word) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
+
+// firstAuthorSurname returns the most distinctive word of the surname of the first
+// name in an " and "-separated author (or editor) list.
+func firstAuthorSurname(names string) string {
+	first := strings.TrimSpace(strings.Split(names, " and ")[0])
+	if first == "" || strings.EqualFold(first, "others") {
+		return ""
+	}
+	surname := first
+	if i := strings.Index(first, ","); i >= 0 {
+		surname = first[:i]
+	} else if i := strings.LastIndex(first, " "); i >= 0 && !strings.HasSuffix(first, "}") {
+		surname = first[i+1:]
+	}
+	longest := ""
+	for _, word := range pdfWords(texToText(surname)) {
+		if len([]rune(word)) > len([]rune(longest)) {
+			longest = word
+		}
+	}
+	return longest
+}
+
+// pdfMatchScore scores the text of a PDF against the metadata of entry key.
+func (l *TBibTeXLibrary) pdfMatchScore(key string, text TPDFText) TPDFMatch {
+	entry := loadEntryFromDb(key)
+	match := TPDFMatch{TitleRatio: titleWordRatio(entry.FieldValue(TitleField), text)}
+
+	if doi := normalizeDOI(entry.FieldValue("doi")); doi != "" {
+		match.HasDOI = true
+		match.DOIFound = strings.Contains(text.compact, strings.Join(strings.Fields(doi), ""))
+	}
+
+	names := entry.FieldValue("author")
+	if names == "" {
+		names = entry.FieldValue("editor")
+	}
+	if surname := firstAuthorSurname(names); surname != "" {
+		match.HasAuthor = true
+		match.AuthorFound = text.words.Contains(surname)
+	}
+
+	switch {
+	case match.DOIFound:
+		match.Score = 1
+	case match.TitleRatio >= 0:
+		match.Score = match.TitleRatio
+		if match.HasAuthor {
+			match.Score = pdfTitleWeight*match.TitleRatio + pdfAuthorWeight*boolScore(match.AuthorFound)
+		}
+	case match.HasAuthor:
+		match.Score = boolScore(match.AuthorFound)
+	default:
+		match.Score = -1
+	}
+	return match
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// String summarises the components of a match.
+func (m TPDFMatch) String() string {
+	var parts []string
+	if m.HasDOI {
+		if m.DOIFound {
+			parts = append(parts, "DOI found")
+		} else {
+			parts = append(parts, "DOI not found")
+		}
+	}
+	if m.TitleRatio >= 0 {
+		parts = append(parts, fmt.Sprintf("%.0f%% of title words", 100*m.TitleRatio))
+	}
+	if m.HasAuthor {
+		if m.AuthorFound {
+			parts = append(parts, "first author found")
+		} else {
+			parts = append(parts, "first author not found")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Mismatch reports whether the match is poor enough to flag the PDF.
+func (m TPDFMatch) Mismatch() bool {
+	return m.Score >= 0 && m.Score < pdfMismatchScore
+}
+
+// TPDFOwnerIndex holds the titles and first-author surnames of all entries, for
+// finding the entry a misfiled PDF belongs to. It is loaded on first use.
+type TPDFOwnerIndex struct {
+	loaded   bool
+	titles   map[string]string
+	surnames map[string]string
+}
+
+func (x *TPDFOwnerIndex) load() {
+	if x.loaded {
+		return
+	}
+	x.loaded = true
+	x.titles = map[string]string{}
+	x.surnames = map[string]string{}
+	rows, err := db.Query(`SELECT entry_key, field, value FROM bib_entries WHERE field IN (?, 'author', 'editor')`, TitleField)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, field, value string
+		if rows.Scan(&key, &field, &value) != nil {
+			continue
+		}
+		switch field {
+		case TitleField:
+			x.titles[key] = value
+		case "author":
+			x.surnames[key] = firstAuthorSurname(value)
+		case "editor":
+			if _, hasAuthor := x.surnames[key]; !hasAuthor {
+				x.surnames[key] = firstAuthorSurname(value)
+			}
+		}
+	}
+}
+
+// pdfOwner returns the entry, other than key, that the PDF text belongs to: an
+// entry with one of the DOIs in the text, or else the entry whose (sufficiently
+// specific) title is most completely present in the text, with its first author.
+func (l *TBibTeXLibrary) pdfOwner(key string, text TPDFText, index *TPDFOwnerIndex) (string, string) {
+	for _, doi := range text.dois {
+		var owner string
+		if bibQueryRow(`SELECT entry_key FROM bib_entries WHERE field = 'doi' AND lower(value) = ? LIMIT 1`, doi).Scan(&owner) == nil {
+			if owner = l.MapEntryKey(owner); owner != key {
+				return owner, "DOI " + doi
+			}
+		}
+	}
+
+	index.load()
+	best, bestRatio, bestWords := "", 0.0, 0
+	for candidate, title := range index.titles {
+		if candidate == key {
+			continue
+		}
+		words := len(significantTitleWords(title))
+		if words < pdfOwnerMinWords {
+			continue
+		}
+		ratio := titleWordRatio(title, text)
+		if ratio < pdfOwnerTitleRatio {
+			continue
+		}
+		if surname := index.surnames[candidate]; surname != "" && !text.words.Contains(surname) {
+			continue
+		}
+		if ratio > bestRatio || (ratio == bestRatio && words > bestWords) {
+			best, bestRatio, bestWords = candidate, ratio, words
+		}
+	}
+	if best == "" {
+		return "", ""
+	}
+	return best, fmt.Sprintf("%.0f%% of title words", 100*bestRatio)
+}
+
+// verifyOnePDFContent checks the PDF of key against its metadata and, for a likely
+// mismatch, asks the user what to do. Returns true when the user asks to quit.
+// With report set, the outcome is also reported for PDFs that match.
+func (l *TBibTeXLibrary) verifyOnePDFContent(key, path string, index *TPDFOwnerIndex, report bool) bool {
+	md5hash := pdfContentMD5(path)
+	if !report && md5hash != "" && l.GetMetadata(key, MetaPropPdfContentOk) == md5hash {
+		return false
+	}
+
+	text, err := readPDFText(path)
+	if err != nil || text.length < pdfVerifyMinText {
+		if report {
+			reason := "too little readable text"
+			if err != nil {
+				reason = err.Error()
+			}
+			l.Progress(ProgressPDFContentUndetermined, key, reason)
+		}
+		return false
+	}
+
+	match := l.pdfMatchScore(key, text)
+	if !match.Mismatch() {
+		if report {
+			l.Progress(ProgressPDFContentMatch, key, match.Score, match)
+		}
+		return false
+	}
+
+	owner, evidence := l.pdfOwner(key, text, index)
+	return l.handlePDFMismatch(key, path, md5hash, match, owner, evidence)
+}
+
+// handlePDFMismatch offers the ways out for a PDF that does not match its entry.
+// When the PDF was found to belong to owner, it can be moved there, or, when owner
+// has a PDF that does not match owner either, the two can be swapped.
+func (l *TBibTeXLibrary) handlePDFMismatch(key, path, md5hash string, match TPDFMatch, owner, evidence string) bool {
+	filesDir := l.FilesRoot + l.FilesFolder
+	ownerPath := filesDir + owner + ".pdf"
+
+	options := TStringSetNew()
+	options.Add("o", "t", "k", "s", "q")
+	prompt := "What to do? (o=open, t=trash, k=keep-as-ok, s=skip, q=quit)"
+	warning := fmt.Sprintf(WarningPDFContentMismatch, key, match.Score, match,
+		texToText(l.EntryFieldValueity(key, TitleField)), path)
+
+	ownerHasPDF := owner != "" && FileExists(ownerPath)
+	canSwap := false
+	if owner != "" {
+		warning += fmt.Sprintf(WarningPDFBelongsToOther, owner, evidence, texToText(l.EntryFieldValueity(owner, TitleField)))
+		if !ownerHasPDF {
+			options.Add("m")
+			prompt = fmt.Sprintf("What to do? (m=move to %s, o=open, t=trash, k=keep-as-ok, s=skip, q=quit)", owner)
+		} else if ownerText, err := readPDFText(ownerPath); err == nil && ownerText.length >= pdfVerifyMinText &&
+			l.pdfMatchScore(owner, ownerText).Mismatch() {
+			canSwap = true
+			options.Add("w")
+			warning += fmt.Sprintf(WarningPDFOwnerMismatchToo, owner)
+			prompt = fmt.Sprintf("What to do? (w=swap with %s, o=open, t=trash, k=keep-as-ok, s=skip, q=quit)", owner)
+		} else {
+			warning += fmt.Sprintf(WarningPDFOwnerHasOwnPDF, owner)
+		}
+	}
+
+	for {
+		askingAbout(key)
+		switch l.WarningQuestion(prompt, options, "%s", warning) {
+		case "o":
+			if ownerHasPDF {
+				openBothPDFs(path, ownerPath)
+			} else {
+				exec.Command("open", path).Start() //nolint:errcheck
+			}
+			continue
+		case "m":
+			if err := os.Rename(path, ownerPath); err != nil {
+				l.Warning("Could not rename PDF %s.pdf → %s.pdf: %s", key, owner, err)
+			} else {
+				delete(l.PDFFiles, key)
+				l.PDFFiles[owner] = true
+				l.Progress(ProgressPDFMovedToOwner, key, owner)
+			}
+		case "w":
+			if canSwap {
+				l.swapPDFFiles(key, owner)
+			}
+		case "t":
+			if l.moveToLibraryTrash(path) {
+				delete(l.PDFFiles, key)
+			} else {
+				l.Warning("Could not move %s to the library trash.", path)
+			}
+		case "k":
+			l.SetMetadata(key, MetaPropPdfContentOk, md5hash)
+		case "s":
+		case "q":
+			return true
+		}
+		return false
+	}
+}
+
+// swapPDFFiles exchanges the PDFs of key1 and key2.
+func (l *TBibTeXLibrary) swapPDFFiles(key1, key2 string) {
+	filesDir := l.FilesRoot + l.FilesFolder
+	path1, path2 := filesDir+key1+".pdf", filesDir+key2+".pdf"
+	tmpPath := path1 + ".swap"
+	if err := os.Rename(path1, tmpPath); err != nil {
+		l.Warning("Could not swap PDFs of %s and %s: %s", key1, key2, err)
+		return
+	}
+	if err := os.Rename(path2, path1); err != nil {
+		os.Rename(tmpPath, path1) //nolint:errcheck
+		l.Warning("Could not swap PDFs of %s and %s: %s", key1, key2, err)
+		return
+	}
+	if err := os.Rename(tmpPath, path2); err != nil {
+		l.Warning("Could not swap PDFs of %s and %s: %s (the PDF of %s is left at %s)", key1, key2, err, key2, tmpPath)
+		return
+	}
+	l.DeleteMetadata(key1, MetaPropPdfContentOk)
+	l.DeleteMetadata(key2, MetaPropPdfContentOk)
+	l.Progress(ProgressPDFsSwapped, key1, key2)
+}
+
+// VerifyPDFContents checks the PDFs of keys against their entries' metadata.
+// Returns true when the user asks to quit. With report set, every PDF's outcome
+// is reported, and earlier keep-as-ok confirmations are not honoured.
+func (l *TBibTeXLibrary) VerifyPDFContents(keys []string, report bool) bool {
+	filesDir := l.FilesRoot + l.FilesFolder
+	index := &TPDFOwnerIndex{}
+	ticker := l.NewProgressTicker(ProgressVerifyingPDFContents, len(keys))
+	for _, key := range keys {
+		if ticker.Step() {
+			break
+		}
+		path := filesDir + key + ".pdf"
+		if !FileExists(path) {
+			if report {
+				l.Warning(WarningNoPDFForEntry, key)
+			}
+			continue
+		}
+		if l.verifyOnePDFContent(key, path, index, report) {
+			ticker.Done()
+			return true
+		}
+	}
+	ticker.Done()
+	return false
+}
@@ -0,0 +1,375 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_pdf_xmp
+ *
+ * Embeds an entry's bibliographic metadata into its PDF (-embed_pdf_metadata).
+ *
+ * The canonical key, title, contributors, DOI, DBLP key and a rendered citation
+ * are written to the PDF's XMP metadata stream and to its Info dictionary, by an
+ * incremental update (see pdf_update.go), so that a PDF shared from the files
+ * folder keeps its connection to the library. XMP metadata already present (e.g.
+ * a publisher's) is kept; ours lives in an rdf:Description of its own.
+ *
+ * A fingerprint of the embedded values is stored both in the PDF and in the
+ * entry's metadata. At the end of every session that changed entries, the PDFs
+ * whose fingerprint no longer matches their entry are refreshed. -harvest uses
+ * the embedded key to recognise PDFs that came from our own files folder.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const pdfXmpNamespace = "urn:bibtex-check:xmp:1.0"
+
+var (
+	pdfXmpKeyPattern         = regexp.MustCompile(`<bibcheck:key>([^<]*)</bibcheck:key>`)
+	pdfXmpFingerprintPattern = regexp.MustCompile(`<bibcheck:fingerprint>([^<]*)</bibcheck:fingerprint>`)
+	pdfXmpOwnPattern         = regexp.MustCompile(`(?s)\s*<rdf:Description[^>]*xmlns:bibcheck="` +
+		regexp.QuoteMeta(pdfXmpNamespace) + `".*?</rdf:Description>`)
+)
+
+// TPDFBibMetadata is the bibliographic metadata embedded into an entry's PDF.
+type TPDFBibMetadata struct {
+	Key      string
+	Title    string
+	Creators []string // in natural order ("First Last")
+	DOI      string
+	DBLP     string
+	Citation string
+}
+
+// naturalOrderName turns "Last, First" and "Last, Jr, First" into "First Last"
+// and "First Last, Jr".
+func naturalOrderName(name string) string {
+	parts := strings.Split(name, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	switch len(parts) {
+	case 2:
+		return strings.TrimSpace(parts[1] + " " + parts[0])
+	case 3:
+		return strings.TrimSpace(parts[2]+" "+parts[0]) + ", " + parts[1]
+	}
+	return name
+}
+
+// pdfBibMetadata collects the metadata of entry key to be embedded in its PDF.
+func (l *TBibTeXLibrary) pdfBibMetadata(key string) TPDFBibMetadata {
+	entry := loadEntryFromDb(key)
+	meta := TPDFBibMetadata{
+		Key:      key,
+		Title:    texToText(entry.FieldValue(TitleField)),
+		DOI:      normalizeDOI(entry.FieldValue("doi")),
+		DBLP:     entry.FieldValue(DBLPField),
+		Citation: strings.Join(strings.Fields(l.renderAsText(key)), " "),
+	}
+	names := entry.FieldValue("author")
+	if names == "" {
+		names = entry.FieldValue("editor")
+	}
+	for _, name := range strings.Split(names, " and ") {
+		if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, "others") {
+			meta.Creators = append(meta.Creators, naturalOrderName(texToText(name)))
+		}
+	}
+	return meta
+}
+
+// fingerprint identifies the embedded values, to tell when they are out of date.
+func (m TPDFBibMetadata) fingerprint() string {
+	sum := md5.Sum([]byte(strings.Join([]string{
+		m.Key, m.Title, strings.Join(m.Creators, "\x01"), m.DOI, m.DBLP, m.Citation}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func xmlEscaped(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s)) //nolint:errcheck // strings.Builder does not fail
+	return b.String()
+}
+
+// xmpDescription returns our rdf:Description. The Dublin Core and PRISM
+// properties are left out when full is not set, i.e. when the PDF already has
+// XMP metadata of its own that may define them.
+func (m TPDFBibMetadata) xmpDescription(full bool) string {
+	var b strings.Builder
+	b.WriteString("\n  <rdf:Description rdf:about=\"\"")
+	if full {
+		b.WriteString("\n    xmlns:dc=\"http://purl.org/dc/elements/1.1/\"")
+		b.WriteString("\n    xmlns:prism=\"http://prismstandard.org/namespaces/basic/2.0/\"")
+	}
+	b.WriteString("\n    xmlns:bibcheck=\"" + pdfXmpNamespace + "\">")
+	if full {
+		if m.Title != "" {
+			fmt.Fprintf(&b, "\n   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>", xmlEscaped(m.Title))
+		}
+		if len(m.Creators) > 0 {
+			b.WriteString("\n   <dc:creator><rdf:Seq>")
+			for _, creator := range m.Creators {
+				fmt.Fprintf(&b, "<rdf:li>%s</rdf:li>", xmlEscaped(creator))
+			}
+			b.WriteString("</rdf:Seq></dc:creator>")
+		}
+		if m.Citation != "" {
+			fmt.Fprintf(&b, "\n   <dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>", xmlEscaped(m.Citation))
+		}
+		if m.DOI != "" {
+			fmt.Fprintf(&b, "\n   <dc:identifier>doi:%s</dc:identifier>", xmlEscaped(m.DOI))
+			fmt.Fprintf(&b, "\n   <prism:doi>%s</prism:doi>", xmlEscaped(m.DOI))
+		}
+	}
+	fmt.Fprintf(&b, "\n   <bibcheck:key>%s</bibcheck:key>", xmlEscaped(m.Key))
+	if m.DBLP != "" {
+		fmt.Fprintf(&b, "\n   <bibcheck:dblp>%s</bibcheck:dblp>", xmlEscaped(m.DBLP))
+	}
+	if m.DOI != "" {
+		fmt.Fprintf(&b, "\n   <bibcheck:doi>%s</bibcheck:doi>", xmlEscaped(m.DOI))
+	}
+	if m.Citation != "" {
+		fmt.Fprintf(&b, "\n   <bibcheck:citation>%s</bibcheck:citation>", xmlEscaped(m.Citation))
+	}
+	fmt.Fprintf(&b, "\n   <bibcheck:fingerprint>%s</bibcheck:fingerprint>", m.fingerprint())
+	b.WriteString("\n  </rdf:Description>")
+	return b.String()
+}
+
+// xmpPacket returns the XMP metadata to embed, given the PDF's current XMP
+// metadata (if any): a previous description of ours is replaced, other
+// descriptions are kept.
+func (m TPDFBibMetadata) xmpPacket(current []byte) []byte {
+	foreign := pdfXmpOwnPattern.ReplaceAllString(string(current), "")
+	if strings.Contains(foreign, "<rdf:Description") {
+		if i := strings.LastIndex(foreign, "</rdf:RDF>"); i >= 0 {
+			return []byte(foreign[:i] + m.xmpDescription(false) + "\n " + foreign[i:])
+		}
+	}
+	return []byte("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>" +
+		"\n<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">" +
+		"\n <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">" +
+		m.xmpDescription(true) +
+		"\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+}
+
+// readPDFForUpdate reads and parses the PDF at path, returning its trailer and catalog.
+func readPDFForUpdate(path string) (data []byte, d *pdfDocument, t pdfTrailer, catalog pdfDict, err error) {
+	defer recoverPDFPanic(&err)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, pdfTrailer{}, nil, err
+	}
+	if info.Size() > pdfTextMaxFileSize {
+		return nil, nil, pdfTrailer{}, nil, errPDFTooLarge
+	}
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, nil, pdfTrailer{}, nil, err
+	}
+	if d, err = parsePDF(data); err != nil {
+		return nil, nil, pdfTrailer{}, nil, err
+	}
+	if t, err = d.trailer(); err != nil {
+		return nil, nil, pdfTrailer{}, nil, err
+	}
+	if catalog = d.dict(t.dict["Root"]); catalog == nil {
+		return nil, nil, pdfTrailer{}, nil, errPDFNoTrailer
+	}
+	return data, d, t, catalog, nil
+}
+
+// pdfEmbeddedKey returns the entry key embedded in the PDF at path by
+// -embed_pdf_metadata, or "" when there is none.
+func pdfEmbeddedKey(path string) string {
+	_, d, t, catalog, err := readPDFForUpdate(path)
+	if err != nil {
+		return ""
+	}
+	if xmp, _ := d.streamOf(catalog["Metadata"]); xmp != nil {
+		if match := pdfXmpKeyPattern.FindSubmatch(xmp); match != nil {
+			return html.UnescapeString(string(match[1]))
+		}
+	}
+	if key, ok := d.dict(t.dict["Info"])["BibCheckKey"].(string); ok {
+		if strings.HasPrefix(key, "\xFE\xFF") {
+			key = pdfUTF16(key[2:])
+		}
+		return key
+	}
+	return ""
+}
+
+// pdfContentMD5 is MD5ForFile over the PDF as it was before we embedded any
+// metadata into it, so that duplicate detection and content confirmations are
+// not disturbed by the embedding.
+func pdfContentMD5(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return MD5ForFile(path)
+	}
+	sum := md5.Sum(data[:pdfOriginalLength(data)])
+	return hex.EncodeToString(sum[:])
+}
+
+// EmbedPDFMetadata writes the metadata of entry key into its PDF, unless the PDF
+// already carries the current metadata. Reports whether the PDF was updated.
+func (l *TBibTeXLibrary) EmbedPDFMetadata(key string) (embedded bool, err error) {
+	defer recoverPDFPanic(&err)
+	path := l.FilesRoot + l.FilesFolder + key + ".pdf"
+	data, d, t, catalog, err := readPDFForUpdate(path)
+	if err != nil {
+		return false, err
+	}
+
+	meta := l.pdfBibMetadata(key)
+	fingerprint := meta.fingerprint()
+	currentXMP, _ := d.streamOf(catalog["Metadata"])
+	if match := pdfXmpFingerprintPattern.FindSubmatch(currentXMP); match != nil && string(match[1]) == fingerprint {
+		if l.GetMetadata(key, MetaPropPdfMetadataFingerprint) != fingerprint {
+			l.SetMetadata(key, MetaPropPdfMetadataFingerprint, fingerprint)
+		}
+		return false, nil
+	}
+
+	size, _ := pdfNumber(t.dict["Size"])
+	next := int(size)
+	for num := range d.objects {
+		next = max(next, num+1)
+	}
+	newRef := func() pdfRef {
+		next++
+		return pdfRef{next - 1, 0}
+	}
+
+	var objects []pdfUpdateObject
+	metadataRef, hasMetadata := catalog["Metadata"].(pdfRef)
+	if !hasMetadata {
+		rootRef, ok := t.dict["Root"].(pdfRef)
+		if !ok {
+			return false, errPDFNoTrailer
+		}
+		metadataRef = newRef()
+		newCatalog := pdfDict{}
+		for k, v := range catalog {
+			newCatalog[k] = v
+		}
+		newCatalog["Metadata"] = metadataRef
+		objects = append(objects, pdfUpdateObject{ref: rootRef, value: newCatalog})
+	}
+	objects = append(objects, pdfUpdateObject{
+		ref:    metadataRef,
+		value:  pdfDict{"Type": pdfName("Metadata"), "Subtype": pdfName("XML")},
+		stream: meta.xmpPacket(currentXMP),
+	})
+
+	info := pdfDict{}
+	for k, v := range d.dict(t.dict["Info"]) {
+		info[k] = v
+	}
+	infoRef, hasInfo := t.dict["Info"].(pdfRef)
+	if !hasInfo {
+		infoRef = newRef()
+	}
+	setInfo := func(name, value string) {
+		if value == "" {
+			delete(info, name)
+		} else {
+			info[name] = pdfTextString(value)
+		}
+	}
+	setInfo("Title", meta.Title)
+	setInfo("Author", strings.Join(meta.Creators, "; "))
+	setInfo("Subject", meta.Citation)
+	setInfo("BibCheckKey", meta.Key)
+	setInfo("DOI", meta.DOI)
+	setInfo("DBLPKey", meta.DBLP)
+	info["ModDate"] = time.Now().Format("D:20060102150405")
+	objects = append(objects, pdfUpdateObject{ref: infoRef, value: info})
+
+	updated := pdfAppendUpdate(data, t, objects, next, pdfDict{"Info": infoRef})
+
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, updated, mode); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return false, err
+	}
+	l.SetMetadata(key, MetaPropPdfMetadataFingerprint, fingerprint)
+	return true, nil
+}
+
+// EmbedAllPDFMetadata embeds the entries' metadata into the PDFs of keys.
+func (l *TBibTeXLibrary) EmbedAllPDFMetadata(keys []string) {
+	updated, current := 0, 0
+	ticker := l.NewProgressTicker(ProgressEmbeddingPDFMetadata, len(keys))
+	for _, key := range keys {
+		if ticker.Step() {
+			break
+		}
+		if !FileExists(l.FilesRoot + l.FilesFolder + key + ".pdf") {
+			l.Warning(WarningNoPDFForEntry, key)
+			continue
+		}
+		changed, err := l.EmbedPDFMetadata(key)
+		switch {
+		case err != nil:
+			l.Warning(WarningPDFMetadataNotEmbedded, key, err)
+		case changed:
+			updated++
+		default:
+			current++
+		}
+	}
+	ticker.Done()
+	l.Progress(ProgressEmbeddedPDFMetadata, updated, current)
+}
+
+// RefreshEmbeddedPDFMetadata re-embeds the metadata of PDFs whose entries changed
+// since their metadata was embedded. Called at the end of sessions that changed
+// entries; PDFs that never had metadata embedded are left alone.
+func (l *TBibTeXLibrary) RefreshEmbeddedPDFMetadata() {
+	var keys []string
+	for key, props := range l.Metadata {
+		if props[MetaPropPdfMetadataFingerprint] != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !l.EntryExists(key) || !FileExists(l.FilesRoot+l.FilesFolder+key+".pdf") {
+			continue
+		}
+		if l.pdfBibMetadata(key).fingerprint() == l.GetMetadata(key, MetaPropPdfMetadataFingerprint) {
+			continue
+		}
+		if changed, err := l.EmbedPDFMetadata(key); err != nil {
+			l.Warning(WarningPDFMetadataNotEmbedded, key, err)
+		} else if changed {
+			l.Progress(ProgressRefreshedPDFMetadata, key)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_policy
+ *
+ * Declarative conflict policy (<basename>.scripts/conflict_policy.json), deciding
+ * field conflicts and potential duplicate merges that would otherwise be asked:
+ *
+ *	{
+ *	  "fields": [
+ *	    {"field": "pages", "prefer": "dblp"},
+ *	    {"field": "title", "when": "case_protection", "prefer": "longer"},
+ *	    {"field": "*", "prefer": "edited"}
+ *	  ],
+ *	  "merges": [
+ *	    {"name": "same-doi-and-dblp", "equal": ["doi", "dblp"]}
+ *	  ]
+ *	}
+ *
+ * A field rule applies to one field (or "*"), optionally only when the two values
+ * differ in case protection (braces) or letter case alone, and prefers:
+ *   - "current" or "challenge": that value
+ *   - "longer" or "shorter": the longer or shorter value
+ *   - "edited": the current value when it was hand-edited (see TLineageRecord)
+ *   - a lineage source such as "dblp": the value delivered by that source
+ * The first rule that reaches a decision wins; when none does, the user is asked
+ * as before. A merge rule accepts a potential duplicate pair when all of its fields
+ * are filled and equal in both entries.
+ *
+ * Field decisions are recorded in the lineage of the chosen value (Policy), and
+ * every decision is logged in policy_decisions: a summary is shown at the end of
+ * the session, and -policy_report [session] lists them.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Conditions of field policy rules.
+const (
+	PolicyWhenCaseProtection = "case_protection" // the values differ in braces only
+	PolicyWhenCase           = "case"            // the values differ in letter case only
+)
+
+// Preferences of field policy rules, besides lineage sources.
+const (
+	PolicyPreferCurrent   = "current"
+	PolicyPreferChallenge = "challenge"
+	PolicyPreferLonger    = "longer"
+	PolicyPreferShorter   = "shorter"
+	PolicyPreferEdited    = "edited"
+)
+
+// TFieldPolicyRule decides a conflict between two values of a field.
+type TFieldPolicyRule struct {
+	Name   string `json:"name,omitempty"` // shown in lineage and reports; defaults to field/when/prefer
+	Field  string `json:"field"`          // field name, or "*" for every field
+	When   string `json:"when,omitempty"` // "", "case_protection" or "case"
+	Prefer string `json:"prefer"`
+}
+
+// TMergePolicyRule accepts the merge of a potential duplicate pair.
+type TMergePolicyRule struct {
+	Name  string   `json:"name,omitempty"` // defaults to "equal " + the fields
+	Equal []string `json:"equal"`          // fields that must be filled and equal in both entries
+}
+
+// TConflictPolicy is the structure of the conflict policy file.
+type TConflictPolicy struct {
+	Fields []TFieldPolicyRule `json:"fields,omitempty"`
+	Merges []TMergePolicyRule `json:"merges,omitempty"`
+}
+
+var (
+	conflictPolicyLoaded bool
+	conflictPolicy       *TConflictPolicy // nil when there is no (valid) policy file
+
+	// policyDecisionCounts counts the decisions per rule in this session.
+	policyDecisionCounts = map[string]int{}
+
+	// policySessionStart identifies the session when nothing is journaled.
+	policySessionStart = time.Now()
+)
+
+// ensurePolicyDecisionsTableExists creates the log of policy decisions.
+func ensurePolicyDecisionsTableExists() {
+	tryCreateTableIfNeeded(`
+		CREATE TABLE IF NOT EXISTS policy_decisions (
+		  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		  session    TEXT NOT NULL,
+		  entry_key  TEXT NOT NULL,
+		  field      TEXT NOT NULL DEFAULT '',
+		  rule       TEXT NOT NULL,
+		  decision   TEXT NOT NULL
+		);`)
+}
+
+// conflictPolicyRules returns the conflict policy, loading it on first use.
+func (l *TBibTeXLibrary) conflictPolicyRules() *TConflictPolicy {
+	if conflictPolicyLoaded {
+		return conflictPolicy
+	}
+	conflictPolicyLoaded = true
+	path := bibTeXFolder + bibTeXBaseName + ConflictPolicyFilePath
+	if !FileExists(path) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.Warning(WarningPolicyFileInvalid, path, err)
+		return nil
+	}
+	var policy TConflictPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		l.Warning(WarningPolicyFileInvalid, path, err)
+		return nil
+	}
+	conflictPolicy = &TConflictPolicy{}
+	for _, rule := range policy.Fields {
+		if rule.Name == "" {
+			rule.Name = strings.Join(nonEmpty(rule.Field, rule.When, rule.Prefer), "/")
+		}
+		switch {
+		case rule.Field == "" || rule.Prefer == "":
+			l.Warning(WarningPolicyRuleInvalid, rule.Name, "needs a field and a preference")
+		case rule.When != "" && rule.When != PolicyWhenCaseProtection && rule.When != PolicyWhenCase:
+			l.Warning(WarningPolicyRuleInvalid, rule.Name, "unknown condition "+rule.When)
+		default:
+			conflictPolicy.Fields = append(conflictPolicy.Fields, rule)
+		}
+	}
+	for _, rule := range policy.Merges {
+		if rule.Name == "" {
+			rule.Name = "equal " + strings.Join(rule.Equal, ", ")
+		}
+		if len(rule.Equal) == 0 {
+			l.Warning(WarningPolicyRuleInvalid, rule.Name, "needs at least one field that must be equal")
+			continue
+		}
+		conflictPolicy.Merges = append(conflictPolicy.Merges, rule)
+	}
+	return conflictPolicy
+}
+
+// nonEmpty returns the non-empty strings of values.
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// policyCondition reports whether current and challenge meet the condition of a rule.
+func policyCondition(when, current, challenge string) bool {
+	switch when {
+	case PolicyWhenCaseProtection:
+		unbraced := strings.NewReplacer("{", "", "}", "")
+		return unbraced.Replace(current) == unbraced.Replace(challenge)
+	case PolicyWhenCase:
+		return strings.EqualFold(current, challenge)
+	}
+	return true
+}
+
+// policyChoice returns the value rule prefers (true for challenge), and whether
+// the rule reaches a decision at all.
+func policyChoice(rule TFieldPolicyRule, currentRec TLineageRecord, challengeSource, current, challenge string) (bool, bool) {
+	switch rule.Prefer {
+	case PolicyPreferCurrent:
+		return false, true
+	case PolicyPreferChallenge:
+		return true, true
+	case PolicyPreferLonger, PolicyPreferShorter:
+		if len(current) == len(challenge) {
+			return false, false
+		}
+		return (len(challenge) > len(current)) == (rule.Prefer == PolicyPreferLonger), true
+	case PolicyPreferEdited:
+		return false, currentRec.Edited
+	}
+	// A lineage source.
+	switch {
+	case challengeSource == rule.Prefer:
+		return true, true
+	case currentRec.Source == rule.Prefer:
+		return false, true
+	}
+	return false, false
+}
+
+// resolveByPolicy decides a field conflict by the first applicable policy rule,
+// recording the decision in the lineage of the chosen value. The second result
+// reports whether a rule decided.
+func (l *TBibTeXLibrary) resolveByPolicy(key, field string, currentRec TLineageRecord, challengeSource, challenge, current string) (string, bool) {
+	policy := l.conflictPolicyRules()
+	if policy == nil {
+		return "", false
+	}
+	for _, rule := range policy.Fields {
+		if (rule.Field != field && rule.Field != "*") || !policyCondition(rule.When, current, challenge) {
+			continue
+		}
+		takeChallenge, decided := policyChoice(rule, currentRec, challengeSource, current, challenge)
+		if !decided {
+			continue
+		}
+		if challengeSource != "" {
+			l.setSourceFieldSignature(key, field, challengeSource, challenge)
+		}
+		if takeChallenge {
+			l.UpdateEntryFieldAlias(key, field, current, challenge)
+			l.setPolicyLineage(key, field, challenge, challengeSource, false, rule.Name)
+			l.notePolicyDecision(key, field, rule.Name, fmt.Sprintf("took {%s} over {%s}", challenge, current))
+			return challenge, true
+		}
+		l.UpdateEntryFieldAlias(key, field, challenge, current)
+		l.setPolicyLineage(key, field, current, challengeSource, true, rule.Name)
+		l.notePolicyDecision(key, field, rule.Name, fmt.Sprintf("kept {%s} over {%s}", current, challenge))
+		return current, true
+	}
+	return "", false
+}
+
+// policyFieldsEqual reports whether field is filled and equal in both entries.
+func (l *TBibTeXLibrary) policyFieldsEqual(source, target, field string) bool {
+	sv := l.NormaliseFieldValue(field, l.EntryFieldValueity(source, field))
+	tv := l.NormaliseFieldValue(field, l.EntryFieldValueity(target, field))
+	switch field {
+	case DBLPField:
+		sv, tv = normalizeDblpKey(sv), normalizeDblpKey(tv)
+	case "doi":
+		sv, tv = strings.ToLower(sv), strings.ToLower(tv)
+	}
+	return sv != "" && sv == tv
+}
+
+// mergeByPolicy merges source into target when a merge rule accepts the pair,
+// and reports whether it did.
+func (l *TBibTeXLibrary) mergeByPolicy(source, target string) bool {
+	policy := l.conflictPolicyRules()
+	if policy == nil {
+		return false
+	}
+	for _, rule := range policy.Merges {
+		accepted := true
+		for _, field := range rule.Equal {
+			accepted = accepted && l.policyFieldsEqual(source, target, field)
+		}
+		if !accepted {
+			continue
+		}
+		l.Progress(ProgressPolicyMerge, source, target, rule.Name)
+		l.notePolicyDecision(target, "", rule.Name, fmt.Sprintf("merged %s into %s", source, target))
+		l.MergeEntries(source, target)
+		return true
+	}
+	return false
+}
+
+// currentPolicySession identifies the session in policy_decisions: the session
+// of the session journal, or the session start when nothing is journaled.
+func currentPolicySession() string {
+	var session string
+	if bibQueryRow(`SELECT session FROM session_journal_state WHERE id = 1`).Scan(&session) != nil || session == "" {
+		session = policySessionStart.Format("2006-01-02 15:04:05")
+	}
+	return session
+}
+
+// notePolicyDecision logs a policy decision.
+func (l *TBibTeXLibrary) notePolicyDecision(key, field, rule, decision string) {
+	policyDecisionCounts[rule]++
+	dbExecSave("notePolicyDecision",
+		`INSERT INTO policy_decisions (session, entry_key, field, rule, decision) VALUES (?, ?, ?, ?, ?)`,
+		currentPolicySession(), key, field, rule, decision)
+}
+
+// reportPolicyDecisions summarises the decisions the policy made in this session.
+func reportPolicyDecisions() {
+	if len(policyDecisionCounts) == 0 {
+		return
+	}
+	var rows []statRow
+	for _, rule := range sortedMapKeys(policyDecisionCounts) {
+		rows = append(rows, statRow{rule, fmt.Sprintf("%d", policyDecisionCounts[rule]), ""})
+	}
+	printStatBlock(StatPolicyDecisions, rows, true)
+	policyDecisionCounts = map[string]int{}
+}
+
+// doPolicyReport lists the decisions the policy made in session, or in the
+// latest session that has any.
+func doPolicyReport(session string) {
+	if session == "" {
+		bibQueryRow(`SELECT session FROM policy_decisions ORDER BY id DESC LIMIT 1`).Scan(&session) //nolint:errcheck
+	}
+	if session == "" {
+		Reporting.Progress(ProgressPolicyNoDecisions)
+		return
+	}
+	rows, err := bibQuery(`SELECT entry_key, field, rule, decision FROM policy_decisions WHERE session = ? ORDER BY id`, session)
+	if err != nil {
+		Reporting.Warning(WarningPolicyReportFailed, err)
+		return
+	}
+	defer rows.Close()
+	type decision struct{ key, field, rule, decision string }
+	var decisions []decision
+	for rows.Next() {
+		var d decision
+		if rows.Scan(&d.key, &d.field, &d.rule, &d.decision) == nil {
+			decisions = append(decisions, d)
+		}
+	}
+	fmt.Printf("Conflict policy decisions of session %s: %d\n", session, len(decisions))
+	for _, d := range decisions {
+		target := d.key
+		if d.field != "" {
+			target += " " + d.field
+		}
+		fmt.Printf("  [%s] %s: %s\n", d.rule, target, d.decision)
+	}
+}
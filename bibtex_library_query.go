@@ -0,0 +1,496 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_query
+ *
+ * A compact boolean query language over the library, used by -find_entries,
+ * by "where" statements in .select files, and by -render_group. On the command
+ * line a query is a single argument that looks like one, or anything after an
+ * explicit "where"; -render_group only takes the latter, as group names may
+ * look like queries.
+ *
+ * Queries compile to the same condition nodes (scriptCond) as the entry_actions
+ * scripts and smart groups, and are evaluated by scriptEvalCond, so a query
+ * means exactly what the equivalent script condition means.
+ *
+ *   query  := or
+ *   or     := and { "or" and }
+ *   and    := unary { ["and"] unary }          (juxtaposition is "and")
+ *   unary  := ("not" | "!") unary | "(" query ")" | atom
+ *   atom   := has_pdf | has_dblp | has_doi | has_warning
+ *           | name op value
+ *   op     := "=" | "!=" | "~" | "!~" | "<" | "<=" | ">" | ">="
+ *
+ * Names other than a field name:
+ *   type         entry type (= exact, ~ regex)
+ *   key          entry key or preferred alias (= exact, ~ regex)
+ *   group        static or smart group (= exact, ~ regex on the group name)
+ *   contributor  EP id, ORCID, or name (= only)
+ *   flag         entry flag (= only)
+ *   warning      recorded warning text (= substring, ~ regex)
+ *   any          any field (~ only)
+ *
+ * For fields, = is equality, ~ a regular expression, and the comparisons are
+ * numeric; "year = 2010..2020" is an inclusive range. Values are bare words or
+ * "quoted strings".
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ─── Lexer ─────────────────────────────────────────────────────────────────────
+
+type qtok int
+
+const (
+	qtokEOF    qtok = iota
+	qtokWord        // bare word: keyword, name, or unquoted value
+	qtokString      // "quoted string"
+	qtokOp          // = != ~ !~ < <= > >=
+	qtokNot         // !
+	qtokLParen      // (
+	qtokRParen      // )
+)
+
+type queryToken struct {
+	kind qtok
+	val  string
+}
+
+// queryDelimiters end a bare word.
+const queryDelimiters = `()"=!~<>`
+
+func scanQuery(src string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(src)
+	for pos := 0; pos < len(runes); {
+		ch := runes[pos]
+		next := rune(0)
+		if pos+1 < len(runes) {
+			next = runes[pos+1]
+		}
+		switch {
+		case unicode.IsSpace(ch):
+			pos++
+		case ch == '(':
+			tokens = append(tokens, queryToken{qtokLParen, "("})
+			pos++
+		case ch == ')':
+			tokens = append(tokens, queryToken{qtokRParen, ")"})
+			pos++
+		case (ch == '!' || ch == '<' || ch == '>') && next == '=', ch == '!' && next == '~':
+			tokens = append(tokens, queryToken{qtokOp, string([]rune{ch, next})})
+			pos += 2
+		case ch == '!':
+			tokens = append(tokens, queryToken{qtokNot, "!"})
+			pos++
+		case ch == '=' || ch == '~' || ch == '<' || ch == '>':
+			tokens = append(tokens, queryToken{qtokOp, string(ch)})
+			pos++
+		case ch == '"':
+			var buf strings.Builder
+			pos++
+			for pos < len(runes) && runes[pos] != '"' {
+				if runes[pos] == '\\' && pos+1 < len(runes) && runes[pos+1] == '"' {
+					pos++
+				}
+				buf.WriteRune(runes[pos])
+				pos++
+			}
+			if pos >= len(runes) {
+				return nil, fmt.Errorf("unterminated quoted string")
+			}
+			pos++
+			tokens = append(tokens, queryToken{qtokString, buf.String()})
+		default:
+			start := pos
+			for pos < len(runes) && !unicode.IsSpace(runes[pos]) && !strings.ContainsRune(queryDelimiters, runes[pos]) {
+				pos++
+			}
+			tokens = append(tokens, queryToken{qtokWord, string(runes[start:pos])})
+		}
+	}
+	return append(tokens, queryToken{kind: qtokEOF}), nil
+}
+
+// ─── Parser ────────────────────────────────────────────────────────────────────
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken { return p.tokens[p.pos] }
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.kind != qtokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) isWord(word string) bool {
+	t := p.peek()
+	return t.kind == qtokWord && strings.EqualFold(t.val, word)
+}
+
+// ParseQuery compiles a query into a condition.
+func ParseQuery(src string) (scriptCond, error) {
+	tokens, err := scanQuery(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == qtokEOF {
+		return nil, fmt.Errorf("empty query")
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != qtokEOF {
+		return nil, fmt.Errorf("unexpected %q", t.val)
+	}
+	return cond, nil
+}
+
+func (p *queryParser) parseOr() (scriptCond, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sCondOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (scriptCond, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind == qtokEOF || t.kind == qtokRParen || p.isWord("or") {
+			return left, nil
+		}
+		if p.isWord("and") {
+			p.next()
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sCondAnd{left, right}
+	}
+}
+
+func (p *queryParser) parseUnary() (scriptCond, error) {
+	t := p.peek()
+	switch {
+	case t.kind == qtokNot || p.isWord("not"):
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &sCondNot{inner}, nil
+	case t.kind == qtokLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != qtokRParen {
+			return nil, fmt.Errorf("missing \")\"")
+		}
+		return inner, nil
+	case t.kind == qtokWord:
+		return p.parseAtom()
+	case t.kind == qtokEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.val)
+}
+
+func (p *queryParser) parseAtom() (scriptCond, error) {
+	name := strings.ToLower(p.next().val)
+	switch name {
+	case "has_pdf":
+		return &sCondHasPdf{}, nil
+	case "has_dblp":
+		return &sCondHasDblp{}, nil
+	case "has_doi":
+		return &sCondFieldEmpty{field: "doi", negated: true}, nil
+	case "has_warning":
+		return &sCondHasWarning{}, nil
+	}
+
+	opTok := p.next()
+	if opTok.kind != qtokOp {
+		return nil, fmt.Errorf("expected an operator after %q", name)
+	}
+	valTok := p.next()
+	if valTok.kind != qtokWord && valTok.kind != qtokString {
+		return nil, fmt.Errorf("expected a value after %s %s", name, opTok.val)
+	}
+	op, value := opTok.val, valTok.val
+
+	negate := func(c scriptCond, err error) (scriptCond, error) {
+		if err != nil || !strings.HasPrefix(op, "!") {
+			return c, err
+		}
+		return &sCondNot{c}, nil
+	}
+	compile := func() (*regexp.Regexp, error) {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %s", value, err)
+		}
+		return re, nil
+	}
+	exact := func() *regexp.Regexp { return regexp.MustCompile("^" + regexp.QuoteMeta(value) + "$") }
+	onlyEquality := func() error {
+		if op != "=" && op != "!=" {
+			return fmt.Errorf("%s only supports = and !=", name)
+		}
+		return nil
+	}
+
+	switch name {
+	case "type", EntryTypeField:
+		if op == "~" || op == "!~" {
+			re, err := compile()
+			return negate(&sCondFieldMatches{field: EntryTypeField, re: re}, err)
+		}
+		return negate(&sCondEntryType{entryType: strings.ToLower(value)}, onlyEquality())
+	case "key":
+		if op == "~" || op == "!~" {
+			re, err := compile()
+			return negate(&sCondKeyMatches{re: re}, err)
+		}
+		return negate(&sCondKeyMatches{re: exact()}, onlyEquality())
+	case "group":
+		if op == "~" || op == "!~" {
+			re, err := compile()
+			return negate(&sCondGroupMatches{re: re}, err)
+		}
+		return negate(&sCondInGroup{group: value}, onlyEquality())
+	case "contributor":
+		return negate(&sCondContributor{ref: value}, onlyEquality())
+	case "flag":
+		return negate(&sCondHasFlag{flag: value}, onlyEquality())
+	case "warning":
+		if op == "~" || op == "!~" {
+			re, err := compile()
+			return negate(&sCondHasWarning{re: re}, err)
+		}
+		return negate(&sCondHasWarning{re: regexp.MustCompile(regexp.QuoteMeta(value))}, onlyEquality())
+	case "any":
+		if op != "~" && op != "!~" {
+			return nil, fmt.Errorf("any only supports ~ and !~")
+		}
+		re, err := compile()
+		return negate(&sCondFieldMatches{re: re}, err)
+	}
+
+	switch op {
+	case "~", "!~":
+		re, err := compile()
+		return negate(&sCondFieldMatches{field: name, re: re}, err)
+	case "=", "!=":
+		if from, to, isRange := strings.Cut(value, ".."); isRange {
+			low, errLow := strconv.Atoi(from)
+			high, errHigh := strconv.Atoi(to)
+			if errLow != nil || errHigh != nil {
+				return nil, fmt.Errorf("invalid range %q", value)
+			}
+			return negate(&sCondAnd{
+				&sCondFieldNumCmp{field: name, op: ">=", value: low},
+				&sCondFieldNumCmp{field: name, op: "<=", value: high},
+			}, nil)
+		}
+		return negate(&sCondFieldEquals{field: name, value: value}, nil)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s needs a number, got %q", name, op, value)
+	}
+	return &sCondFieldNumCmp{field: name, op: op, value: n}, nil
+}
+
+// queryKeywords are the words that, on their own, make an argument a query.
+var queryKeywords = TStringSetNew()
+
+func init() {
+	queryKeywords.Add("and", "or", "not", "has_pdf", "has_dblp", "has_doi", "has_warning")
+}
+
+// looksLikeQuery reports whether s is written in the query language rather
+// than being a plain field or group name.
+func looksLikeQuery(s string) bool {
+	if strings.ContainsAny(s, "=~<>()!") {
+		return true
+	}
+	for _, word := range strings.Fields(s) {
+		if queryKeywords.Contains(strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryMarker explicitly marks an argument as a query, e.g. "where has_pdf".
+const queryMarker = "where"
+
+// explicitQuery returns s without its leading queryMarker, and whether it had one.
+func explicitQuery(s string) (string, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], queryMarker) {
+		return s, false
+	}
+	return strings.TrimSpace(strings.TrimSpace(s)[len(queryMarker):]), true
+}
+
+// queryFromArgs returns the query given by the arguments of -find_entries: any
+// arguments after a leading queryMarker, or a single argument that looks like a
+// query. Two or more plain arguments are the legacy <field> <value> form, even
+// when the value happens to contain query words or operators.
+func queryFromArgs(args []string) (string, bool) {
+	if query, ok := explicitQuery(strings.Join(args, " ")); ok {
+		return query, true
+	}
+	if len(args) == 1 && looksLikeQuery(args[0]) {
+		return args[0], true
+	}
+	return "", false
+}
+
+// ─── Evaluation and output ─────────────────────────────────────────────────────
+
+// QueryEntries returns the sorted keys of all entries satisfying cond.
+func (l *TBibTeXLibrary) QueryEntries(cond scriptCond) []string {
+	prog := &scriptProgram{groupSets: map[string][]string{}}
+	var keys []string
+	forEachBibEntryKey(func(key string) bool {
+		if scriptEvalCond(l, key, prog, cond) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// QueryEntryKeys parses and runs query in one go.
+func (l *TBibTeXLibrary) QueryEntryKeys(query string) ([]string, error) {
+	cond, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return l.QueryEntries(cond), nil
+}
+
+// defaultQueryColumns are the output columns of -find_entries queries when no
+// -columns are given.
+const defaultQueryColumns = "key,year,title"
+
+// queryColumnValue returns the value of column for key. Besides field names
+// (inherited fields are taken from the crossref parent) it knows key, alias,
+// groups, pdf and warnings.
+func (l *TBibTeXLibrary) queryColumnValue(key, column string) string {
+	switch column {
+	case "key":
+		return key
+	case "alias":
+		return l.PreferredKey(key)
+	case "type":
+		return l.EntryType(key)
+	case "groups":
+		var groups []string
+		for group, members := range l.GroupEntries {
+			if members.Set().Contains(key) {
+				groups = append(groups, group)
+			}
+		}
+		sort.Strings(groups)
+		return strings.Join(groups, ", ")
+	case "pdf":
+		if l.PDFFiles[key] {
+			return l.FilesRoot + l.FilesFolder + key + ".pdf"
+		}
+		return ""
+	case "warnings":
+		return strings.Join(entryWarningTexts(key), "; ")
+	}
+	return scriptFieldValue(l, key, column)
+}
+
+// WriteQueryResults writes one row per key with the given columns, as TSV
+// (tabs and newlines in values become spaces) or, with format "json", as an
+// array of objects.
+func (l *TBibTeXLibrary) WriteQueryResults(w io.Writer, keys, columns []string, format string) error {
+	if format == "json" {
+		rows := make([]map[string]string, 0, len(keys))
+		for _, key := range keys {
+			row := map[string]string{}
+			for _, column := range columns {
+				row[column] = l.queryColumnValue(key, column)
+			}
+			rows = append(rows, row)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(rows)
+	}
+	flatten := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	for _, key := range keys {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = flatten.Replace(l.queryColumnValue(key, column))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(values, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseQueryColumns splits a -columns list, falling back to defaultQueryColumns.
+func parseQueryColumns(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		list = defaultQueryColumns
+	}
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		if column = strings.ToLower(strings.TrimSpace(column)); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
@@ -227,6 +227,15 @@ func (l *TBibTeXLibrary) ResolveFieldValue(key, challengeKey, field, challengeRa
 		currentRec.Edited = false
 	}
 
+	// Conflicts the conflict policy decides are not asked. This comes before the
+	// semantic comparison below, so that rules on case or case protection also
+	// apply to challenges that differ only in braces or case.
+	if !forceInteractive && field != PreferredAliasField {
+		if result, decided := l.resolveByPolicy(key, field, currentRec, challengeSource, challenge, current); decided {
+			return result
+		}
+	}
+
 	// Equal or higher priority challenger: compare semantic content.
 	if !forceInteractive && TeXStringIndexer(current) == TeXStringIndexer(challenge) {
 		// For title/booktitle in library-to-library merges (no external source), brace structure
@@ -397,7 +406,7 @@ func (l *TBibTeXLibrary) ResolveFieldValue(key, challengeKey, field, challengeRa
 		l.setLineage(key, field, challenge, challengeSource, false)
 		return challenge
 	case "e":
-		edited, _ := l.AskForInput("Enter the resolved value for " + field)
+		edited, _ := l.AskForInput("Enter the resolved value for "+field, key)
 		edited = strings.TrimSpace(edited)
 		if edited == "" {
 			edited = current
@@ -521,7 +530,7 @@ func (l *TBibTeXLibrary) resolveNamePair(key, field string, namePos, nameTotal,
 	}
 
 	answer := l.WarningQuestionOrdered(
-		"Keep current name? (Y/N = globally, y/n = this entry only), edit canonical (e), different people (d), quit (q)?",
+		QuestionNamePair,
 		[]string{"N", "Y", "n", "y", "e", "d", "q"},
 		"Name %d of %d (difference %d of %d) for entry %s field %s:\n- Current:    %s\n- Challenger: %s",
 		namePos, nameTotal, diffIdx, diffTotal, key, field, currentDisplay, challengerDisplay)
@@ -541,7 +550,7 @@ func (l *TBibTeXLibrary) resolveNamePair(key, field string, namePos, nameTotal,
 		// Entry-only: use challenger name for this entry, no mapping recorded.
 		return challengerName, false, false
 	case "e":
-		canonical, err := l.AskForInput("Enter canonical name")
+		canonical, err := l.AskForInput("Enter canonical name", key, field, currentName, challengerName)
 		if err == nil && canonical != "" {
 			l.AddNameMapping(canonical, currentName)
 			l.AddNameMapping(canonical, challengerName)
@@ -0,0 +1,465 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_review_server
+ *
+ * Local web UI for reviewing potential duplicates and DBLP candidates
+ * (-review_server <addr>), for curators who do not use the terminal.
+ *
+ * Pages:
+ *   GET  /                                   the pending duplicate pairs (as -fix_duplicates) and DBLP candidates (as -fix_candidates)
+ *   GET  /review?kind=&key=&other=           one item: both records side by side, differing fields marked
+ *   POST /decide                             apply a decision to an item, then show the next one
+ *                                            (only with the session token of the review form, from the same origin)
+ *   GET  /pdf/{key}                          the PDF of a library entry
+ *
+ * Decisions: merge (either way) or non-double for duplicate pairs; link, non-double
+ * or waive for DBLP candidates. They are applied through the same calls as the
+ * interactive runs: MergeDecidedEntries (the merge policy, then MergeEntries; a
+ * pair whose years differ needs an extra tick), AddNonDoubleEntries, and
+ * AssociateDblpKey followed by the entry checks, as in -fix_candidates. Waiving adds the entry to dblp_waived
+ * and dismisses its current candidates.
+ *
+ * The server is a write session like any other run. All library and database
+ * work is done by the main goroutine, one request at a time; the HTTP handlers
+ * only hand it over. Ctrl-C stops the server and ends the session as usual.
+ * Questions a decision still raises (e.g. conflicting field values in a merge)
+ * are asked in the terminal running the server.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	stdlib_html "html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// reviewListLimit bounds the number of items listed on the overview page.
+const reviewListLimit = 500
+
+// Review decisions.
+const (
+	ReviewMerge        = "merge"         // duplicate: merge the right entry into the left one
+	ReviewMergeReverse = "merge_reverse" // duplicate: merge the left entry into the right one
+	ReviewNonDouble    = "non_double"    // duplicate or DBLP candidate: not the same publication
+	ReviewLink         = "link"          // DBLP candidate: associate the DBLP key with the entry
+	ReviewWaive        = "waive"         // DBLP candidate: the entry has no DBLP counterpart
+)
+
+// TReviewServer holds the review queue. Its fields are only used by the main
+// goroutine (see run).
+type TReviewServer struct {
+	items    []*THomeworkItem
+	notice   string            // feedback shown on the next page
+	work     chan func()       // jobs for the main goroutine
+	stopping chan struct{}     // closed when the server shuts down
+	pdfPaths map[string]string // entry key → PDF path, for the /pdf handler
+	token    string            // per-session token of the decision form, against cross-site requests
+}
+
+// run has the main goroutine execute job, and reports false when the server is
+// shutting down.
+func (s *TReviewServer) run(job func()) bool {
+	done := make(chan struct{})
+	select {
+	case s.work <- func() { job(); close(done) }:
+		<-done
+		return true
+	case <-s.stopping:
+		return false
+	}
+}
+
+// page wraps a page handler: the page is built by the main goroutine and then
+// written as HTML.
+func (s *TReviewServer) page(build func(r *http.Request, b *strings.Builder) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		redirect := ""
+		if !s.run(func() { redirect = build(r, &b) }) {
+			http.Error(w, "the review server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		if redirect != "" {
+			http.Redirect(w, r, redirect, http.StatusSeeOther)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, reviewPageHead, b.String(), "</body></html>\n")
+	}
+}
+
+const reviewPageHead = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Library review</title>
+<style>
+body { font-family: sans-serif; margin: 1.5em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+td, th { padding: 2px 6px; vertical-align: top; text-align: left; }
+table.list tr:nth-child(even) { background: #f4f4f4; }
+table.fields td.name { width: 10em; color: #666; }
+table.fields tr.diff { background: #fff0c0; }
+div.records { display: flex; gap: 2em; }
+div.record { flex: 1; border: 1px solid #ccc; padding: 0.5em 1em; }
+p.notice { background: #e8f0ff; padding: 0.5em; }
+form.decide button { margin-right: 0.5em; }
+</style></head><body>
+<p><a href="/">Overview</a></p>
+`
+
+// reviewItemOther is the other side of it: the second entry of a duplicate
+// pair, or the DBLP key of a candidate.
+func reviewItemOther(it *THomeworkItem) string {
+	if it.Kind == HomeworkDuplicate {
+		return it.Keys[1]
+	}
+	return it.Value
+}
+
+// reviewItemURL is the address of the review page of it.
+func reviewItemURL(it *THomeworkItem) string {
+	return "/review?" + url.Values{"kind": {it.Kind}, "key": {it.Keys[0]}, "other": {reviewItemOther(it)}}.Encode()
+}
+
+// reviewItem returns the pending item identified by the kind, key and other
+// values of a request.
+func reviewItem(values url.Values) (*THomeworkItem, bool) {
+	key, other := values.Get("key"), values.Get("other")
+	if key == "" || other == "" {
+		return nil, false
+	}
+	var it *THomeworkItem
+	switch values.Get("kind") {
+	case HomeworkDuplicate:
+		it = &THomeworkItem{Kind: HomeworkDuplicate, Keys: []string{key, other}}
+	case HomeworkDblp:
+		it = &THomeworkItem{Kind: HomeworkDblp, Keys: []string{key}, Value: other}
+	default:
+		return nil, false
+	}
+	return it, it.pending()
+}
+
+// sameItem reports whether a and b are about the same decision.
+func sameItem(a, b *THomeworkItem) bool {
+	return reviewItemURL(a) == reviewItemURL(b)
+}
+
+// nextItem returns the queued item following it, or nil.
+func (s *TReviewServer) nextItem(it *THomeworkItem) *THomeworkItem {
+	for i, queued := range s.items {
+		if sameItem(queued, it) {
+			if i+1 < len(s.items) {
+				return s.items[i+1]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// prune drops the queued items that no longer need a decision.
+func (s *TReviewServer) prune() {
+	var items []*THomeworkItem
+	for _, it := range s.items {
+		if it.pending() {
+			items = append(items, it)
+		}
+	}
+	s.items = items
+}
+
+func (s *TReviewServer) overviewPage(r *http.Request, b *strings.Builder) string {
+	if r.URL.Query().Get("refresh") != "" {
+		s.items = append(collectDuplicateItems(), collectDblpCandidateItems()...)
+	}
+	s.writeNotice(b)
+	duplicates, candidates := 0, 0
+	for _, it := range s.items {
+		if it.Kind == HomeworkDuplicate {
+			duplicates++
+		} else {
+			candidates++
+		}
+	}
+	fmt.Fprintf(b, "<h1>Review</h1>\n<p>%d potential duplicate pair(s), %d DBLP candidate(s). <a href=\"/?refresh=1\">Refresh</a></p>\n",
+		duplicates, candidates)
+	b.WriteString("<table class=\"list\">\n<tr><th>Kind</th><th>Entry</th><th>Other</th><th>Title</th></tr>\n")
+	for i, it := range s.items {
+		if i == reviewListLimit {
+			fmt.Fprintf(b, "<tr><td colspan=\"4\">… %d more</td></tr>\n", len(s.items)-i)
+			break
+		}
+		fmt.Fprintf(b, "<tr><td>%s</td><td><a href=\"%s\">%s</a></td><td>%s</td><td>%s</td></tr>\n",
+			it.Kind, stdlib_html.EscapeString(reviewItemURL(it)), stdlib_html.EscapeString(it.Keys[0]),
+			stdlib_html.EscapeString(reviewItemOther(it)), stdlib_html.EscapeString(Library.EntryFieldValueity(it.Keys[0], TitleField)))
+	}
+	b.WriteString("</table>\n")
+	return ""
+}
+
+func (s *TReviewServer) reviewPage(r *http.Request, b *strings.Builder) string {
+	it, pending := reviewItem(r.URL.Query())
+	if !pending {
+		s.notice = "That item no longer needs a decision."
+		return "/"
+	}
+	s.writeNotice(b)
+
+	columns, rows := it.columns()
+	b.WriteString("<div class=\"records\">\n")
+	if it.Kind == HomeworkDuplicate {
+		s.writeLibraryRecord(b, it.Keys[0])
+		s.writeLibraryRecord(b, it.Keys[1])
+	} else {
+		s.writeLibraryRecord(b, it.Keys[0])
+		writeDblpRecord(b, it.Value)
+	}
+	b.WriteString("</div>\n")
+
+	fmt.Fprintf(b, "<h2>Fields</h2>\n<table class=\"fields\">\n<tr><th></th><th>%s</th><th>%s</th></tr>\n",
+		stdlib_html.EscapeString(columns[0].Title), stdlib_html.EscapeString(columns[1].Title))
+	for _, row := range rows {
+		class := ""
+		if columns[0].Fields[row] != columns[1].Fields[row] {
+			class = " class=\"diff\""
+		}
+		fmt.Fprintf(b, "<tr%s><td class=\"name\">%s</td><td>%s</td><td>%s</td></tr>\n", class,
+			stdlib_html.EscapeString(row), stdlib_html.EscapeString(columns[0].Fields[row]), stdlib_html.EscapeString(columns[1].Fields[row]))
+	}
+	b.WriteString("</table>\n")
+
+	b.WriteString("<form class=\"decide\" method=\"post\" action=\"/decide\">\n")
+	for _, field := range [][2]string{{"kind", it.Kind}, {"key", it.Keys[0]}, {"other", reviewItemOther(it)}, {"token", s.token}} {
+		fmt.Fprintf(b, "<input type=\"hidden\" name=\"%s\" value=\"%s\">\n", field[0], stdlib_html.EscapeString(field[1]))
+	}
+	var decisions [][2]string
+	if it.Kind == HomeworkDuplicate {
+		decisions = [][2]string{
+			{ReviewMerge, "Merge right into left"},
+			{ReviewMergeReverse, "Merge left into right"},
+			{ReviewNonDouble, "Different publications"},
+		}
+	} else {
+		decisions = [][2]string{
+			{ReviewLink, "Link to this DBLP entry"},
+			{ReviewNonDouble, "Not this DBLP entry"},
+			{ReviewWaive, "Waive DBLP for this entry"},
+		}
+	}
+	for _, d := range decisions {
+		fmt.Fprintf(b, "<button name=\"decision\" value=\"%s\">%s</button>\n", d[0], d[1])
+	}
+	if it.Kind == HomeworkDuplicate {
+		if leftYear, rightYear, differ := Library.mergeYearsDiffer(it.Keys[0], it.Keys[1]); differ {
+			fmt.Fprintf(b, "<p><label><input type=\"checkbox\" name=\"years_confirmed\" value=\"1\"> The years differ (%s and %s); merge all the same</label></p>\n",
+				stdlib_html.EscapeString(leftYear), stdlib_html.EscapeString(rightYear))
+		}
+	}
+	b.WriteString("</form>\n")
+	if next := s.nextItem(it); next != nil {
+		fmt.Fprintf(b, "<p><a href=\"%s\">Skip</a></p>\n", stdlib_html.EscapeString(reviewItemURL(next)))
+	}
+	return ""
+}
+
+// writeLibraryRecord renders a library entry with links to its PDF and DOI.
+func (s *TReviewServer) writeLibraryRecord(b *strings.Builder, key string) {
+	fmt.Fprintf(b, "<div class=\"record\">\n<h3>%s</h3>\n<p>%s</p>\n", stdlib_html.EscapeString(key), Library.renderAsHTML(key))
+	if path := Library.queryColumnValue(key, "pdf"); path != "" {
+		s.pdfPaths[key] = path
+		fmt.Fprintf(b, "<p><a href=\"/pdf/%s\">PDF</a></p>\n", url.PathEscape(key))
+	}
+	if doi := Library.EntryFieldValueity(key, "doi"); doi != "" {
+		fmt.Fprintf(b, "<p><a href=\"https://doi.org/%s\">doi:%s</a></p>\n", stdlib_html.EscapeString(doi), stdlib_html.EscapeString(doi))
+	}
+	b.WriteString("</div>\n")
+}
+
+// writeDblpRecord renders a DBLP entry as displayDblpEntry does, with links to
+// its electronic editions.
+func writeDblpRecord(b *strings.Builder, dblpKey string) {
+	fmt.Fprintf(b, "<div class=\"record\">\n<h3>dblp: %s</h3>\n", stdlib_html.EscapeString(dblpKey))
+	entry := dblpEntryFromFile(dblpKey)
+	if entry == nil {
+		b.WriteString("<p>Not found in the local DBLP file store.</p>\n</div>\n")
+		return
+	}
+	var parent *TBibTeXEntry
+	if crossref := entry.Fields["crossref"]; crossref != "" {
+		parent = dblpEntryFromFile(crossref)
+	}
+	fmt.Fprintf(b, "<p>%s</p>\n", Library.renderEntryAsHTML(entry, parent))
+	for _, ee := range strings.Fields(entry.Fields["ee"]) {
+		fmt.Fprintf(b, "<p><a href=\"%s\">%s</a></p>\n", stdlib_html.EscapeString(ee), stdlib_html.EscapeString(ee))
+	}
+	fmt.Fprintf(b, "<p><a href=\"https://dblp.org/rec/%s\">dblp.org</a></p>\n</div>\n", stdlib_html.EscapeString(dblpKey))
+}
+
+func (s *TReviewServer) writeNotice(b *strings.Builder) {
+	if s.notice != "" {
+		fmt.Fprintf(b, "<p class=\"notice\">%s</p>\n", stdlib_html.EscapeString(s.notice))
+		s.notice = ""
+	}
+}
+
+// sameOrigin reports whether a browser request comes from the review pages
+// themselves: an Origin or Referer header, when sent, must name this server.
+func sameOrigin(r *http.Request) bool {
+	for _, header := range []string{"Origin", "Referer"} {
+		if value := r.Header.Get(header); value != "" {
+			u, err := url.Parse(value)
+			return err == nil && u.Host == r.Host
+		}
+	}
+	return true
+}
+
+func (s *TReviewServer) decide(r *http.Request, b *strings.Builder) string {
+	if err := r.ParseForm(); err != nil {
+		s.notice = err.Error()
+		return "/"
+	}
+	// Any web page open in the browser can post to localhost: only accept
+	// decisions from our own form.
+	if !sameOrigin(r) || r.PostForm.Get("token") != s.token {
+		s.notice = "Ignored a decision that did not come from these review pages."
+		return "/"
+	}
+	it, pending := reviewItem(r.PostForm)
+	if !pending {
+		s.notice = "That item no longer needs a decision."
+		return "/"
+	}
+	decision := r.PostForm.Get("decision")
+	Library.ResetQuestionFlag()
+	switch {
+	case it.Kind == HomeworkDuplicate && (decision == ReviewMerge || decision == ReviewMergeReverse):
+		source, target := it.Keys[1], it.Keys[0]
+		if decision == ReviewMergeReverse {
+			source, target = target, source
+		}
+		yearsConfirmed := r.PostForm.Get("years_confirmed") != ""
+		if !Library.MergeDecidedEntries(source, target, func(string, string) bool { return yearsConfirmed }) {
+			s.notice = "The years differ: tick the box to merge these entries all the same."
+			return reviewItemURL(it)
+		}
+		s.notice = fmt.Sprintf("Merged %s into %s.", source, target)
+	case it.Kind == HomeworkDuplicate && decision == ReviewNonDouble:
+		Library.AddNonDoubleEntries(it.Keys[0], it.Keys[1])
+		s.notice = fmt.Sprintf("Recorded %s and %s as different publications.", it.Keys[0], it.Keys[1])
+	case it.Kind == HomeworkDblp && decision == ReviewLink:
+		Library.AssociateDblpKey(it.Keys[0], it.Value)
+		doAllChecks(Library.MapEntryKey(it.Keys[0]))
+		s.notice = fmt.Sprintf("Linked %s to %s.", it.Keys[0], KeyForDBLP(it.Value))
+	case it.Kind == HomeworkDblp && decision == ReviewNonDouble:
+		Library.AddNonDoubleEntries(it.Keys[0], KeyForDBLP(it.Value))
+		s.notice = fmt.Sprintf("Recorded %s as not being %s.", it.Keys[0], KeyForDBLP(it.Value))
+	case it.Kind == HomeworkDblp && decision == ReviewWaive:
+		Library.DblpWaived.Set(it.Keys[0], true)
+		for _, queued := range s.items {
+			if queued.Kind == HomeworkDblp && queued.Keys[0] == it.Keys[0] {
+				Library.AddNonDoubleEntries(it.Keys[0], KeyForDBLP(queued.Value))
+			}
+		}
+		s.notice = fmt.Sprintf("Waived DBLP for %s.", it.Keys[0])
+	default:
+		s.notice = fmt.Sprintf("Unknown decision %q.", decision)
+		return reviewItemURL(it)
+	}
+	Library.Progress(ProgressReviewDecision, s.notice)
+	flushWorkingDbToHome()
+
+	// The next item is looked up before pruning, so it follows the decided one.
+	var next *THomeworkItem
+	for candidate := s.nextItem(it); candidate != nil; candidate = s.nextItem(candidate) {
+		if candidate.pending() {
+			next = candidate
+			break
+		}
+	}
+	s.prune()
+	if next == nil {
+		return "/"
+	}
+	return reviewItemURL(next)
+}
+
+func (s *TReviewServer) servePDF(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	path := ""
+	if !s.run(func() { path = s.pdfPaths[key] }) || path == "" {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// ReviewLibrary opens the library for updating and serves the review pages on
+// addr until Ctrl-C, running all library work on the calling goroutine.
+func ReviewLibrary(addr string) error {
+	if !openLibraryToUpdate() {
+		return fmt.Errorf("the library could not be opened")
+	}
+	Library.ReadKeyNonDoublesFile()
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	s := &TReviewServer{
+		items:    append(collectDuplicateItems(), collectDblpCandidateItems()...),
+		work:     make(chan func()),
+		stopping: make(chan struct{}),
+		pdfPaths: map[string]string{},
+		token:    hex.EncodeToString(token),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /{$}", s.page(s.overviewPage))
+	mux.Handle("GET /review", s.page(s.reviewPage))
+	mux.Handle("POST /decide", s.page(s.decide))
+	mux.HandleFunc("GET /pdf/{key}", s.servePDF)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	failed := make(chan error, 1)
+	go func() { failed <- server.ListenAndServe() }()
+	Library.Progress(ProgressReviewServerListening, addr, len(s.items))
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case job := <-s.work:
+			job()
+		case err := <-failed:
+			close(s.stopping)
+			return err
+		case <-ticker.C:
+			if !Library.QuitWasRequested() {
+				continue
+			}
+			close(s.stopping)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := server.Shutdown(ctx)
+			cancel()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}
+	}
+}
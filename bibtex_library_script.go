@@ -6,11 +6,12 @@
  *   - bibtex_library_script
  *
  * This module implements the parser and evaluator for .script files, which
- * specify group assignment rules for BibTeX library entries.
+ * specify group assignment and field, flag, metadata and preferred-alias
+ * editing rules for BibTeX library entries.
  *
  * Creator: Henderik A. Proper (erikproper@gmail.com)
  *
- * Version of: 29.05.2026
+ * Version of: 19.10.2026
  *
  */
 
@@ -19,6 +20,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -40,6 +42,9 @@ const (
 	stokLT          // <
 	stokLTE         // <=
 	stokEQ          // = (numeric field comparison)
+	stokLParen      // (
+	stokRParen      // )
+	stokRegex       // /regular expression/
 )
 
 type scriptToken struct {
@@ -126,6 +131,32 @@ func (lx *scriptLexer) scan() scriptToken {
 	case '=':
 		lx.pos++
 		return scriptToken{kind: stokEQ, val: "=", line: line}
+	case '(':
+		lx.pos++
+		return scriptToken{kind: stokLParen, val: "(", line: line}
+	case ')':
+		lx.pos++
+		return scriptToken{kind: stokRParen, val: ")", line: line}
+	case '/':
+		// A regular expression ends at the next unescaped slash on the same line;
+		// \/ stands for a literal slash, other escapes are passed to the regexp.
+		lx.pos++
+		var buf strings.Builder
+		for lx.pos < len(lx.src) && lx.src[lx.pos] != '\n' {
+			c := lx.src[lx.pos]
+			if c == '/' {
+				lx.pos++
+				break
+			}
+			if c == '\\' && lx.pos+1 < len(lx.src) && lx.src[lx.pos+1] == '/' {
+				buf.WriteRune('/')
+				lx.pos += 2
+				continue
+			}
+			buf.WriteRune(c)
+			lx.pos++
+		}
+		return scriptToken{kind: stokRegex, val: buf.String(), line: line}
 	case '"':
 		lx.pos++
 		var buf strings.Builder
@@ -180,6 +211,15 @@ type sBlock           struct{ stmts []scriptStmt }
 type sAddToGroup      struct{ group string }
 type sRemoveFromGroup struct{ group string }
 type sIssueWarning    struct{ msg string }
+type sSetField        struct{ field string; value sValue }
+type sClearField      struct{ field string }
+type sAppendToField   struct{ field, sep string; value sValue }
+type sReplaceInField  struct{ field string; re *regexp.Regexp; repl string }
+type sSetFlag         struct{ flag string }
+type sClearFlag       struct{ flag string }
+type sSetMetadata     struct{ prop string; value sValue }
+type sClearMetadata   struct{ prop string }
+type sSetAlias        struct{ value sValue }
 type sIf              struct {
 	cond  scriptCond
 	then  scriptStmt
@@ -191,10 +231,23 @@ type sElif struct {
 	body scriptStmt
 }
 
+// sValue is the right-hand side of an editing action: a quoted string, or
+// (when field is set) the current value of another field of the same entry.
+type sValue struct{ text, field string }
+
 func (*sBlock) isStmt()           {}
 func (*sAddToGroup) isStmt()      {}
 func (*sRemoveFromGroup) isStmt() {}
 func (*sIssueWarning) isStmt()    {}
+func (*sSetField) isStmt()        {}
+func (*sClearField) isStmt()      {}
+func (*sAppendToField) isStmt()   {}
+func (*sReplaceInField) isStmt()  {}
+func (*sSetFlag) isStmt()         {}
+func (*sClearFlag) isStmt()       {}
+func (*sSetMetadata) isStmt()     {}
+func (*sClearMetadata) isStmt()   {}
+func (*sSetAlias) isStmt()        {}
 func (*sIf) isStmt()              {}
 
 type scriptCond interface{ isCond() }
@@ -208,6 +261,19 @@ type sCondFieldIncludes    struct{ field, value string }
 type sCondFieldEquals      struct{ field, value string }
 type sCondFieldEmpty       struct{ field string; negated bool }
 type sCondFieldNumCmp      struct{ field, op string; value int }
+type sCondOr               struct{ left, right scriptCond }
+type sCondNot              struct{ inner scriptCond }
+type sCondFieldMatches     struct{ field string; re *regexp.Regexp } // field "" = any field
+type sCondKeyMatches       struct{ re *regexp.Regexp }
+type sCondGroupMatches     struct{ re *regexp.Regexp }
+type sCondHasPdf           struct{}
+type sCondHasDblp          struct{}
+type sCondHasFlag          struct{ flag string }
+type sCondHasWarning       struct{ re *regexp.Regexp } // nil = any warning
+type sCondContributor      struct {
+	ref  string // EP id, ORCID or name
+	role string // "author", "editor", or "" for either
+}
 
 func (*sCondAnd) isCond()              {}
 func (*sCondEntryType) isCond()        {}
@@ -218,13 +284,38 @@ func (*sCondFieldIncludes) isCond()    {}
 func (*sCondFieldEquals) isCond()      {}
 func (*sCondFieldEmpty) isCond()       {}
 func (*sCondFieldNumCmp) isCond()      {}
+func (*sCondOr) isCond()               {}
+func (*sCondNot) isCond()              {}
+func (*sCondFieldMatches) isCond()     {}
+func (*sCondKeyMatches) isCond()       {}
+func (*sCondGroupMatches) isCond()     {}
+func (*sCondHasPdf) isCond()           {}
+func (*sCondHasDblp) isCond()          {}
+func (*sCondHasFlag) isCond()          {}
+func (*sCondHasWarning) isCond()       {}
+func (*sCondContributor) isCond()      {}
 
 type scriptProgram struct {
 	groupSets map[string][]string
 	rules     []*sIf
-	changes   int // group assignments added or removed during this run
+	lint      []*scriptLintRule
+	changes   int // group assignments and entry edits made during this run
 }
 
+// scriptLintRule is a named house-style check from a script's lint section.
+// Findings are recorded in entry_warnings by CheckLintRules.
+type scriptLintRule struct {
+	name     string
+	severity string // one of lintSeverities
+	cond     scriptCond
+	message  string
+}
+
+var (
+	lintSeverities      = []string{"error", "warning", "info"}
+	lintRuleNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
 // ─── Parser ─────────────────────────────────────────────────────────────────────
 
 type scriptParser struct {
@@ -263,6 +354,17 @@ func (p *scriptParser) eatString() (string, bool) {
 	return t.val, true
 }
 
+func (p *scriptParser) eatNumber() (int, bool) {
+	t := p.lx.peek()
+	if t.kind != stokNumber {
+		p.errorf(t.line, "expected number, got %q", t.val)
+		return 0, false
+	}
+	p.lx.next()
+	n, _ := strconv.Atoi(t.val)
+	return n, true
+}
+
 func (p *scriptParser) optSemi() {
 	if p.lx.peek().kind == stokSemi {
 		p.lx.next()
@@ -274,6 +376,8 @@ func (p *scriptParser) parseProgram() *scriptProgram {
 	for p.lx.peek().kind != stokEOF {
 		if p.is("define") {
 			p.parseDefine(prog)
+		} else if p.is("lint") {
+			p.parseLint(prog)
 		} else if p.is("if") {
 			if rule := p.parseIf(); rule != nil {
 				prog.rules = append(prog.rules, rule)
@@ -306,6 +410,70 @@ func (p *scriptParser) parseDefine(prog *scriptProgram) {
 	prog.groupSets[name] = groups
 }
 
+// parseLint parses a lint section:
+//
+//	lint begin
+//	  rule "<name>" [is a[n] error|warning|info] if <cond> then report "<message>";
+//	  ...
+//	end
+//
+// The severity defaults to warning.
+func (p *scriptParser) parseLint(prog *scriptProgram) {
+	p.eat("lint")
+	p.eat("begin")
+	for !p.is("end") && p.lx.peek().kind != stokEOF {
+		if p.is("rule") {
+			p.parseLintRule(prog)
+		} else {
+			t := p.lx.next()
+			p.errorf(t.line, "expected \"rule\" in lint section, got %q", t.val)
+		}
+		p.optSemi()
+	}
+	p.eat("end")
+}
+
+func (p *scriptParser) parseLintRule(prog *scriptProgram) {
+	p.eat("rule")
+	t := p.lx.peek()
+	name, ok := p.eatString()
+	if ok && !lintRuleNamePattern.MatchString(name) {
+		p.errorf(t.line, "invalid lint rule name %q (use letters, digits, '-', '_' and '.')", name)
+		ok = false
+	}
+	for _, rule := range prog.lint {
+		if ok && rule.name == name {
+			p.errorf(t.line, "duplicate lint rule %q", name)
+			ok = false
+		}
+	}
+	severity := "warning"
+	if p.is("is") {
+		p.lx.next()
+		if p.is("a") || p.is("an") {
+			p.lx.next()
+		}
+		s := p.lx.next()
+		severity = strings.ToLower(s.val)
+		known := false
+		for _, candidate := range lintSeverities {
+			known = known || candidate == severity
+		}
+		if !known {
+			p.errorf(s.line, "unknown lint severity %q (known: %s)", s.val, strings.Join(lintSeverities, ", "))
+			ok = false
+		}
+	}
+	p.eat("if")
+	cond := p.parseCond()
+	p.eat("then")
+	p.eat("report")
+	message, mok := p.eatString()
+	if ok && mok && cond != nil {
+		prog.lint = append(prog.lint, &scriptLintRule{name: name, severity: severity, cond: cond, message: message})
+	}
+}
+
 func (p *scriptParser) parseIf() *sIf {
 	p.eat("if")
 	cond := p.parseCond()
@@ -342,6 +510,14 @@ func (p *scriptParser) parseStmt() scriptStmt {
 		return p.parseRemove()
 	case p.is("issue"):
 		return p.parseIssue()
+	case p.is("set"):
+		return p.parseSet()
+	case p.is("clear"):
+		return p.parseClear()
+	case p.is("append"):
+		return p.parseAppend()
+	case p.is("replace"):
+		return p.parseReplace()
 	default:
 		p.errorf(t.line, "expected statement, got %q", t.val)
 		p.lx.next() // consume to prevent infinite loop in block parsing
@@ -390,21 +566,247 @@ func (p *scriptParser) parseIssue() scriptStmt {
 	return &sIssueWarning{msg}
 }
 
+// scriptProtectedFields may not be edited by field actions: the entry type is
+// structural, and the preferred alias has its own action so that it is
+// validated and registered as a key hint.
+var scriptProtectedFields = TStringSetNew()
+
+func init() {
+	scriptProtectedFields.Add(EntryTypeField, PreferredAliasField)
+}
+
+// parseFieldRef parses "[the] <name> field" and returns the lower-cased name.
+// The article is optional so that parseSet and parseClear, which consume it
+// before deciding between a field, a flag or metadata, can share this.
+func (p *scriptParser) parseFieldRef() (string, bool) {
+	if p.is("the") {
+		p.lx.next()
+	}
+	t := p.lx.peek()
+	if t.kind != stokIdent {
+		p.errorf(t.line, "expected field name, got %q", t.val)
+		return "", false
+	}
+	field := strings.ToLower(p.lx.next().val)
+	p.eat("field")
+	return field, true
+}
+
+// parseEditedField parses a field reference that is the target of an editing
+// action, rejecting fields the script may not touch.
+func (p *scriptParser) parseEditedField() (string, bool) {
+	line := p.lx.peek().line
+	field, ok := p.parseFieldRef()
+	if ok && scriptProtectedFields.Contains(field) {
+		p.errorf(line, "the %s field cannot be edited by a script", field)
+		return "", false
+	}
+	return field, ok
+}
+
+// parseValue parses a quoted string or "the <name> field".
+func (p *scriptParser) parseValue() (sValue, bool) {
+	if p.lx.peek().kind == stokString {
+		return sValue{text: p.lx.next().val}, true
+	}
+	field, ok := p.parseFieldRef()
+	return sValue{field: field}, ok
+}
+
+// parseFlag parses the quoted name of a flag, which must be one of the flags
+// the library persists.
+func (p *scriptParser) parseFlag() (string, bool) {
+	t := p.lx.peek()
+	flag, ok := p.eatString()
+	if !ok {
+		return "", false
+	}
+	if isEntryFlagProperty(flag) {
+		return flag, true
+	}
+	p.errorf(t.line, "unknown flag %q (known: %s, %s<rule>)", flag, strings.Join(knownEntryFlags(), ", "), FlagLintWaivedPrefix)
+	return "", false
+}
+
+// parseMetadataProp parses the quoted name of a metadata property. Flags are
+// stored alongside metadata but must be edited through the flag actions.
+func (p *scriptParser) parseMetadataProp() (string, bool) {
+	t := p.lx.peek()
+	prop, ok := p.eatString()
+	if !ok {
+		return "", false
+	}
+	if isEntryFlagProperty(prop) {
+		p.errorf(t.line, "%q is a flag; use \"set the flag\" or \"clear the flag\"", prop)
+		return "", false
+	}
+	return prop, true
+}
+
+func (p *scriptParser) parseSet() scriptStmt {
+	p.eat("set")
+	p.eat("the")
+	switch {
+	case p.is("flag"):
+		// set the flag "<name>"
+		p.lx.next()
+		if flag, ok := p.parseFlag(); ok {
+			return &sSetFlag{flag}
+		}
+	case p.is("metadata"):
+		// set the metadata "<property>" to <value>
+		p.lx.next()
+		prop, ok := p.parseMetadataProp()
+		p.eat("to")
+		if value, vok := p.parseValue(); ok && vok {
+			return &sSetMetadata{prop, value}
+		}
+	case p.is("preferred"):
+		// set the preferred alias to <value>
+		p.lx.next()
+		p.eat("alias")
+		p.eat("to")
+		if value, ok := p.parseValue(); ok {
+			return &sSetAlias{value}
+		}
+	default:
+		// set the <name> field to <value>
+		field, ok := p.parseEditedField()
+		p.eat("to")
+		if value, vok := p.parseValue(); ok && vok {
+			return &sSetField{field, value}
+		}
+	}
+	return nil
+}
+
+func (p *scriptParser) parseClear() scriptStmt {
+	p.eat("clear")
+	p.eat("the")
+	switch {
+	case p.is("flag"):
+		// clear the flag "<name>"
+		p.lx.next()
+		if flag, ok := p.parseFlag(); ok {
+			return &sClearFlag{flag}
+		}
+	case p.is("metadata"):
+		// clear the metadata "<property>"
+		p.lx.next()
+		if prop, ok := p.parseMetadataProp(); ok {
+			return &sClearMetadata{prop}
+		}
+	default:
+		// clear the <name> field
+		if field, ok := p.parseEditedField(); ok {
+			return &sClearField{field}
+		}
+	}
+	return nil
+}
+
+// parseAppend parses: append <value> to the <name> field [separated by "<sep>"]
+// The separator is only inserted when the field already has a value.
+func (p *scriptParser) parseAppend() scriptStmt {
+	p.eat("append")
+	value, vok := p.parseValue()
+	p.eat("to")
+	field, ok := p.parseEditedField()
+	sep := ""
+	if p.is("separated") {
+		p.lx.next()
+		p.eat("by")
+		sep, _ = p.eatString()
+	}
+	if !ok || !vok {
+		return nil
+	}
+	return &sAppendToField{field, sep, value}
+}
+
+// parseReplace parses: replace /<regexp>/ with "<replacement>" in the <name> field
+// The replacement may refer to capture groups as $1, ${name}, etc.
+func (p *scriptParser) parseReplace() scriptStmt {
+	p.eat("replace")
+	re, _ := p.eatPattern()
+	p.eat("with")
+	repl, _ := p.eatString()
+	p.eat("in")
+	field, ok := p.parseEditedField()
+	if !ok || re == nil {
+		return nil
+	}
+	return &sReplaceInField{field, re, repl}
+}
+
+// parseCond parses a condition:
+//
+//	cond  := and { "or" and }
+//	and   := unary { "and" unary }
+//	unary := "not" unary | "(" cond ")" | simple
 func (p *scriptParser) parseCond() scriptCond {
-	left := p.parseSimpleCond()
+	left := p.parseAndCond()
+	for p.is("or") {
+		p.lx.next()
+		right := p.parseAndCond()
+		left = &sCondOr{left, right}
+	}
+	return left
+}
+
+func (p *scriptParser) parseAndCond() scriptCond {
+	left := p.parseUnaryCond()
 	for p.is("and") {
 		p.lx.next()
-		right := p.parseSimpleCond()
+		right := p.parseUnaryCond()
 		left = &sCondAnd{left, right}
 	}
 	return left
 }
 
+func (p *scriptParser) parseUnaryCond() scriptCond {
+	if p.is("not") {
+		p.lx.next()
+		return &sCondNot{p.parseUnaryCond()}
+	}
+	if p.lx.peek().kind == stokLParen {
+		p.lx.next()
+		inner := p.parseCond()
+		if t := p.lx.peek(); t.kind == stokRParen {
+			p.lx.next()
+		} else {
+			p.errorf(t.line, "expected \")\", got %q", t.val)
+		}
+		return inner
+	}
+	return p.parseSimpleCond()
+}
+
+// eatPattern consumes a /regular expression/ or a quoted string holding one.
+func (p *scriptParser) eatPattern() (*regexp.Regexp, bool) {
+	t := p.lx.peek()
+	if t.kind != stokRegex && t.kind != stokString {
+		p.errorf(t.line, "expected /regular expression/, got %q", t.val)
+		return nil, false
+	}
+	p.lx.next()
+	re, err := regexp.Compile(t.val)
+	if err != nil {
+		p.errorf(t.line, "invalid regular expression %q: %s", t.val, err)
+		return nil, false
+	}
+	return re, true
+}
+
 func (p *scriptParser) parseSimpleCond() scriptCond {
-	// Allow "is ..." as shorthand for "the entry is ..." in continuation clauses.
+	// Allow "is ..." and "has ..." as shorthand for "the entry is/has ..." in
+	// continuation clauses.
 	if p.is("is") {
 		return p.parseEntryCond()
 	}
+	if p.is("has") {
+		return p.parseHasCond()
+	}
 	if !p.eat("the") {
 		return nil
 	}
@@ -415,6 +817,9 @@ func (p *scriptParser) parseSimpleCond() scriptCond {
 	}
 	if strings.EqualFold(t.val, "entry") {
 		p.lx.next()
+		if p.is("has") {
+			return p.parseHasCond()
+		}
 		return p.parseEntryCond()
 	}
 	field := strings.ToLower(p.lx.next().val)
@@ -422,6 +827,57 @@ func (p *scriptParser) parseSimpleCond() scriptCond {
 	return p.parseFieldCond(field)
 }
 
+// parseHasCond parses the conditions on what an entry has:
+//
+//	has [a] pdf
+//	has [a] dblp [key]
+//	has [a] flag "<name>"
+//	has [a] warning ["<text>" | /regex/]
+//	has [a] contributor <EP id> | "<EP id, ORCID or name>"
+func (p *scriptParser) parseHasCond() scriptCond {
+	p.eat("has")
+	if p.is("a") || p.is("an") {
+		p.lx.next()
+	}
+	t := p.lx.peek()
+	switch {
+	case p.is("pdf"):
+		p.lx.next()
+		return &sCondHasPdf{}
+	case p.is("dblp"):
+		p.lx.next()
+		if p.is("key") {
+			p.lx.next()
+		}
+		return &sCondHasDblp{}
+	case p.is("flag"):
+		p.lx.next()
+		flag, _ := p.eatString()
+		return &sCondHasFlag{flag: flag}
+	case p.is("warning"):
+		p.lx.next()
+		switch p.lx.peek().kind {
+		case stokString:
+			return &sCondHasWarning{re: regexp.MustCompile(regexp.QuoteMeta(p.lx.next().val))}
+		case stokRegex:
+			re, _ := p.eatPattern()
+			return &sCondHasWarning{re: re}
+		}
+		return &sCondHasWarning{}
+	case p.is("contributor"):
+		p.lx.next()
+		ref := p.lx.peek()
+		if ref.kind != stokString && ref.kind != stokIdent {
+			p.errorf(ref.line, "expected a contributor id, ORCID or name, got %q", ref.val)
+			return nil
+		}
+		p.lx.next()
+		return &sCondContributor{ref: ref.val}
+	}
+	p.errorf(t.line, "unexpected token after \"has\": %q", t.val)
+	return nil
+}
+
 func (p *scriptParser) parseEntryCond() scriptCond {
 	if !p.eat("is") {
 		return nil
@@ -517,6 +973,9 @@ func (p *scriptParser) parseFieldCond(field string) scriptCond {
 	if p.is("includes") {
 		p.lx.next()
 		val, _ := p.eatString()
+		if field == "author" || field == "editor" {
+			return &sCondContributor{ref: val, role: field}
+		}
 		return &sCondFieldIncludes{field: field, value: val}
 	}
 	if p.is("equals") {
@@ -524,6 +983,13 @@ func (p *scriptParser) parseFieldCond(field string) scriptCond {
 		val, _ := p.eatString()
 		return &sCondFieldEquals{field: field, value: val}
 	}
+	if p.is("matches") {
+		p.lx.next()
+		if re, ok := p.eatPattern(); ok {
+			return &sCondFieldMatches{field: field, re: re}
+		}
+		return nil
+	}
 	if p.is("is") {
 		p.lx.next()
 		negated := false
@@ -531,6 +997,24 @@ func (p *scriptParser) parseFieldCond(field string) scriptCond {
 			p.lx.next()
 			negated = true
 		}
+		if p.is("between") {
+			// the year field is [not] between 2010 and 2020 (inclusive)
+			p.lx.next()
+			low, lowOK := p.eatNumber()
+			p.eat("and")
+			high, highOK := p.eatNumber()
+			if !lowOK || !highOK {
+				return nil
+			}
+			var cond scriptCond = &sCondAnd{
+				&sCondFieldNumCmp{field: field, op: ">=", value: low},
+				&sCondFieldNumCmp{field: field, op: "<=", value: high},
+			}
+			if negated {
+				cond = &sCondNot{cond}
+			}
+			return cond
+		}
 		p.eat("empty")
 		return &sCondFieldEmpty{field: field, negated: negated}
 	}
@@ -538,13 +1022,10 @@ func (p *scriptParser) parseFieldCond(field string) scriptCond {
 	switch t.kind {
 	case stokGT, stokGTE, stokLT, stokLTE, stokEQ:
 		op := p.lx.next().val
-		numTok := p.lx.peek()
-		if numTok.kind != stokNumber {
-			p.errorf(numTok.line, "expected number after %q, got %q", op, numTok.val)
+		n, ok := p.eatNumber()
+		if !ok {
 			return nil
 		}
-		p.lx.next()
-		n, _ := strconv.Atoi(numTok.val)
 		return &sCondFieldNumCmp{field: field, op: op, value: n}
 	default:
 		p.errorf(t.line, "expected field condition operator, got %q", t.val)
@@ -588,6 +1069,45 @@ func scriptNameInField(l *TBibTeXLibrary, fieldVal, searchName string) bool {
 	return false
 }
 
+// contributorRefIDs resolves ref — a contributor (EP) id, an ORCID, or a name —
+// to the contributor IDs it may denote. A name can be ambiguous; every
+// candidate is returned.
+func contributorRefIDs(l *TBibTeXLibrary, ref string) []string {
+	var id string
+	if bibQueryRow(`SELECT id FROM contributors WHERE id = ?`, ref).Scan(&id) == nil {
+		return []string{id}
+	}
+	if bibQueryRow(`SELECT contributor_id FROM contributor_orcids WHERE orcid = ?`, ref).Scan(&id) == nil {
+		return []string{id}
+	}
+	return contributorIDCandidates(l, ref)
+}
+
+// contributorInRole reports whether one of ids (the contributors denoted by
+// ref) is linked to key in role, or, for a role children inherit and do not
+// fill themselves, to key's crossref parent. When neither is linked in that
+// role, or ref resolves to no contributor at all, it falls back to matching ref
+// as a name in the (inherited) field.
+func contributorInRole(l *TBibTeXLibrary, key, role, ref string, ids []string) bool {
+	linked := contributorRoleIDs(key, role)
+	if len(linked) == 0 && BibTeXMustInheritFields.Contains(role) && l.EntryFieldValueity(key, role) == "" {
+		if parent := l.EntryFieldValueity(key, "crossref"); parent != "" {
+			linked = contributorRoleIDs(parent, role)
+		}
+	}
+	if len(ids) == 0 || len(linked) == 0 {
+		return scriptNameInField(l, scriptFieldValue(l, key, role), ref)
+	}
+	for _, id := range linked {
+		for _, candidate := range ids {
+			if id == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func scriptEvalCond(l *TBibTeXLibrary, key string, prog *scriptProgram, cond scriptCond) bool {
 	if cond == nil {
 		return false
@@ -597,9 +1117,13 @@ func scriptEvalCond(l *TBibTeXLibrary, key string, prog *scriptProgram, cond scr
 		return scriptEvalCond(l, key, prog, c.left) && scriptEvalCond(l, key, prog, c.right)
 	case *sCondEntryType:
 		return strings.ToLower(l.EntryFieldValueity(key, EntryTypeField)) == c.entryType
+	case *sCondOr:
+		return scriptEvalCond(l, key, prog, c.left) || scriptEvalCond(l, key, prog, c.right)
+	case *sCondNot:
+		return c.inner != nil && !scriptEvalCond(l, key, prog, c.inner)
 	case *sCondGroupSetAny:
 		for _, g := range prog.groupSets[c.set] {
-			if l.GroupEntries[g].Set().Contains(key) {
+			if l.EntryInGroup(g, key) {
 				return !c.negated
 			}
 		}
@@ -607,7 +1131,7 @@ func scriptEvalCond(l *TBibTeXLibrary, key string, prog *scriptProgram, cond scr
 	case *sCondGroupSetMultiple:
 		count := 0
 		for _, g := range prog.groupSets[c.set] {
-			if l.GroupEntries[g].Set().Contains(key) {
+			if l.EntryInGroup(g, key) {
 				count++
 				if count > 1 {
 					return true
@@ -616,13 +1140,60 @@ func scriptEvalCond(l *TBibTeXLibrary, key string, prog *scriptProgram, cond scr
 		}
 		return false
 	case *sCondInGroup:
-		return l.GroupEntries[c.group].Set().Contains(key) != c.negated
-	case *sCondFieldIncludes:
-		val := scriptFieldValue(l, key, c.field)
-		if c.field == "author" || c.field == "editor" {
-			return scriptNameInField(l, val, c.value)
+		return l.EntryInGroup(c.group, key) != c.negated
+	case *sCondFieldMatches:
+		if c.field != "" {
+			return c.re.MatchString(scriptFieldValue(l, key, c.field))
+		}
+		for _, val := range loadEntryFromDb(key).Fields {
+			if c.re.MatchString(val) {
+				return true
+			}
+		}
+		return false
+	case *sCondKeyMatches:
+		return c.re.MatchString(key) || c.re.MatchString(l.PreferredKey(key))
+	case *sCondGroupMatches:
+		for group, members := range l.GroupEntries {
+			if c.re.MatchString(group) && members.Set().Contains(key) {
+				return true
+			}
+		}
+		return false
+	case *sCondHasPdf:
+		return l.PDFFiles[key]
+	case *sCondHasDblp:
+		return scriptFieldValue(l, key, "dblp") != ""
+	case *sCondHasFlag:
+		return l.EntryHasFlag(key, c.flag)
+	case *sCondHasWarning:
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM entry_warnings WHERE key = ?`, key).Scan(&n) //nolint:errcheck
+		if c.re == nil || n == 0 {
+			return n > 0
+		}
+		for _, w := range entryWarningTexts(key) {
+			if c.re.MatchString(w) {
+				return true
+			}
+		}
+		return false
+	case *sCondContributor:
+		// Resolved per entry, as the library changes while a program is reused
+		// (hooks, lint).
+		roles := []string{"author", "editor"}
+		if c.role != "" {
+			roles = []string{c.role}
+		}
+		ids := contributorRefIDs(l, c.ref)
+		for _, role := range roles {
+			if contributorInRole(l, key, role, c.ref, ids) {
+				return true
+			}
 		}
-		return strings.Contains(val, c.value)
+		return false
+	case *sCondFieldIncludes:
+		return strings.Contains(scriptFieldValue(l, key, c.field), c.value)
 	case *sCondFieldEquals:
 		return scriptFieldValue(l, key, c.field) == c.value
 	case *sCondFieldEmpty:
@@ -648,6 +1219,36 @@ func scriptEvalCond(l *TBibTeXLibrary, key string, prog *scriptProgram, cond scr
 	return false
 }
 
+// scriptValue evaluates the right-hand side of an editing action for key.
+func scriptValue(l *TBibTeXLibrary, key string, v sValue) string {
+	if v.field != "" {
+		return scriptFieldValue(l, key, v.field)
+	}
+	return v.text
+}
+
+// scriptSetField writes value to field of key when it differs from the current
+// value, recording the script as the value's source in the field's lineage.
+// An empty value clears the field.
+func scriptSetField(l *TBibTeXLibrary, key string, prog *scriptProgram, field, value string) {
+	if value != "" && (field == "author" || field == "editor") {
+		value = l.normPersonNameField(value)
+	}
+	if l.EntryFieldValueity(key, field) == value {
+		return
+	}
+	l.SetEntryFieldValue(key, field, value)
+	l.setLineage(key, field, value, "script", false)
+	bibEntriesModified = true
+	prog.changes++
+	SpinnerInterrupt()
+	if value == "" {
+		l.Progress("Cleared field %s of %s", field, key)
+	} else {
+		l.Progress("Set field %s of %s to %q", field, key, value)
+	}
+}
+
 func scriptEvalStmt(l *TBibTeXLibrary, key string, prog *scriptProgram, stmt scriptStmt) {
 	if stmt == nil {
 		return
@@ -679,6 +1280,75 @@ func scriptEvalStmt(l *TBibTeXLibrary, key string, prog *scriptProgram, stmt scr
 		}
 	case *sIssueWarning:
 		l.Warning("%s: %s", key, s.msg)
+	case *sSetField:
+		scriptSetField(l, key, prog, s.field, scriptValue(l, key, s.value))
+	case *sClearField:
+		scriptSetField(l, key, prog, s.field, "")
+	case *sAppendToField:
+		addition := scriptValue(l, key, s.value)
+		if addition == "" {
+			return
+		}
+		current := l.EntryFieldValueity(key, s.field)
+		if current != "" {
+			addition = current + s.sep + addition
+		}
+		scriptSetField(l, key, prog, s.field, addition)
+	case *sReplaceInField:
+		if current := l.EntryFieldValueity(key, s.field); current != "" {
+			scriptSetField(l, key, prog, s.field, s.re.ReplaceAllString(current, s.repl))
+		}
+	case *sSetFlag:
+		if !l.EntryHasFlag(key, s.flag) {
+			l.SetEntryFlag(key, s.flag)
+			prog.changes++
+			SpinnerInterrupt()
+			l.Progress("Set flag %s on %s", s.flag, key)
+		}
+	case *sClearFlag:
+		if l.EntryHasFlag(key, s.flag) {
+			l.ClearEntryFlag(key, s.flag)
+			prog.changes++
+			SpinnerInterrupt()
+			l.Progress("Cleared flag %s on %s", s.flag, key)
+		}
+	case *sSetMetadata:
+		value := scriptValue(l, key, s.value)
+		if value == "" {
+			scriptEvalStmt(l, key, prog, &sClearMetadata{s.prop})
+		} else if l.GetMetadata(key, s.prop) != value {
+			l.SetMetadata(key, s.prop, value)
+			prog.changes++
+			SpinnerInterrupt()
+			l.Progress("Set metadata %s of %s to %q", s.prop, key, value)
+		}
+	case *sClearMetadata:
+		if l.HasMetadata(key, s.prop) {
+			l.DeleteMetadata(key, s.prop)
+			prog.changes++
+			SpinnerInterrupt()
+			l.Progress("Cleared metadata %s of %s", s.prop, key)
+		}
+	case *sSetAlias:
+		alias := scriptValue(l, key, s.value)
+		if alias == "" || l.EntryFieldValueity(key, PreferredAliasField) == alias {
+			return
+		}
+		if !validPreferredAlias(alias) {
+			l.Warning("%s: script preferred alias %q has an invalid format", key, alias)
+			return
+		}
+		if target := l.HintToKey.GetValue(alias); target != "" && target != key {
+			l.Warning("%s: script preferred alias %q is already in use by %s", key, alias, target)
+			return
+		}
+		l.SetEntryFieldValue(key, PreferredAliasField, alias)
+		l.setLineage(key, PreferredAliasField, alias, "script", false)
+		l.AddKeyHint(alias, key)
+		bibEntriesModified = true
+		prog.changes++
+		SpinnerInterrupt()
+		l.Progress("Set preferred alias of %s to %s", key, alias)
 	case *sIf:
 		if scriptEvalCond(l, key, prog, s.cond) {
 			scriptEvalStmt(l, key, prog, s.then)
@@ -696,13 +1366,13 @@ func scriptEvalStmt(l *TBibTeXLibrary, key string, prog *scriptProgram, stmt scr
 	}
 }
 
-// ApplyScript parses the script at path and evaluates all rules against every
-// entry in l.
-func (l *TBibTeXLibrary) ApplyScript(path string) {
+// parseScriptFile parses the script at path, reporting read and parse errors.
+// Returns nil when the script cannot be used.
+func (l *TBibTeXLibrary) parseScriptFile(path string) *scriptProgram {
 	src, err := os.ReadFile(path)
 	if err != nil {
 		l.Warning("Cannot read script file %s: %s", path, err)
-		return
+		return nil
 	}
 	parser := &scriptParser{lx: newScriptLexer(string(src))}
 	prog := parser.parseProgram()
@@ -710,6 +1380,43 @@ func (l *TBibTeXLibrary) ApplyScript(path string) {
 		for _, e := range parser.errors {
 			l.Warning("Script error in %s: %s", path, e)
 		}
+		return nil
+	}
+	return prog
+}
+
+var (
+	entryActionsProgram *scriptProgram // parsed entry_actions script; nil when absent or invalid
+	entryActionsLoaded  bool
+)
+
+// entryActions returns the library's entry_actions script, parsing it on first
+// use. Used by the lint checks and the hooks, which evaluate it per entry. The
+// parsed program is shared for the whole run, so its conditions must not cache
+// library state: entries imported or linked after the first hook dispatch have
+// to match just like the others.
+func (l *TBibTeXLibrary) entryActions() *scriptProgram {
+	if !entryActionsLoaded {
+		entryActionsLoaded = true
+		if path := bibTeXFolder + bibTeXBaseName + ScriptFilePath; FileExists(path) {
+			entryActionsProgram = l.parseScriptFile(path)
+		}
+	}
+	return entryActionsProgram
+}
+
+// applyScriptRules evaluates the rules of prog against key.
+func (l *TBibTeXLibrary) applyScriptRules(prog *scriptProgram, key string) {
+	for _, rule := range prog.rules {
+		scriptEvalStmt(l, key, prog, rule)
+	}
+}
+
+// ApplyScript parses the script at path and evaluates all rules against every
+// entry in l. Lint rules are not evaluated here; see CheckLintRules.
+func (l *TBibTeXLibrary) ApplyScript(path string) {
+	prog := l.parseScriptFile(path)
+	if prog == nil {
 		return
 	}
 	total := countBibEntries()
@@ -718,14 +1425,12 @@ func (l *TBibTeXLibrary) ApplyScript(path string) {
 		if ticker.Step() {
 			return false
 		}
-		for _, rule := range prog.rules {
-			scriptEvalStmt(l, key, prog, rule)
-		}
+		l.applyScriptRules(prog, key)
 		return true
 	})
 	ticker.Done()
 	if prog.changes > 0 {
-		l.Progress("Script %s: %d group change(s)", path, prog.changes)
+		l.Progress("Script %s: %d change(s)", path, prog.changes)
 	} else {
 		l.Progress("Script %s: no changes", path)
 	}
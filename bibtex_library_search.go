@@ -0,0 +1,265 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_search
+ *
+ * Full-text search over titles, abstracts and notes (-search "<terms>").
+ *
+ * An SQLite FTS5 virtual table (bib_entries_fts) mirrors the plain-text form of
+ * the searchable fields of bib_entries. It is kept current by the bib_entries
+ * write primitives (upsertBibEntryField, deleteBibEntryField, closeEntry and
+ * deleteBibEntry), and rebuilt wholesale after a re-import of the bib file, a
+ * table import and an undo. Each (entry, field) pair has a fixed rowid derived
+ * from a hash of its key and field name, so that a single field can be replaced
+ * or removed without a scan of the index.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+)
+
+// searchIndexedFields are the bib_entries fields mirrored in bib_entries_fts.
+var searchIndexedFields = []string{
+	TitleField, "abstract", "keywords", "note", "annote", "annotation", "comment"}
+
+func isSearchIndexedField(field string) bool {
+	for _, f := range searchIndexedFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func ensureEntrySearchIndexExists() {
+	tryCreateTableIfNeeded(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS bib_entries_fts USING fts5(
+		  entry_key UNINDEXED,
+		  field UNINDEXED,
+		  value,
+		  tokenize = 'unicode61 remove_diacritics 2'
+		);`)
+}
+
+// entrySearchRowID returns the fixed bib_entries_fts rowid of (key, field).
+func entrySearchRowID(key, field string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key + "\x00" + field))
+	return int64(h.Sum64())
+}
+
+// updateEntrySearchIndex brings the index entry of (key, field) in line with
+// value; an empty value removes it. Fields that are not indexed are ignored.
+func updateEntrySearchIndex(key, field, value string) {
+	if !isSearchIndexedField(field) {
+		return
+	}
+	rowID := entrySearchRowID(key, field)
+	if err := bibExec(`DELETE FROM bib_entries_fts WHERE rowid = ?`, rowID); err != nil {
+		dbInteraction.Warning("bib_entries_fts delete failed for %s.%s: %s", key, field, err)
+		return
+	}
+	if value == "" {
+		return
+	}
+	if err := bibExec(`INSERT INTO bib_entries_fts (rowid, entry_key, field, value) VALUES (?, ?, ?, ?)`,
+		rowID, key, field, texToText(value)); err != nil {
+		dbInteraction.Warning("bib_entries_fts insert failed for %s.%s: %s", key, field, err)
+	}
+}
+
+// deleteEntrySearchIndex removes all index entries of key.
+func deleteEntrySearchIndex(key string) {
+	for _, field := range searchIndexedFields {
+		if err := bibExec(`DELETE FROM bib_entries_fts WHERE rowid = ?`, entrySearchRowID(key, field)); err != nil {
+			dbInteraction.Warning("bib_entries_fts delete failed for %s: %s", key, err)
+			return
+		}
+	}
+}
+
+// rebuildEntrySearchIndex refills bib_entries_fts from bib_entries.
+func rebuildEntrySearchIndex() {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(searchIndexedFields)), ",")
+	args := make([]any, len(searchIndexedFields))
+	for i, f := range searchIndexedFields {
+		args[i] = f
+	}
+	rows, err := db.Query(`SELECT entry_key, field, value FROM bib_entries WHERE field IN (`+placeholders+`)`, args...)
+	if err != nil {
+		dbInteraction.Warning("Could not read bib_entries for the search index: %s", err)
+		return
+	}
+	type kfv struct{ key, field, value string }
+	var entries []kfv
+	for rows.Next() {
+		var e kfv
+		if rows.Scan(&e.key, &e.field, &e.value) == nil && e.value != "" {
+			entries = append(entries, e)
+		}
+	}
+	rows.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		dbInteraction.Warning("Could not rebuild the search index: %s", err)
+		return
+	}
+	if _, err := tx.Exec(`DELETE FROM bib_entries_fts`); err != nil {
+		tx.Rollback()
+		dbInteraction.Warning("Could not clear the search index: %s", err)
+		return
+	}
+	ticker := dbInteraction.NewProgressTicker(ProgressSearchIndexTicker, len(entries))
+	for _, e := range entries {
+		if _, err := tx.Exec(`INSERT INTO bib_entries_fts (rowid, entry_key, field, value) VALUES (?, ?, ?, ?)`,
+			entrySearchRowID(e.key, e.field), e.key, e.field, texToText(e.value)); err != nil {
+			dbInteraction.Warning("bib_entries_fts insert failed for %s.%s: %s", e.key, e.field, err)
+		}
+		ticker.Step()
+	}
+	ticker.Done()
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		dbInteraction.Warning("Could not rebuild the search index: %s", err)
+	}
+}
+
+// entrySearchIndexEmpty reports whether bib_entries_fts holds no rows at all.
+func entrySearchIndexEmpty() bool {
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM (SELECT 1 FROM bib_entries_fts LIMIT 1)`).Scan(&n) //nolint:errcheck
+	return n == 0
+}
+
+// maybeBuildEntrySearchIndex builds the search index of a library that predates
+// it (or whose index was lost), so that -search works without a re-import.
+func maybeBuildEntrySearchIndex() {
+	if entrySearchIndexEmpty() && Library.ValidBibDb() {
+		rebuildEntrySearchIndex()
+	}
+}
+
+// TSearchHit is one matching field of an entry, with its highlighted snippet.
+type TSearchHit struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// TSearchResult groups the matching fields of one entry; Rank is the best
+// (lowest) bm25 score among them.
+type TSearchResult struct {
+	Key   string       `json:"key"`
+	Title string       `json:"title"`
+	Rank  float64      `json:"rank"`
+	Hits  []TSearchHit `json:"hits"`
+}
+
+// quoteSearchTerms turns free text into an FTS5 query of quoted tokens, keeping
+// a trailing * as a prefix marker. It is the fallback for input that is not
+// valid FTS5 syntax, such as titles containing a colon or a hyphen.
+func quoteSearchTerms(terms string) string {
+	var quoted []string
+	for _, token := range strings.Fields(terms) {
+		prefix := strings.HasSuffix(token, "*")
+		token = strings.Trim(token, `*"()`)
+		if token == "" {
+			continue
+		}
+		token = `"` + strings.ReplaceAll(token, `"`, `""`) + `"`
+		if prefix {
+			token += "*"
+		}
+		quoted = append(quoted, token)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// runEntrySearch runs the FTS5 query against bib_entries_fts.
+func runEntrySearch(query string) ([]TSearchResult, error) {
+	rows, err := db.Query(`
+		SELECT entry_key, field, snippet(bib_entries_fts, 2, '[', ']', '…', 12), bm25(bib_entries_fts)
+		  FROM bib_entries_fts
+		 WHERE bib_entries_fts MATCH ?
+		 ORDER BY bm25(bib_entries_fts)`, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []TSearchResult
+	index := map[string]int{}
+	for rows.Next() {
+		var key, field, snippet string
+		var rank float64
+		if err := rows.Scan(&key, &field, &snippet, &rank); err != nil {
+			return nil, err
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(results)
+			index[key] = i
+			results = append(results, TSearchResult{Key: key, Rank: rank})
+		}
+		results[i].Hits = append(results[i].Hits, TSearchHit{Field: field, Snippet: snippet})
+	}
+	return results, rows.Err()
+}
+
+// SearchEntries runs a full-text search and returns the matching entries, best
+// match first. terms is FTS5 query syntax, so "a phrase", prefix* and the
+// AND/OR/NOT operators work as such; input that does not parse as FTS5 is
+// searched for as plain words instead.
+func (l *TBibTeXLibrary) SearchEntries(terms string) ([]TSearchResult, error) {
+	results, err := runEntrySearch(terms)
+	if err != nil {
+		quoted := quoteSearchTerms(terms)
+		if quoted == "" {
+			return nil, err
+		}
+		if results, err = runEntrySearch(quoted); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank < results[j].Rank })
+	for i := range results {
+		results[i].Title = texToText(loadEntryFromDb(results[i].Key).FieldValue(TitleField))
+	}
+	return results, nil
+}
+
+// WriteSearchResults writes results to w as TSV (key, title, snippets) or as JSON.
+func (l *TBibTeXLibrary) WriteSearchResults(w io.Writer, results []TSearchResult, format string) error {
+	if format == "json" {
+		if results == nil {
+			results = []TSearchResult{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	for _, r := range results {
+		snippets := make([]string, len(r.Hits))
+		for i, h := range r.Hits {
+			snippets[i] = h.Field + ": " + h.Snippet
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", r.Key, r.Title, strings.Join(snippets, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,392 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_serve
+ *
+ * Read-only JSON HTTP API over the library (-serve <addr>).
+ *
+ * Endpoints:
+ *   GET /entries[?q=<query>&columns=<list>]   all entries, or those matching a -find_entries query
+ *   GET /entries/{key}                        one entry (key, preferred alias, old key or hint)
+ *   GET /entries/{key}/{bibtex|tex|html|text} a rendering of one entry
+ *   GET /groups                               static and smart groups
+ *   GET /groups/{name}                        the members of one group
+ *   GET /contributors?name=<name|id|ORCID>    contributors matching a reference
+ *   GET /contributors/{id}                    one contributor with its name forms and entries
+ *   GET /aliases/{alias}                      the entry an alias, old key or hint resolves to
+ *   GET /search?q=<terms>                     full-text search, as -search
+ *
+ * Every response carries an ETag derived from the modification times (in
+ * table_modification_times) of the tables it depends on, and a matching
+ * If-None-Match is answered with 304. When another run changes the library, the
+ * in-memory library is reloaded before the next request is answered.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The tables that the responses of the different endpoints depend on.
+var (
+	serveEntryTables = []string{
+		"bib_entries", "bib_groups", "bib_comments", "contributor_roles", "entry_metadata",
+		"entry_warnings", "key_oldies", "key_hints", "entry_doi_aliases"}
+	serveRenderTables = append([]string{
+		"field_mappings", "generic_field_mappings", "cross_field_mappings", "shorten_mappings"},
+		serveEntryTables...)
+	serveContributorTables = []string{
+		"contributors", "contributor_names", "contributor_orcids", "contributor_roles"}
+	serveLibraryTables = append(append([]string{}, serveRenderTables...), serveContributorTables...)
+)
+
+// tablesStamp combines the modification times of tables into an ETag value.
+func tablesStamp(tables []string) string {
+	h := fnv.New64a()
+	for _, table := range tables {
+		fmt.Fprintf(h, "%s=%d;", table, tableModTime(table))
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64())
+}
+
+// TLibraryServer answers the API requests. The library is not safe for
+// concurrent use, so requests are handled one at a time.
+type TLibraryServer struct {
+	mu          sync.Mutex
+	loadedStamp string // tablesStamp(serveLibraryTables) when the library was (re)loaded
+}
+
+// errServe is an API error with its HTTP status.
+type errServe struct {
+	status  int
+	message string
+}
+
+func (e *errServe) Error() string { return e.message }
+
+func serveNotFound(format string, args ...any) error {
+	return &errServe{http.StatusNotFound, fmt.Sprintf(format, args...)}
+}
+
+func serveBadRequest(format string, args ...any) error {
+	return &errServe{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+// handle wraps an endpoint: it serialises requests, reloads a library that was
+// changed by another run, answers conditional requests from the ETag of tables,
+// and writes the result (or error) as JSON.
+func (s *TLibraryServer) handle(tables []string, endpoint func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if stamp := tablesStamp(serveLibraryTables); stamp != s.loadedStamp {
+			if !openLibraryToReport() {
+				writeServeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "library could not be reloaded"})
+				return
+			}
+			s.loadedStamp = stamp
+		}
+
+		etag := tablesStamp(tables)
+		if match := r.Header.Get("If-None-Match"); match != "" && (match == etag || match == "*") {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		result, err := endpoint(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if e, ok := err.(*errServe); ok {
+				status = e.status
+			}
+			writeServeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		w.Header().Set("ETag", etag)
+		writeServeJSON(w, http.StatusOK, result)
+	}
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v) //nolint:errcheck
+}
+
+// serveEntryKey resolves the {key} path value to a library entry.
+func serveEntryKey(r *http.Request) (string, error) {
+	raw := cleanKey(r.PathValue("key"))
+	key := resolveInputKey(raw)
+	if key == "" || !Library.EntryExists(key) {
+		return "", serveNotFound("no entry found for %s", raw)
+	}
+	return key, nil
+}
+
+// entryAllGroups returns the sorted static and smart groups key is a member of.
+func (l *TBibTeXLibrary) entryAllGroups(key string) []string {
+	groups := append([]string{}, l.entryGroups(key)...)
+	for _, group := range l.SmartGroupNames() {
+		if l.EntryInSmartGroup(group, key) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// groupMembers returns the sorted members of a static or smart group, and
+// whether group exists at all.
+func (l *TBibTeXLibrary) groupMembers(group string) ([]string, bool) {
+	if members, ok := l.GroupEntries[group]; ok {
+		return members.Set().ElementsSorted(), true
+	}
+	if _, ok := l.smartGroups()[group]; !ok {
+		return nil, false
+	}
+	keys := []string{}
+	forEachBibEntryKey(func(key string) bool {
+		if l.EntryInSmartGroup(group, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	sort.Strings(keys)
+	return keys, true
+}
+
+// TServeEntry is the JSON form of one entry.
+type TServeEntry struct {
+	Key      string            `json:"key"`
+	Alias    string            `json:"alias,omitempty"`
+	Type     string            `json:"type"`
+	Fields   map[string]string `json:"fields"`
+	Groups   []string          `json:"groups"`
+	Aliases  []string          `json:"aliases"`
+	Hints    []string          `json:"hints"`
+	DOIs     []string          `json:"dois"`
+	PDF      string            `json:"pdf,omitempty"`
+	Warnings []string          `json:"warnings"`
+}
+
+func (s *TLibraryServer) getEntries(r *http.Request) (any, error) {
+	var keys []string
+	if query := r.URL.Query().Get("q"); query != "" {
+		var err error
+		if keys, err = Library.QueryEntryKeys(query); err != nil {
+			return nil, serveBadRequest("invalid query %q: %s", query, err)
+		}
+	} else {
+		forEachBibEntryKey(func(key string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		sort.Strings(keys)
+	}
+	columns := parseQueryColumns(defaultQueryColumns)
+	if list := r.URL.Query().Get("columns"); list != "" {
+		columns = parseQueryColumns(list)
+	}
+	rows := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		row := map[string]string{}
+		for _, column := range columns {
+			row[column] = Library.queryColumnValue(key, column)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *TLibraryServer) getEntry(r *http.Request) (any, error) {
+	key, err := serveEntryKey(r)
+	if err != nil {
+		return nil, err
+	}
+	entry := loadEntryFromDb(key)
+	fields := map[string]string{}
+	for field, value := range entry.Fields {
+		if field != EntryTypeField {
+			fields[field] = value
+		}
+	}
+	warnings := entryWarningTexts(key)
+	if warnings == nil {
+		warnings = []string{}
+	}
+	return TServeEntry{
+		Key:      key,
+		Alias:    Library.PreferredKey(key),
+		Type:     entry.FieldValue(EntryTypeField),
+		Fields:   fields,
+		Groups:   Library.entryAllGroups(key),
+		Aliases:  append([]string{}, Library.entryKeyAliases(key)...),
+		Hints:    append([]string{}, Library.entryKeyHints(key)...),
+		DOIs:     append([]string{}, entryDoiAliases(key)...),
+		PDF:      Library.queryColumnValue(key, "pdf"),
+		Warnings: warnings,
+	}, nil
+}
+
+func (s *TLibraryServer) getEntryRendering(r *http.Request) (any, error) {
+	key, err := serveEntryKey(r)
+	if err != nil {
+		return nil, err
+	}
+	format := r.PathValue("format")
+	var rendered string
+	switch format {
+	case "bibtex":
+		rendered = Library.renderAsBibTeX(key)
+	case "tex":
+		rendered = Library.renderAsTeX(key)
+	case "html":
+		rendered = Library.renderAsHTML(key)
+	case "text":
+		rendered = Library.renderAsText(key)
+	default:
+		return nil, serveNotFound("unknown rendering %q (use bibtex, tex, html or text)", format)
+	}
+	return map[string]string{"key": key, "format": format, "rendered": rendered}, nil
+}
+
+func (s *TLibraryServer) getGroups(r *http.Request) (any, error) {
+	type group struct {
+		Name  string `json:"name"`
+		Smart bool   `json:"smart"`
+		Size  int    `json:"size,omitempty"`
+	}
+	groups := []group{}
+	for name, members := range Library.GroupEntries {
+		groups = append(groups, group{Name: name, Size: members.Set().Size()})
+	}
+	for _, name := range Library.SmartGroupNames() {
+		groups = append(groups, group{Name: name, Smart: true})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+func (s *TLibraryServer) getGroup(r *http.Request) (any, error) {
+	name := r.PathValue("name")
+	members, ok := Library.groupMembers(name)
+	if !ok {
+		return nil, serveNotFound("no group named %s", name)
+	}
+	return map[string]any{"name": name, "entries": members}, nil
+}
+
+// TServeContributor is the JSON form of one contributor.
+type TServeContributor struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	ORCID   string   `json:"orcid,omitempty"`
+	DblpKey string   `json:"dblp,omitempty"`
+	Names   []string `json:"names"`
+	Entries []string `json:"entries"`
+}
+
+func serveContributor(id string) (TServeContributor, bool) {
+	c := TServeContributor{ID: id}
+	if db.QueryRow(`SELECT name FROM contributors WHERE id = ?`, id).Scan(&c.Name) != nil {
+		return c, false
+	}
+	if contributor := Library.ContributorByID[id]; contributor != nil {
+		c.ORCID = contributor.ORCID
+		c.DblpKey = contributor.DblpKey
+	}
+	c.Names = append([]string{}, contributorAliasesFromDB(id)...)
+	sort.Strings(c.Names)
+	c.Entries = append([]string{}, contributorEntryKeys(id)...)
+	sort.Strings(c.Entries)
+	return c, true
+}
+
+func (s *TLibraryServer) getContributors(r *http.Request) (any, error) {
+	ref := strings.TrimSpace(r.URL.Query().Get("name"))
+	if ref == "" {
+		return nil, serveBadRequest("missing name parameter")
+	}
+	contributors := []TServeContributor{}
+	for _, id := range contributorRefIDs(&Library, ref) {
+		if c, ok := serveContributor(id); ok {
+			contributors = append(contributors, c)
+		}
+	}
+	return contributors, nil
+}
+
+func (s *TLibraryServer) getContributor(r *http.Request) (any, error) {
+	id := r.PathValue("id")
+	c, ok := serveContributor(id)
+	if !ok {
+		return nil, serveNotFound("no contributor with id %s", id)
+	}
+	return c, nil
+}
+
+func (s *TLibraryServer) getAlias(r *http.Request) (any, error) {
+	key, err := serveEntryKey(r)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"alias": r.PathValue("key"), "key": key}, nil
+}
+
+func (s *TLibraryServer) getSearch(r *http.Request) (any, error) {
+	terms := strings.TrimSpace(r.URL.Query().Get("q"))
+	if terms == "" {
+		return nil, serveBadRequest("missing q parameter")
+	}
+	results, err := Library.SearchEntries(terms)
+	if err != nil {
+		return nil, serveBadRequest("invalid search %q: %s", terms, err)
+	}
+	if results == nil {
+		results = []TSearchResult{}
+	}
+	return results, nil
+}
+
+// ServeLibrary opens the library read-only and answers API requests on addr
+// until the process is stopped.
+func ServeLibrary(addr string) error {
+	if !openLibraryToReport() {
+		return fmt.Errorf("the library could not be opened")
+	}
+	s := &TLibraryServer{loadedStamp: tablesStamp(serveLibraryTables)}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /entries", s.handle(serveLibraryTables, s.getEntries))
+	mux.Handle("GET /entries/{key}", s.handle(serveEntryTables, s.getEntry))
+	mux.Handle("GET /entries/{key}/{format}", s.handle(serveRenderTables, s.getEntryRendering))
+	mux.Handle("GET /groups", s.handle(serveEntryTables, s.getGroups))
+	mux.Handle("GET /groups/{name}", s.handle(serveEntryTables, s.getGroup))
+	mux.Handle("GET /contributors", s.handle(serveContributorTables, s.getContributors))
+	mux.Handle("GET /contributors/{id}", s.handle(serveContributorTables, s.getContributor))
+	mux.Handle("GET /aliases/{key}", s.handle(serveEntryTables, s.getAlias))
+	mux.Handle("GET /search", s.handle([]string{"bib_entries"}, s.getSearch))
+
+	fmt.Fprintf(os.Stderr, "Serving the library on http://%s/ (read-only; Ctrl-C to stop)\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
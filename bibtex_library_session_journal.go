@@ -0,0 +1,412 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_session_journal
+ *
+ * Session journal and -undo.
+ *
+ * Every write session journals the rows it inserts, updates, or deletes in the
+ * decision tables (entries, groups, contributors, aliases, hints, mappings,
+ * non-doubles, ...) into session_journal, with the before/after row images as
+ * JSON. The journal is filled by SQLite triggers rather than by the individual
+ * write paths, so cascaded deletes (e.g. deleteBibEntry → bib_groups) and the
+ * multi-table rewrites of mergeContributorInDB are captured just as completely
+ * as a plain upsertBibEntryField.
+ *
+ * Each answered question starts a new decision within the session, so that
+ * -undo can roll back either the whole last session or only its last N
+ * decisions. Undo replays the inverse operations newest-first in one
+ * transaction with deferred foreign keys; the usual postCheckGate at the end
+ * of the run then verifies foreign-key integrity before anything reaches home.
+ *
+ * A wholesale re-import of the bib file (clearBibTables + parse) is not
+ * journaled; it leaves a barrier row instead, and undo never crosses it.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionJournalTables lists the tables whose changes are journaled. Caches and
+// derived tables (entry_warnings, signatures, generic/cross field mappings,
+// table_modification_times) are left out: they are rebuilt, not decided.
+var sessionJournalTables = []string{
+	"bib_entry_keys",
+	"bib_entries",
+	"bib_groups",
+	"deleted_entries",
+	"entry_metadata",
+	"entry_lineage",
+	"policy_decisions",
+	"entry_doi_aliases",
+	"entry_venues",
+	"superseded_field_values",
+	"field_mappings",
+	"key_oldies",
+	"key_hints",
+	"non_double_entries",
+	"dblp_parent",
+	"dblp_waived",
+	"contributors",
+	"contributor_names",
+	"contributor_roles",
+	"entry_contributor_names",
+	"contributor_id_oldies",
+	"contributor_orcids",
+	"non_double_contributors",
+	"non_double_contributor_names",
+}
+
+// sessionJournalSessionsKept bounds the journal to the most recent sessions.
+const sessionJournalSessionsKept = 20
+
+// sessionJournalBarrier is the operation of the row left by an unjournaled
+// wholesale rewrite; undo stops there.
+const sessionJournalBarrier = "barrier"
+
+func ensureSessionJournalExists() {
+	tryCreateTableIfNeeded(`
+		CREATE TABLE IF NOT EXISTS session_journal (
+		  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		  session       TEXT    NOT NULL,
+		  decision      INTEGER NOT NULL DEFAULT 0,
+		  table_name    TEXT    NOT NULL,
+		  operation     TEXT    NOT NULL,
+		  before_values TEXT,
+		  after_values  TEXT
+		);`)
+	tryCreateTableIfNeeded(`CREATE INDEX IF NOT EXISTS session_journal_session ON session_journal (session, decision);`)
+	// At most one row: present (and not paused) exactly while a write session journals.
+	tryCreateTableIfNeeded(`
+		CREATE TABLE IF NOT EXISTS session_journal_state (
+		  id       INTEGER PRIMARY KEY CHECK (id = 1),
+		  session  TEXT    NOT NULL,
+		  decision INTEGER NOT NULL DEFAULT 0,
+		  paused   INTEGER NOT NULL DEFAULT 0
+		);`)
+	for _, table := range sessionJournalTables {
+		ensureSessionJournalTriggers(table)
+	}
+}
+
+// tableColumns returns the columns of table in declaration order, and which of
+// them make up its primary key. Returns nil when the table does not exist.
+func tableColumns(table string) (columns, keyColumns []string) {
+	rows, err := db.Query(`SELECT name, pk FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, nil
+	}
+	defer rows.Close()
+	type keyColumn struct {
+		name     string
+		position int
+	}
+	var keys []keyColumn
+	for rows.Next() {
+		var name string
+		var pk int
+		if rows.Scan(&name, &pk) != nil {
+			continue
+		}
+		columns = append(columns, name)
+		if pk > 0 {
+			keys = append(keys, keyColumn{name, pk})
+		}
+	}
+	for position := 1; position <= len(keys); position++ {
+		for _, k := range keys {
+			if k.position == position {
+				keyColumns = append(keyColumns, k.name)
+			}
+		}
+	}
+	return columns, keyColumns
+}
+
+// ensureSessionJournalTriggers (re)creates the insert/update/delete triggers
+// for table. They are dropped and recreated on every connect so that columns
+// added by later migrations are always part of the journaled row images.
+func ensureSessionJournalTriggers(table string) {
+	columns, _ := tableColumns(table)
+	if len(columns) == 0 {
+		return
+	}
+	image := func(row string) string {
+		parts := make([]string, len(columns))
+		for i, c := range columns {
+			parts[i] = "'" + c + "', " + row + "." + c
+		}
+		return "json_object(" + strings.Join(parts, ", ") + ")"
+	}
+	changed := make([]string, len(columns))
+	for i, c := range columns {
+		changed[i] = "OLD." + c + " IS NOT NEW." + c
+	}
+
+	for _, t := range []struct{ operation, event, when, before, after string }{
+		{"insert", "INSERT", "", "NULL", image("NEW")},
+		{"update", "UPDATE", "WHEN " + strings.Join(changed, " OR "), image("OLD"), image("NEW")},
+		{"delete", "DELETE", "", image("OLD"), "NULL"},
+	} {
+		trigger := "session_journal_" + table + "_" + t.operation
+		tryCreateTableIfNeeded(`DROP TRIGGER IF EXISTS ` + trigger + `;`)
+		tryCreateTableIfNeeded(`
+			CREATE TRIGGER ` + trigger + ` AFTER ` + t.event + ` ON ` + table + ` ` + t.when + `
+			BEGIN
+			  INSERT INTO session_journal (session, decision, table_name, operation, before_values, after_values)
+			    SELECT session, decision, '` + table + `', '` + t.operation + `', ` + t.before + `, ` + t.after + `
+			      FROM session_journal_state WHERE paused = 0;
+			END;`)
+	}
+}
+
+// clearSessionJournalState stops journaling until the next write session
+// starts. Called on connect, so read-only runs never journal.
+func clearSessionJournalState() {
+	dbExecSave("clearSessionJournalState", `DELETE FROM session_journal_state`)
+}
+
+// beginSessionJournal starts journaling a new write session and drops the
+// journals of sessions beyond sessionJournalSessionsKept.
+func beginSessionJournal() {
+	// Nanoseconds keep two write runs within the same second apart.
+	session := time.Now().Format("2006-01-02 15:04:05.000000000")
+	dbExecSave("beginSessionJournal: prune",
+		`DELETE FROM session_journal WHERE session NOT IN (
+		   SELECT session FROM session_journal GROUP BY session ORDER BY MAX(id) DESC LIMIT ?)`,
+		sessionJournalSessionsKept)
+	dbExecSave("beginSessionJournal",
+		`INSERT INTO session_journal_state (id, session, decision, paused) VALUES (1, ?, 0, 0)
+		   ON CONFLICT(id) DO UPDATE SET session = excluded.session, decision = 0, paused = 0`,
+		session)
+	questionAnsweredHook = nextSessionJournalDecision
+}
+
+// nextSessionJournalDecision attributes all following writes to a new decision.
+func nextSessionJournalDecision() {
+	dbExecSave("nextSessionJournalDecision", `UPDATE session_journal_state SET decision = decision + 1`)
+}
+
+// pauseSessionJournal suspends journaling for a wholesale rewrite that cannot
+// sensibly be undone row by row. A non-empty reason leaves a barrier row.
+func pauseSessionJournal(reason string) {
+	if reason != "" {
+		dbExecSave("pauseSessionJournal: barrier",
+			`INSERT INTO session_journal (session, decision, table_name, operation, before_values)
+			   SELECT session, decision, '', ?, ? FROM session_journal_state WHERE paused = 0`,
+			sessionJournalBarrier, reason)
+	}
+	dbExecSave("pauseSessionJournal", `UPDATE session_journal_state SET paused = 1`)
+}
+
+func resumeSessionJournal() {
+	dbExecSave("resumeSessionJournal", `UPDATE session_journal_state SET paused = 0`)
+}
+
+// TSessionJournalRow is one journaled row change.
+type TSessionJournalRow struct {
+	id         int64
+	table      string
+	operation  string
+	before     string
+	after      string
+	beforeNull bool
+	afterNull  bool
+}
+
+// inverseStatement returns the statement (and its arguments) that undoes r.
+// Values are taken straight out of the JSON row images with json_extract, so
+// they come back with the SQL types they were journaled with.
+func (r TSessionJournalRow) inverseStatement(columns, keyColumns []string) (string, []any) {
+	extract := func(param, column string) string {
+		return "json_extract(" + param + ", '$." + column + "')"
+	}
+	where := make([]string, len(keyColumns))
+	for i, c := range keyColumns {
+		where[i] = c + " = " + extract("?2", c)
+	}
+	switch r.operation {
+	case "insert":
+		for i, c := range keyColumns {
+			where[i] = c + " = " + extract("?1", c)
+		}
+		return "DELETE FROM " + r.table + " WHERE " + strings.Join(where, " AND "), []any{r.after}
+	case "update":
+		set := make([]string, len(columns))
+		for i, c := range columns {
+			set[i] = c + " = " + extract("?1", c)
+		}
+		return "UPDATE " + r.table + " SET " + strings.Join(set, ", ") + " WHERE " + strings.Join(where, " AND "), []any{r.before, r.after}
+	case "delete":
+		values := make([]string, len(columns))
+		for i, c := range columns {
+			values[i] = extract("?1", c)
+		}
+		return "INSERT OR IGNORE INTO " + r.table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ")", []any{r.before}
+	}
+	return "", nil
+}
+
+// lastJournaledSession returns the most recent session that has journal rows.
+func lastJournaledSession() string {
+	var session string
+	db.QueryRow(`SELECT session FROM session_journal ORDER BY id DESC LIMIT 1`).Scan(&session) //nolint:errcheck
+	return session
+}
+
+// undoStartID returns the id of the oldest journal row to undo for session:
+// all of it when decisions <= 0, otherwise only its last decisions decisions.
+func undoStartID(session string, decisions int) int64 {
+	var startID int64
+	if decisions <= 0 {
+		db.QueryRow(`SELECT MIN(id) FROM session_journal WHERE session = ?`, session).Scan(&startID) //nolint:errcheck
+		return startID
+	}
+	db.QueryRow(`
+		SELECT MIN(id) FROM session_journal
+		 WHERE session = ? AND decision >= (
+		   SELECT MIN(decision) FROM (
+		     SELECT DISTINCT decision FROM session_journal
+		      WHERE session = ? ORDER BY decision DESC LIMIT ?))`,
+		session, session, decisions).Scan(&startID) //nolint:errcheck
+	return startID
+}
+
+// UndoSessionJournal undoes the last journaled session, or only its last
+// decisions decisions when decisions > 0. The undone rows are removed from the
+// journal, so a repeated -undo walks further back. Returns whether anything
+// was undone.
+func (l *TBibTeXLibrary) UndoSessionJournal(decisions int) bool {
+	session := lastJournaledSession()
+	if session == "" {
+		l.Progress(ProgressUndoNothing)
+		return false
+	}
+	startID := undoStartID(session, decisions)
+
+	// Never undo across a barrier: rows before it describe a state that the
+	// re-import has since replaced wholesale. The barrier row itself stays, so a
+	// repeated -undo stops at it again.
+	var barrierID int64
+	var barrierReason string
+	db.QueryRow(`SELECT id, before_values FROM session_journal WHERE session = ? AND operation = ? AND id >= ? ORDER BY id DESC LIMIT 1`,
+		session, sessionJournalBarrier, startID).Scan(&barrierID, &barrierReason) //nolint:errcheck
+	if barrierID > 0 {
+		l.Warning(WarningUndoBarrier, session, barrierReason)
+		startID = barrierID + 1
+	}
+
+	rows, err := db.Query(`
+		SELECT id, table_name, operation, COALESCE(before_values, ''), COALESCE(after_values, ''),
+		       before_values IS NULL, after_values IS NULL
+		  FROM session_journal WHERE session = ? AND id >= ? AND operation <> ?
+		 ORDER BY id DESC`, session, startID, sessionJournalBarrier)
+	if err != nil {
+		l.Warning("Could not read the session journal: %s", err)
+		return false
+	}
+	var changes []TSessionJournalRow
+	for rows.Next() {
+		var r TSessionJournalRow
+		if rows.Scan(&r.id, &r.table, &r.operation, &r.before, &r.after, &r.beforeNull, &r.afterNull) == nil {
+			changes = append(changes, r)
+		}
+	}
+	rows.Close()
+
+	scope := "whole session"
+	if decisions > 0 {
+		scope = "last " + strconv.Itoa(decisions) + " decision(s)"
+	}
+	l.Progress(ProgressUndoSession, len(changes), session, scope)
+
+	// The undo itself is not journaled: repeating -undo should step further
+	// back in time, not redo what was just undone.
+	pauseSessionJournal("")
+	defer resumeSessionJournal()
+
+	type tableShape struct{ columns, keyColumns []string }
+	shapes := map[string]tableShape{}
+	touched := TStringSetNew()
+	beginBibTransaction()
+	// Rows are replayed strictly newest-first, but a cascade may have journaled a
+	// child before its parent; defer the FK checks to commit and leave the final
+	// verdict to postCheckGate.
+	dbExecSave("UndoSessionJournal: defer foreign keys", `PRAGMA defer_foreign_keys = ON`)
+	ticker := l.NewProgressTicker(ProgressUndoTicker, len(changes))
+	for _, r := range changes {
+		ticker.Step()
+		shape, known := shapes[r.table]
+		if !known {
+			shape.columns, shape.keyColumns = tableColumns(r.table)
+			shapes[r.table] = shape
+		}
+		if len(shape.keyColumns) == 0 {
+			l.Warning(WarningUndoUnknownTable, r.table, r.id)
+			continue
+		}
+		statement, args := r.inverseStatement(shape.columns, shape.keyColumns)
+		if statement == "" {
+			continue
+		}
+		dbExecSave(fmt.Sprintf("UndoSessionJournal: %s %s (journal row %d)", r.operation, r.table, r.id), statement, args...)
+		touched.Add(r.table)
+	}
+	ticker.Done()
+	dbExecSave("UndoSessionJournal: drop undone rows",
+		`DELETE FROM session_journal WHERE session = ? AND id >= ?`, session, startID)
+	commitBibTransaction()
+
+	now := time.Now().UnixMicro()
+	for _, table := range touched.ElementsSorted() {
+		setTableDate(table, now)
+	}
+	return touched.Contains("bib_entries")
+}
+
+// parseUndoArgs interprets the arguments of -undo: none or "session" for the
+// whole last session, "N" or "last N [decisions]" for its last N decisions.
+// Returns ok == false on anything else.
+func parseUndoArgs(args []string) (decisions int, ok bool) {
+	if len(args) == 0 || (len(args) == 1 && args[0] == "session") {
+		return 0, true
+	}
+	if args[0] == "last" {
+		args = args[1:]
+	}
+	if len(args) == 2 && (args[1] == "decisions" || args[1] == "decision") {
+		args = args[:1]
+	}
+	if len(args) != 1 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// doUndo implements -undo. It works on the database alone, before any of the
+// library is loaded into memory, so no in-memory state can go stale.
+func doUndo(decisions int) {
+	if !prepareWorkingDatabase() {
+		return
+	}
+	if Library.UndoSessionJournal(decisions) {
+		bibEntriesModified = true
+		rebuildEntrySearchIndex()
+	}
+}
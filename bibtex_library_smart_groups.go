@@ -0,0 +1,676 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_smart_groups
+ *
+ * Evaluation of BibDesk smart groups and JabRef search groups. Both are stored
+ * (and written back) verbatim as @comment blocks in l.Comments; this module
+ * additionally parses their conditions into the entry_actions condition model
+ * (scriptCond, see bibtex_library_script.go) so the library can compute their
+ * members, and so they can be used wherever a static group can be used:
+ * "group" statements in .select files, -render_group, -list_group_aliases,
+ * -find_entries groups, and "the entry is in the group" conditions in scripts.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	bibDeskSmartGroupsPrefix = "BibDesk Smart Groups{"
+	jabrefGroupingPrefix     = "jabref-meta: grouping:"
+	jabrefGroupsTreePrefix   = "jabref-meta: groupstree:"
+
+	SmartGroupSourceBibDesk = "bibdesk"
+	SmartGroupSourceJabRef  = "jabref"
+)
+
+// TSmartGroup is one dynamic group whose members are determined by a condition
+// rather than by an explicit list of keys.
+type TSmartGroup struct {
+	Name       string
+	Source     string     // SmartGroupSourceBibDesk or SmartGroupSourceJabRef
+	Definition string     // human-readable rendering of the original condition(s)
+	cond       scriptCond // nil when the definition could not be translated
+	evaluating bool       // recursion guard for groups that refer to (smart) groups
+}
+
+// BibDesk BDSKFilterCondition comparison codes. Codes above bibDeskLarger are
+// date comparisons (today, last week, between, …), which have no counterpart in
+// our condition model.
+const (
+	bibDeskContain    = 0
+	bibDeskNotContain = 1
+	bibDeskEqual      = 2
+	bibDeskNotEqual   = 3
+	bibDeskStartWith  = 4
+	bibDeskEndWith    = 5
+	bibDeskSmaller    = 6
+	bibDeskLarger     = 7
+)
+
+// smartGroups returns the smart groups defined in l.Comments, parsing them on
+// first use. l.SmartGroups is reset to nil whenever Comments is reloaded.
+func (l *TBibTeXLibrary) smartGroups() map[string]*TSmartGroup {
+	if l.SmartGroups == nil {
+		l.LoadSmartGroups()
+	}
+	return l.SmartGroups
+}
+
+// LoadSmartGroups (re)parses all BibDesk smart group and JabRef search group
+// definitions from l.Comments. Definitions that cannot be translated are kept
+// (so they are listed) but have no members; a warning names the offending part.
+func (l *TBibTeXLibrary) LoadSmartGroups() {
+	l.SmartGroups = map[string]*TSmartGroup{}
+	for _, comment := range l.Comments {
+		trimmed := strings.TrimSpace(comment)
+		switch {
+		case strings.HasPrefix(trimmed, bibDeskSmartGroupsPrefix):
+			for _, g := range l.parseBibDeskSmartGroups(trimmed) {
+				l.SmartGroups[g.Name] = g
+			}
+		case strings.HasPrefix(trimmed, jabrefGroupingPrefix):
+			for _, g := range l.parseJabRefSearchGroups(trimmed[len(jabrefGroupingPrefix):]) {
+				l.SmartGroups[g.Name] = g
+			}
+		case strings.HasPrefix(trimmed, jabrefGroupsTreePrefix):
+			for _, g := range l.parseJabRefSearchGroups(trimmed[len(jabrefGroupsTreePrefix):]) {
+				l.SmartGroups[g.Name] = g
+			}
+		}
+	}
+}
+
+// SmartGroupNames returns the names of all smart groups, sorted.
+func (l *TBibTeXLibrary) SmartGroupNames() []string {
+	var names []string
+	for name := range l.smartGroups() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EntryInSmartGroup reports whether key satisfies the condition of smart group name.
+func (l *TBibTeXLibrary) EntryInSmartGroup(name, key string) bool {
+	g, ok := l.smartGroups()[name]
+	if !ok || g.cond == nil || g.evaluating {
+		return false
+	}
+	g.evaluating = true
+	defer func() { g.evaluating = false }()
+	return scriptEvalCond(l, key, &scriptProgram{groupSets: map[string][]string{}}, g.cond)
+}
+
+// EntryInGroup reports whether key is a member of group, which may be a static
+// group (GroupEntries) or a smart group.
+func (l *TBibTeXLibrary) EntryInGroup(group, key string) bool {
+	if l.GroupEntries[group].Set().Contains(key) {
+		return true
+	}
+	return l.EntryInSmartGroup(group, key)
+}
+
+// SmartGroupMembers returns the sorted canonical keys of all entries that
+// satisfy the condition of smart group name.
+func (l *TBibTeXLibrary) SmartGroupMembers(name string) []string {
+	var members []string
+	forEachBibEntryKey(func(key string) bool {
+		if l.EntryInSmartGroup(name, key) {
+			members = append(members, key)
+		}
+		return true
+	})
+	sort.Strings(members)
+	return members
+}
+
+// FindEntriesByGroup is the smart-group-aware counterpart of findBibEntriesByGroup:
+// static group rows whose name contains groupFilter (case-insensitively), plus the
+// members of every smart group whose name does. Results are sorted by entry key.
+func (l *TBibTeXLibrary) FindEntriesByGroup(groupFilter string) []TBibFieldMatch {
+	matches := findBibEntriesByGroup(groupFilter)
+	filter := strings.ToLower(groupFilter)
+	for _, name := range l.SmartGroupNames() {
+		if !strings.Contains(strings.ToLower(name), filter) {
+			continue
+		}
+		for _, key := range l.SmartGroupMembers(name) {
+			matches = append(matches, TBibFieldMatch{key, name})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Key < matches[j].Key })
+	return matches
+}
+
+// ─── BibDesk smart groups ──────────────────────────────────────────────────────
+
+// plistDecodeValue decodes the plist value element opened by start into a
+// map[string]any (dict), []any (array), bool (true/false) or string (any scalar).
+func plistDecodeValue(d *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]any{}
+		key := ""
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					var k string
+					if err := d.DecodeElement(&k, &t); err != nil {
+						return nil, err
+					}
+					key = k
+					continue
+				}
+				v, err := plistDecodeValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = v
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var list []any
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				v, err := plistDecodeValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			case xml.EndElement:
+				return list, nil
+			}
+		}
+	case "true", "false":
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	default:
+		var s string
+		if err := d.DecodeElement(&s, &start); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+// plistDecode decodes the top-level value of a plist document.
+func plistDecode(src string) (any, error) {
+	d := xml.NewDecoder(strings.NewReader(src))
+	d.Strict = false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no plist value found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := tok.(xml.StartElement); ok && t.Name.Local != "plist" {
+			return plistDecodeValue(d, t)
+		}
+	}
+}
+
+func plistString(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// parseBibDeskSmartGroups parses a "BibDesk Smart Groups{<plist>}" comment.
+func (l *TBibTeXLibrary) parseBibDeskSmartGroups(comment string) []*TSmartGroup {
+	body := strings.TrimSuffix(strings.TrimSpace(comment[len(bibDeskSmartGroupsPrefix):]), "}")
+	root, err := plistDecode(body)
+	if err != nil {
+		l.Warning("Could not parse BibDesk smart groups: %s", err)
+		return nil
+	}
+	list, _ := root.([]any)
+	var groups []*TSmartGroup
+	for _, item := range list {
+		dict, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		g := &TSmartGroup{Name: plistString(dict["group name"]), Source: SmartGroupSourceBibDesk}
+		if g.Name == "" {
+			continue
+		}
+		disjunctive := plistString(dict["conjunction"]) == "1"
+		conditions, _ := dict["conditions"].([]any)
+		var parts []string
+		var cond scriptCond
+		failed := false
+		for _, c := range conditions {
+			cd, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			field := plistString(cd["key"])
+			value := plistString(cd["value"])
+			comparison, _ := strconv.Atoi(plistString(cd["comparison"]))
+			parts = append(parts, fmt.Sprintf("%s %s %q", field, bibDeskComparisonName(comparison), value))
+			next, err := bibDeskCondition(field, comparison, value)
+			if err != nil {
+				l.Warning("BibDesk smart group %q: %s", g.Name, err)
+				failed = true
+				continue
+			}
+			switch {
+			case cond == nil:
+				cond = next
+			case disjunctive:
+				cond = &sCondOr{cond, next}
+			default:
+				cond = &sCondAnd{cond, next}
+			}
+		}
+		if disjunctive {
+			g.Definition = strings.Join(parts, " or ")
+		} else {
+			g.Definition = strings.Join(parts, " and ")
+		}
+		if len(conditions) == 0 {
+			// BibDesk puts every entry in a smart group without conditions.
+			cond = &sCondKeyMatches{re: regexp.MustCompile("")}
+			g.Definition = "all entries"
+		}
+		if !failed {
+			g.cond = cond
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+func bibDeskComparisonName(comparison int) string {
+	switch comparison {
+	case bibDeskContain:
+		return "contains"
+	case bibDeskNotContain:
+		return "does not contain"
+	case bibDeskEqual:
+		return "is"
+	case bibDeskNotEqual:
+		return "is not"
+	case bibDeskStartWith:
+		return "starts with"
+	case bibDeskEndWith:
+		return "ends with"
+	case bibDeskSmaller:
+		return "is smaller than"
+	case bibDeskLarger:
+		return "is larger than"
+	}
+	return fmt.Sprintf("comparison#%d", comparison)
+}
+
+// bibDeskCondition translates one BibDesk filter condition into a scriptCond.
+// BibDesk compares case-insensitively throughout.
+func bibDeskCondition(field string, comparison int, value string) (scriptCond, error) {
+	lower := strings.ToLower(field)
+	quoted := regexp.QuoteMeta(value)
+	var pattern string
+	negated := false
+	switch comparison {
+	case bibDeskContain:
+		pattern = quoted
+	case bibDeskNotContain:
+		pattern, negated = quoted, true
+	case bibDeskEqual:
+		pattern = "^" + quoted + "$"
+	case bibDeskNotEqual:
+		pattern, negated = "^"+quoted+"$", true
+	case bibDeskStartWith:
+		pattern = "^" + quoted
+	case bibDeskEndWith:
+		pattern = quoted + "$"
+	case bibDeskSmaller, bibDeskLarger:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("non-numeric comparison value %q for %s is not supported", value, field)
+		}
+		op := "<"
+		if comparison == bibDeskLarger {
+			op = ">"
+		}
+		return &sCondFieldNumCmp{field: lower, op: op, value: n}, nil
+	default:
+		return nil, fmt.Errorf("date comparison (%s) on %s is not supported", bibDeskComparisonName(comparison), field)
+	}
+
+	var cond scriptCond
+	switch lower {
+	case "pub type", "type":
+		if comparison == bibDeskEqual || comparison == bibDeskNotEqual {
+			cond := scriptCond(&sCondEntryType{entryType: strings.ToLower(value)})
+			if negated {
+				cond = &sCondNot{cond}
+			}
+			return cond, nil
+		}
+		cond = &sCondFieldMatches{field: EntryTypeField, re: regexp.MustCompile("(?i)" + pattern)}
+	case "group", "groups":
+		if comparison == bibDeskEqual || comparison == bibDeskNotEqual {
+			return &sCondInGroup{group: value, negated: negated}, nil
+		}
+		return nil, fmt.Errorf("only \"is\"/\"is not\" comparisons are supported on %s", field)
+	case "cite key":
+		cond = &sCondKeyMatches{re: regexp.MustCompile("(?i)" + pattern)}
+	case "any field", "all fields", "anywhere":
+		cond = &sCondFieldMatches{field: "", re: regexp.MustCompile("(?i)" + pattern)}
+	default:
+		cond = &sCondFieldMatches{field: lower, re: regexp.MustCompile("(?i)" + pattern)}
+	}
+	if negated {
+		return &sCondNot{cond}, nil
+	}
+	return cond, nil
+}
+
+// ─── JabRef search groups ──────────────────────────────────────────────────────
+
+// parseJabRefSearchGroups extracts the SearchGroup lines from the body of a
+// jabref-meta: grouping: (or legacy groupstree:) block. Each line has the form
+//
+//	<level> SearchGroup:<name>\;<context>\;<query>\;<case sensitive>\;<regex>\;…
+func (l *TBibTeXLibrary) parseJabRefSearchGroups(content string) []*TSmartGroup {
+	var groups []*TSmartGroup
+	for _, rawLine := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(rawLine)
+		i := strings.Index(line, " SearchGroup:")
+		if i < 0 {
+			continue
+		}
+		parts := strings.Split(line[i+len(" SearchGroup:"):], `\;`)
+		if len(parts) < 3 {
+			continue
+		}
+		g := &TSmartGroup{Name: strings.TrimSpace(parts[0]), Source: SmartGroupSourceJabRef}
+		if g.Name == "" {
+			continue
+		}
+		g.Definition = strings.ReplaceAll(parts[2], `\\`, `\`)
+		caseSensitive := len(parts) > 3 && strings.TrimSpace(parts[3]) == "1"
+		regex := len(parts) > 4 && strings.TrimSpace(parts[4]) == "1"
+		cond, err := parseJabRefQuery(g.Definition, caseSensitive, regex)
+		if err != nil {
+			l.Warning("JabRef search group %q: %s", g.Name, err)
+		} else {
+			g.cond = cond
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// jabrefQueryParser is a recursive-descent parser for JabRef's search syntax:
+//
+//	query      := or
+//	or         := and { ("or" | "|") and }
+//	and        := unary { ["and" | "&"] unary }
+//	unary      := ("not" | "!") unary | "(" query ")" | comparison | term
+//	comparison := field op value
+//	op         := "=" | ":" | "contains" | "=!" | "==" | "!=" | "=~" | "matches"
+//
+// A bare term searches all fields. Fields anyfield/any and anykeyword map to
+// all fields and the keywords field; entrytype compares with the entry type.
+type jabrefQueryParser struct {
+	tokens        []string
+	pos           int
+	caseSensitive bool
+	regex         bool
+}
+
+var jabrefOperators = []string{"=~", "==", "!=", "=!", "=", ":"}
+
+func tokeniseJabRefQuery(query string) ([]string, error) {
+	var tokens []string
+	src := []rune(query)
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(' || ch == ')' || ch == '&' || ch == '|':
+			tokens = append(tokens, string(ch))
+			i++
+		case ch == '"':
+			j := i + 1
+			var buf strings.Builder
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				buf.WriteRune(src[j])
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated quoted string in %q", query)
+			}
+			// A leading NUL marks a quoted literal so it is never taken for a keyword.
+			tokens = append(tokens, "\x00"+buf.String())
+			i = j + 1
+		default:
+			matched := false
+			for _, op := range jabrefOperators {
+				if strings.HasPrefix(string(src[i:]), op) {
+					tokens = append(tokens, op)
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			if ch == '!' {
+				tokens = append(tokens, "!")
+				i++
+				continue
+			}
+			j := i
+			for j < len(src) && !unicode.IsSpace(src[j]) && !strings.ContainsRune(`()"&|=:!`, src[j]) {
+				j++
+			}
+			tokens = append(tokens, string(src[i:j]))
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// parseJabRefQuery translates a JabRef search expression into a scriptCond.
+func parseJabRefQuery(query string, caseSensitive, regex bool) (scriptCond, error) {
+	tokens, err := tokeniseJabRefQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty search expression")
+	}
+	p := &jabrefQueryParser{tokens: tokens, caseSensitive: caseSensitive, regex: regex}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", strings.TrimPrefix(p.tokens[p.pos], "\x00"), query)
+	}
+	return cond, nil
+}
+
+func (p *jabrefQueryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *jabrefQueryParser) isKeyword(word string) bool {
+	return strings.EqualFold(p.peek(), word)
+}
+
+func (p *jabrefQueryParser) parseOr() (scriptCond, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") || p.peek() == "|" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sCondOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *jabrefQueryParser) parseAnd() (scriptCond, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isKeyword("and") || p.peek() == "&":
+			p.pos++
+		case p.peek() == "" || p.peek() == ")" || p.isKeyword("or") || p.peek() == "|":
+			return left, nil
+		}
+		// Juxtaposed terms are an implicit "and".
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sCondAnd{left, right}
+	}
+}
+
+func (p *jabrefQueryParser) parseUnary() (scriptCond, error) {
+	switch {
+	case p.peek() == "":
+		return nil, fmt.Errorf("unexpected end of search expression")
+	case p.isKeyword("not") || p.peek() == "!":
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &sCondNot{inner}, nil
+	case p.peek() == "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing \")\"")
+		}
+		p.pos++
+		return inner, nil
+	}
+	word := strings.TrimPrefix(p.tokens[p.pos], "\x00")
+	p.pos++
+	op := p.peek()
+	switch {
+	case op == "=" || op == ":" || op == "=!" || op == "==" || op == "!=" || op == "=~":
+	case strings.EqualFold(op, "contains"):
+		op = "="
+	case strings.EqualFold(op, "matches"):
+		op = "=~"
+	default:
+		// Bare term: search all fields.
+		return p.fieldCondition("", "=", word)
+	}
+	p.pos++
+	if p.peek() == "" {
+		return nil, fmt.Errorf("missing value after %s %s", word, op)
+	}
+	value := strings.TrimPrefix(p.tokens[p.pos], "\x00")
+	p.pos++
+	return p.fieldCondition(strings.ToLower(word), op, value)
+}
+
+// fieldCondition builds the condition for field op value. The group's
+// case-sensitivity and regex flags apply to the plain contains operators.
+func (p *jabrefQueryParser) fieldCondition(field, op, value string) (scriptCond, error) {
+	caseFlag := "(?i)"
+	if p.caseSensitive || op == "=!" {
+		caseFlag = ""
+	}
+	var pattern string
+	negated := false
+	switch op {
+	case "=", ":", "=!":
+		if p.regex {
+			pattern = value
+		} else {
+			pattern = regexp.QuoteMeta(value)
+		}
+	case "==":
+		pattern = "^" + regexp.QuoteMeta(value) + "$"
+	case "!=":
+		pattern, negated = regexp.QuoteMeta(value), true
+	case "=~":
+		pattern = value
+	}
+	re, err := regexp.Compile(caseFlag + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %s", value, err)
+	}
+	var cond scriptCond
+	switch field {
+	case "anyfield", "any":
+		cond = &sCondFieldMatches{field: "", re: re}
+	case "anykeyword":
+		cond = &sCondFieldMatches{field: "keywords", re: re}
+	case "entrytype", "type":
+		if op == "==" {
+			return &sCondEntryType{entryType: strings.ToLower(value)}, nil
+		}
+		cond = &sCondFieldMatches{field: EntryTypeField, re: re}
+	case "key", "citationkey", "bibtexkey":
+		cond = &sCondKeyMatches{re: re}
+	case "groups":
+		cond = &sCondGroupMatches{re: re}
+	default:
+		cond = &sCondFieldMatches{field: field, re: re}
+	}
+	if negated {
+		return &sCondNot{cond}, nil
+	}
+	return cond, nil
+}
@@ -0,0 +1,381 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_stats
+ *
+ * Library statistics and health dashboard (-stats).
+ *
+ * Collects entries per year, type, venue and group; DBLP, DOI, ISBN and PDF
+ * coverage; contributor counts with and without ORCID; the open homework
+ * queues; warning counts by kind; and the growth of the library over time, as
+ * read from the creation times encoded in KeyFromTime keys. The report renders
+ * as text, as JSON, or as a standalone HTML page with simple bar charts.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	stdlib_html "html"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// statsVenuesShown is the number of venues listed in the report.
+const statsVenuesShown = 25
+
+// TStatsCount is one labelled count of the statistics report.
+type TStatsCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// TStatsCoverage is the number of entries (out of Total) that have something.
+type TStatsCoverage struct {
+	Label   string  `json:"label"`
+	Count   int     `json:"count"`
+	Total   int     `json:"total"`
+	Percent float64 `json:"percent"`
+}
+
+// TStatsGrowth is the number of entries added in a month, and the library
+// size at its end, for entries whose key records their creation time.
+type TStatsGrowth struct {
+	Month string `json:"month"`
+	Added int    `json:"added"`
+	Total int    `json:"total"`
+}
+
+// TLibraryStats is the statistics report of the library.
+type TLibraryStats struct {
+	Generated           string           `json:"generated"`
+	Entries             int              `json:"entries"`
+	ByYear              []TStatsCount    `json:"by_year"`
+	ByType              []TStatsCount    `json:"by_type"`
+	ByVenue             []TStatsCount    `json:"by_venue"`
+	ByGroup             []TStatsCount    `json:"by_group"`
+	Coverage            []TStatsCoverage `json:"coverage"`
+	Contributors        int              `json:"contributors"`
+	ContributorsORCID   int              `json:"contributors_with_orcid"`
+	Homework            []TStatsCount    `json:"homework"`
+	EntriesWithWarnings int              `json:"entries_with_warnings"`
+	WarningsByKind      []TStatsCount    `json:"warnings_by_kind"`
+	Growth              []TStatsGrowth   `json:"growth"`
+}
+
+var (
+	statsQuotedPattern = regexp.MustCompile(`"[^"]*"|\{[^{}]*\}|'[^']*'`)
+	statsNumberPattern = regexp.MustCompile(`[0-9]+`)
+)
+
+// warningKind reduces a warning text to its kind, by blanking out the quoted
+// and braced values and the numbers that differ from entry to entry.
+func warningKind(warning string) string {
+	kind := statsQuotedPattern.ReplaceAllString(warning, "…")
+	kind = statsNumberPattern.ReplaceAllString(kind, "#")
+	if i := strings.Index(kind, ": "); i > 0 {
+		kind = kind[:i]
+	}
+	return kind
+}
+
+// keyCreationTime returns the creation time encoded in a KeyFromTime key.
+func keyCreationTime(key string) (time.Time, bool) {
+	stamp, found := strings.CutPrefix(key, keyPrefix+"-")
+	if !found {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("2006-01-02-15-04-05", stamp)
+	return t, err == nil
+}
+
+// entryVenue returns the journal or book title an entry appeared in, taking
+// the book title from its crossref parent when it has none of its own.
+func (l *TBibTeXLibrary) entryVenue(entry *TBibTeXEntry) string {
+	for _, field := range []string{"journal", "booktitle"} {
+		if venue := entry.FieldValue(field); venue != "" {
+			return texToText(venue)
+		}
+	}
+	if parent, _ := l.resolveParent(entry); parent != nil {
+		for _, field := range []string{"booktitle", TitleField} {
+			if venue := parent.FieldValue(field); venue != "" {
+				return texToText(venue)
+			}
+		}
+	}
+	return ""
+}
+
+// sortedStatsCounts turns counts into a list, by descending count (and label),
+// or by label when byLabel is set.
+func sortedStatsCounts(counts map[string]int, byLabel bool) []TStatsCount {
+	list := make([]TStatsCount, 0, len(counts))
+	for label, count := range counts {
+		list = append(list, TStatsCount{label, count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !byLabel && list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Label < list[j].Label
+	})
+	return list
+}
+
+// CollectStats gathers the statistics report. It expects the title index to
+// have been built, for the duplicate-group homework count.
+func (l *TBibTeXLibrary) CollectStats() TLibraryStats {
+	stats := TLibraryStats{Generated: time.Now().Format("2006-01-02 15:04")}
+
+	years := map[string]int{}
+	types := map[string]int{}
+	venues := map[string]int{}
+	groups := map[string]int{}
+	added := map[string]int{}
+	var withDblp, withDoi, withIsbn, withPdf, unknownCreation int
+	smartGroups := l.SmartGroupNames()
+	forEachBibEntryKey(func(key string) bool {
+		entry := loadEntryFromDb(key)
+		stats.Entries++
+		year := entry.FieldValue("year")
+		if year == "" {
+			year = "(no year)"
+		}
+		years[year]++
+		types[entry.FieldValue(EntryTypeField)]++
+		if venue := l.entryVenue(entry); venue != "" {
+			venues[venue]++
+		}
+		for _, group := range smartGroups {
+			if l.EntryInSmartGroup(group, key) {
+				groups[group]++
+			}
+		}
+		if entry.FieldValue(DBLPField) != "" {
+			withDblp++
+		}
+		if entry.FieldValue("doi") != "" {
+			withDoi++
+		}
+		if entry.FieldValue("isbn") != "" {
+			withIsbn++
+		}
+		if l.PDFFiles[key] {
+			withPdf++
+		}
+		if created, ok := keyCreationTime(key); ok {
+			added[created.Format("2006-01")]++
+		} else {
+			unknownCreation++
+		}
+		return true
+	})
+	for group, members := range l.GroupEntries {
+		groups[group] = members.Set().Size()
+	}
+
+	stats.ByYear = sortedStatsCounts(years, true)
+	stats.ByType = sortedStatsCounts(types, false)
+	stats.ByVenue = sortedStatsCounts(venues, false)
+	if len(stats.ByVenue) > statsVenuesShown {
+		stats.ByVenue = stats.ByVenue[:statsVenuesShown]
+	}
+	stats.ByGroup = sortedStatsCounts(groups, false)
+
+	coverage := func(label string, count int) TStatsCoverage {
+		c := TStatsCoverage{Label: label, Count: count, Total: stats.Entries}
+		if stats.Entries > 0 {
+			c.Percent = float64(count) * 100 / float64(stats.Entries)
+		}
+		return c
+	}
+	stats.Coverage = []TStatsCoverage{
+		coverage("DBLP", withDblp),
+		coverage("DOI", withDoi),
+		coverage("ISBN", withIsbn),
+		coverage("PDF", withPdf),
+	}
+
+	bibQueryRow(`SELECT COUNT(*) FROM contributors`).Scan(&stats.Contributors) //nolint:errcheck
+	bibQueryRow(`
+		SELECT COUNT(*) FROM contributors c
+		 WHERE COALESCE(c.orcid, '') != ''
+		    OR EXISTS (SELECT 1 FROM contributor_orcids o WHERE o.contributor_id = c.id)`).Scan(&stats.ContributorsORCID) //nolint:errcheck
+
+	if n, ok := countOrcidNotYetEnriched(); ok {
+		stats.Homework = append(stats.Homework, TStatsCount{StatContributorsWithOrcidNotYetEnriched, n})
+	}
+	stats.Homework = append(stats.Homework,
+		TStatsCount{StatLosingValuesPending, countTriagePending()},
+		TStatsCount{StatEntriesWithUnresolvedDblpCandidates, countDblpCandidates()},
+		TStatsCount{StatTitleGroupsWithUnresolvedDuplicates, countUnresolvedGroups()},
+	)
+	if len(l.lintRules()) > 0 {
+		stats.Homework = append(stats.Homework, TStatsCount{StatEntriesWithLintFindings, countLintFindings()})
+	}
+
+	kinds := map[string]int{}
+	if rows, err := bibQuery(`SELECT key, warning FROM entry_warnings WHERE warning != ''`); err == nil {
+		warned := map[string]bool{}
+		for rows.Next() {
+			var key, warning string
+			if rows.Scan(&key, &warning) == nil {
+				warned[key] = true
+				kinds[warningKind(warning)]++
+			}
+		}
+		rows.Close()
+		stats.EntriesWithWarnings = len(warned)
+	}
+	stats.WarningsByKind = sortedStatsCounts(kinds, false)
+
+	total := unknownCreation
+	for _, month := range sortedStatsCounts(added, true) {
+		total += month.Count
+		stats.Growth = append(stats.Growth, TStatsGrowth{month.Label, month.Count, total})
+	}
+	return stats
+}
+
+// WriteStats writes the report as text, as JSON (format "json") or as a
+// standalone HTML page (format "html").
+func (s TLibraryStats) WriteStats(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(s)
+	case "html":
+		return s.writeHTML(w)
+	}
+	return s.writeText(w)
+}
+
+func (s TLibraryStats) writeText(w io.Writer) error {
+	var b strings.Builder
+	section := func(title string, counts []TStatsCount) {
+		if len(counts) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		width := 0
+		for _, c := range counts {
+			width = max(width, len(c.Label))
+		}
+		for _, c := range counts {
+			fmt.Fprintf(&b, "  %-*s  %6d\n", width, c.Label, c.Count)
+		}
+	}
+
+	fmt.Fprintf(&b, "Library statistics (%s)\n", s.Generated)
+	fmt.Fprintf(&b, "\n%s: %d\n", StatEntries, s.Entries)
+	fmt.Fprintf(&b, "%s: %d (%d with ORCID, %d without)\n", StatContributors, s.Contributors, s.ContributorsORCID, s.Contributors-s.ContributorsORCID)
+	fmt.Fprintf(&b, "\nCoverage:\n")
+	for _, c := range s.Coverage {
+		fmt.Fprintf(&b, "  %-5s %6d / %d (%.0f%%)\n", c.Label, c.Count, c.Total, c.Percent)
+	}
+	section("Homework", s.Homework)
+	section("Entries per year", s.ByYear)
+	section("Entries per type", s.ByType)
+	section(fmt.Sprintf("Top %d venues", statsVenuesShown), s.ByVenue)
+	section("Entries per group", s.ByGroup)
+	if len(s.WarningsByKind) > 0 {
+		fmt.Fprintf(&b, "\nEntries with warnings: %d\n", s.EntriesWithWarnings)
+	}
+	section("Warnings by kind", s.WarningsByKind)
+	if len(s.Growth) > 0 {
+		fmt.Fprintf(&b, "\nGrowth:\n")
+		for _, g := range s.Growth {
+			fmt.Fprintf(&b, "  %s  %+6d  %6d\n", g.Month, g.Added, g.Total)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// statsBarChart renders counts as an HTML table with proportional bars.
+func statsBarChart(title string, counts []TStatsCount) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	highest := 1
+	for _, c := range counts {
+		highest = max(highest, c.Count)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h2>%s</h2>\n<table class=\"chart\">\n", stdlib_html.EscapeString(title))
+	for _, c := range counts {
+		fmt.Fprintf(&b, "<tr><td class=\"label\">%s</td><td class=\"count\">%d</td><td><div class=\"bar\" style=\"width:%.1f%%\"></div></td></tr>\n",
+			stdlib_html.EscapeString(c.Label), c.Count, float64(c.Count)*100/float64(highest))
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+// statsGrowthChart renders the cumulative library size as an SVG line chart.
+func statsGrowthChart(growth []TStatsGrowth) string {
+	if len(growth) < 2 {
+		return ""
+	}
+	const width, height = 800.0, 200.0
+	highest := growth[len(growth)-1].Total
+	var points []string
+	for i, g := range growth {
+		x := width * float64(i) / float64(len(growth)-1)
+		y := height - height*float64(g.Total)/float64(max(highest, 1))
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return fmt.Sprintf(`<h2>Growth</h2>
+<svg viewBox="0 -5 %.0f %.0f" class="growth"><polyline points="%s"/></svg>
+<p class="axis">%s – %s: %d → %d entries</p>
+`, width, height+10, strings.Join(points, " "),
+		growth[0].Month, growth[len(growth)-1].Month, growth[0].Total, highest)
+}
+
+func (s TLibraryStats) writeHTML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Library statistics</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; color: #222; }
+table.chart { border-collapse: collapse; width: 100%; }
+table.chart td { padding: 1px 6px; vertical-align: middle; }
+td.label { width: 40%; } td.count { width: 5em; text-align: right; }
+div.bar { background: #4a7ab5; height: 0.9em; }
+svg.growth { width: 100%; height: 200px; } svg.growth polyline { fill: none; stroke: #4a7ab5; stroke-width: 2; }
+p.axis { color: #666; font-size: smaller; }
+</style></head><body>
+`)
+	fmt.Fprintf(&b, "<h1>Library statistics</h1>\n<p>Generated %s</p>\n", stdlib_html.EscapeString(s.Generated))
+	fmt.Fprintf(&b, "<p>%s: %d. %s: %d (%d with ORCID, %d without).</p>\n",
+		StatEntries, s.Entries, StatContributors, s.Contributors, s.ContributorsORCID, s.Contributors-s.ContributorsORCID)
+
+	coverage := make([]TStatsCount, len(s.Coverage))
+	for i, c := range s.Coverage {
+		coverage[i] = TStatsCount{fmt.Sprintf("%s (%.0f%%)", c.Label, c.Percent), c.Count}
+	}
+	b.WriteString(statsBarChart("Coverage", coverage))
+	b.WriteString(statsBarChart("Homework", s.Homework))
+	b.WriteString(statsGrowthChart(s.Growth))
+	b.WriteString(statsBarChart("Entries per year", s.ByYear))
+	b.WriteString(statsBarChart("Entries per type", s.ByType))
+	b.WriteString(statsBarChart(fmt.Sprintf("Top %d venues", statsVenuesShown), s.ByVenue))
+	b.WriteString(statsBarChart("Entries per group", s.ByGroup))
+	b.WriteString(statsBarChart(fmt.Sprintf("Warnings by kind (entries with warnings: %d)", s.EntriesWithWarnings), s.WarningsByKind))
+	b.WriteString("</body></html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
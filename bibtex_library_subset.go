@@ -369,7 +369,7 @@ func applySubsetBibToDb(bibEntry TBibTeXEntry, canonicalKey string, trusted bool
 			for _, f := range illegalFields {
 				fmt.Fprintf(os.Stderr, "WARNING: entry %s has field %q which is not allowed for entry type %q — it will be ignored during merge.\n", canonicalKey, f, entryType)
 			}
-			if !Library.ConfirmAction("Proceed anyway (illegal fields will be dropped)") {
+			if !Library.ConfirmAction("Proceed anyway (illegal fields will be dropped)", canonicalKey) {
 				subsetSyncBailOut()
 			}
 		}
@@ -386,7 +386,7 @@ func applySubsetBibToDb(bibEntry TBibTeXEntry, canonicalKey string, trusted bool
 		fmt.Fprintf(os.Stderr, "Fields to clear: %s\n", strings.Join(toClear, ", "))
 	}
 
-	if !Library.ConfirmAction(QuestionSubsetBibChanged) {
+	if !Library.ConfirmAction(QuestionSubsetBibChanged, canonicalKey) {
 		return canonicalKey
 	}
 	// Apply type change before MergeEntries — otherwise priority logic silently
@@ -414,7 +414,7 @@ func deleteSubsetEntry(canonicalKey, localFilesDir, outputKey string, trusted bo
 	if !trusted {
 		fmt.Fprintf(os.Stderr, "\nEntry deleted from subset bib:\n")
 		fmt.Fprint(os.Stderr, Library.entryDisplayString(canonicalKey))
-		if !Library.ConfirmAction(QuestionSubsetDeleteEntry) {
+		if !Library.ConfirmAction(QuestionSubsetDeleteEntry, canonicalKey) {
 			return false
 		}
 	}
@@ -1099,7 +1099,7 @@ func runSubsetUpSync(cfg TBibGetConfig, sourcePath, keysBasePath string, syncSta
 			fmt.Fprint(os.Stderr, Library.entryDisplayString(c.canonicalKey))
 			fmt.Fprintf(os.Stderr, "Bib entry:\n")
 			printEntryFields(c.bibEntry.Fields[EntryTypeField], c.bibEntry.Key, c.bibEntry.Fields)
-			if !Library.ConfirmAction(QuestionSubsetBothChanged) {
+			if !Library.ConfirmAction(QuestionSubsetBothChanged, c.canonicalKey) {
 				continue // keep DB version
 			}
 		}
@@ -119,6 +119,8 @@ func init() {
 		// Metadata and miscellaneous (no cascade)
 		{"entry_metadata", EntryMetadataFilePath,
 			exportEntryMetadataCSV, importEntryMetadataCSV, nil},
+		{"entry_venues", EntryVenuesFilePath,
+			ExportEntryVenues, func() { importEntryVenuesFromCSV(true) }, nil},
 		// shorten_mappings lives in globalFolder — not in the per-library .tables/ dir.
 		// It is imported directly via ImportAllCSVExchangeFiles; no registry entry needed.
 		{"urls_ignore", URLsIgnoreFilePath,
@@ -355,6 +357,7 @@ func importBibEntriesReplace() {
 	})
 	if ok {
 		importReport("Imported", path, n)
+		rebuildEntrySearchIndex()
 	}
 }
 
@@ -471,5 +474,8 @@ func renormaliseField(l *TBibTeXLibrary, field string) {
 		tx.Rollback()
 		return
 	}
+	for _, u := range updates {
+		updateEntrySearchIndex(u.key, field, u.val)
+	}
 	dbInteraction.Progress("  Renormalised %q: %d value(s) updated", field, len(updates))
 }
@@ -302,7 +302,7 @@ func (l *TBibTeXLibrary) CheckTitleFromURL(entry *TBibTeXEntry) {
 
 	fmt.Fprint(os.Stderr, "QUESTION: Accept title (y), skip (n), or type alternative: ")
 	for {
-		input := readStdinLine()
+		input := answerLine("Accept title from URL", []any{entry.Key}, nil, "n")
 		switch input {
 		case "y":
 			entry.Fields["title"] = fetchedTitle
@@ -0,0 +1,588 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - bibtex_library
+ *   - bibtex_library_venue
+ *
+ * Structured event data of proceedings: series name and number, event city,
+ * region and country (ISO 3166-1 alpha-2), and event dates. It is parsed from
+ * the booktitle, title and address of each proceedings entry, e.g.
+ *
+ *	Conceptual Modeling - ER 2006, 25th International Conference on Conceptual
+ *	Modeling, Tucson, AZ, USA, November 6-9, 2006, Proceedings
+ *
+ * gives series ER, number 25, Tucson, Arizona, US, 2006-11-06 to 2006-11-09,
+ * using the state/country tables of TBibTeXLibraryGeoMappings. Venues are kept in
+ * entry_venues, re-parsed when the texts they were parsed from change; clearing
+ * parsed_from (e.g. via the entry_venues exchange CSV) pins a hand-corrected venue.
+ *
+ * Sync files with venue_fields emit the venue as BibLaTeX venue/eventdate (with
+ * biber_mode) or as a BibTeX address. CheckVenueSeries checks the venues of each
+ * series against one another.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TVenue is the structured event data of a proceedings entry.
+type TVenue struct {
+	Series       string // series acronym, e.g. "ER"
+	SeriesNumber string // number of the event within its series, e.g. "25"
+	City         string
+	Region       string // canonical state/region name (state_names)
+	Country      string // ISO 3166-1 alpha-2 code
+	EventStart   string // YYYY-MM-DD, or YYYY-MM when the day is unknown
+	EventEnd     string // as EventStart; "" for single-day events
+	ParsedFrom   string // the texts the venue was parsed from; "" when maintained by hand
+}
+
+// IsEmpty reports whether nothing was found for the venue.
+func (v TVenue) IsEmpty() bool {
+	return v.Series == "" && v.SeriesNumber == "" && v.City == "" && v.Region == "" &&
+		v.Country == "" && v.EventStart == ""
+}
+
+// EventDate returns the event dates as a BibLaTeX date range.
+func (v TVenue) EventDate() string {
+	if v.EventEnd == "" || v.EventEnd == v.EventStart {
+		return v.EventStart
+	}
+	return v.EventStart + "/" + v.EventEnd
+}
+
+// Year returns the year of the event, or "" when its dates are unknown.
+func (v TVenue) Year() string {
+	if len(v.EventStart) < 4 {
+		return ""
+	}
+	return v.EventStart[:4]
+}
+
+var (
+	venueMonths = []string{"january", "february", "march", "april", "may", "june",
+		"july", "august", "september", "october", "november", "december"}
+
+	venueSeriesYear = regexp.MustCompile(`^([A-Z][A-Za-z0-9&+/-]*[A-Za-z0-9+])\s*'?(\d{4}|\d{2})$`)
+	venueOrdinal    = regexp.MustCompile(`(?i)\b(\d{1,3})\s*(?:st|nd|rd|th)\b`)
+	venueYear       = regexp.MustCompile(`^\d{4}$`)
+	venueDigits     = regexp.MustCompile(`[0-9]`)
+
+	// venueNonCityWords mark a title part as something other than a city name.
+	venueNonCityWords = []string{"conference", "workshop", "symposium", "proceedings",
+		"congress", "meeting", "forum", "summit", "seminar", "part", "volume", "papers", "international"}
+
+	venueTeXCleaner = strings.NewReplacer(`\textsuperscript{`, "", "$^{", "", "$^", "", "}$", "", "{", "", "}", "", "~", " ")
+
+	// countryISOCodes maps canonical country names to their ISO 3166-1 alpha-2 code,
+	// taken from the two-letter aliases in country_names. Built on first use.
+	countryISOCodes map[string]string
+
+	// countryNonISOCodes are common two-letter country aliases that are not
+	// ISO 3166-1 alpha-2 codes (e.g. "UK" next to "GB").
+	countryNonISOCodes = map[string]bool{"UK": true, "EL": true}
+)
+
+// countryISOCode returns the ISO 3166-1 alpha-2 code of a canonical country name.
+// When a country has several two-letter aliases, the smallest one is taken, so
+// the code does not depend on map order.
+func (l *TBibTeXLibrary) countryISOCode(country string) string {
+	if countryISOCodes == nil {
+		countryISOCodes = map[string]string{}
+		for alias, canonical := range l.CountryAliasToCanonical {
+			if len(alias) != 2 || countryNonISOCodes[alias] {
+				continue
+			}
+			if alias[0] < 'A' || alias[0] > 'Z' || alias[1] < 'A' || alias[1] > 'Z' {
+				continue
+			}
+			if code, ok := countryISOCodes[canonical]; !ok || alias < code {
+				countryISOCodes[canonical] = alias
+			}
+		}
+	}
+	return countryISOCodes[country]
+}
+
+// countryName returns the canonical country name for an ISO 3166-1 alpha-2 code.
+func (l *TBibTeXLibrary) countryName(code string) string {
+	if name, ok := l.CountryAliasToCanonical[code]; ok {
+		return name
+	}
+	return code
+}
+
+// venueLocation returns the venue as "City, Region, Country".
+func (l *TBibTeXLibrary) venueLocation(v TVenue) string {
+	var parts []string
+	for _, part := range []string{v.City, v.Region, l.countryName(v.Country)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseEventDates parses "November 6-9", "6-9 November 2006", "October 30 - November 2"
+// and the like into ISO dates, taking the year from the text or else from year.
+func parseEventDates(text, year string) (string, string, bool) {
+	type tDate struct {
+		month, day int
+		year       string
+	}
+	parse := func(segment string) (tDate, bool) {
+		var d tDate
+		for _, token := range strings.Fields(segment) {
+			lower := strings.ToLower(token)
+			month := 0
+			if len(lower) >= 3 {
+				for i, name := range venueMonths {
+					if strings.HasPrefix(name, lower) {
+						month = i + 1
+					}
+				}
+			}
+			switch {
+			case month > 0 && d.month == 0:
+				d.month = month
+			case venueYear.MatchString(token) && d.year == "":
+				d.year = token
+			case len(token) <= 2 && d.day == 0:
+				day, err := strconv.Atoi(token)
+				if err != nil || day < 1 || day > 31 {
+					return d, false
+				}
+				d.day = day
+			default:
+				return d, false
+			}
+		}
+		return d, true
+	}
+	format := func(d tDate) string {
+		if d.day == 0 {
+			return fmt.Sprintf("%s-%02d", d.year, d.month)
+		}
+		return fmt.Sprintf("%s-%02d-%02d", d.year, d.month, d.day)
+	}
+
+	normalised := strings.NewReplacer("--", " - ", "–", " - ", "-", " - ", ".", " ", ",", " ").Replace(venueTeXCleaner.Replace(text))
+	segments := strings.Split(normalised, " - ")
+	if len(segments) > 2 {
+		return "", "", false
+	}
+	start, ok := parse(segments[0])
+	if !ok {
+		return "", "", false
+	}
+	end := start
+	if len(segments) == 2 {
+		if end, ok = parse(segments[1]); !ok {
+			return "", "", false
+		}
+		if start.month == 0 {
+			start.month = end.month
+		}
+		if end.month == 0 {
+			end.month = start.month
+		}
+		if start.year == "" {
+			start.year = end.year
+		}
+		if end.year == "" {
+			end.year = start.year
+		}
+	}
+	if start.year == "" {
+		start.year, end.year = year, year
+	}
+	if start.month == 0 || start.year == "" {
+		return "", "", false
+	}
+	if len(segments) == 1 {
+		return format(start), "", true
+	}
+	return format(start), format(end), true
+}
+
+// venueCity returns part as a city name, or "" when it does not look like one.
+func venueCity(part string) string {
+	city := strings.TrimSpace(venueTeXCleaner.Replace(part))
+	if city == "" || venueDigits.MatchString(city) || len(strings.Fields(city)) > 4 {
+		return ""
+	}
+	if first := city[0]; first < 'A' || first > 'Z' {
+		if !strings.HasPrefix(city, `\`) {
+			return ""
+		}
+	}
+	for _, word := range strings.Fields(strings.ToLower(city)) {
+		for _, nonCity := range venueNonCityWords {
+			if strings.HasPrefix(word, nonCity) {
+				return ""
+			}
+		}
+	}
+	return city
+}
+
+// parseVenueText fills the still empty parts of v from one booktitle, title or
+// address text. year is the year of the entry.
+func (l *TBibTeXLibrary) parseVenueText(v *TVenue, text, year string) {
+	var parts []string
+	for _, part := range strings.Split(text, ",") {
+		parts = append(parts, strings.TrimSpace(part))
+	}
+
+	// Event dates, possibly followed by a separate year part.
+	dateParts := map[int]bool{}
+	for i, part := range parts {
+		if venueYear.MatchString(part) {
+			dateParts[i] = true
+			if year == "" {
+				year = part
+			}
+		}
+	}
+	for i, part := range parts {
+		if dateParts[i] {
+			continue
+		}
+		partYear := year
+		if i+1 < len(parts) && venueYear.MatchString(parts[i+1]) {
+			partYear = parts[i+1]
+		}
+		if start, end, ok := parseEventDates(part, partYear); ok {
+			dateParts[i] = true
+			if v.EventStart == "" {
+				v.EventStart, v.EventEnd = start, end
+			}
+		}
+	}
+
+	// Location: the last country, preceded by an optional region and a city. A
+	// region is also looked up in braced form, so that "AZ" after a country is
+	// read as Arizona rather than as the country code of Azerbaijan.
+	region := func(part string) string {
+		if canonical, ok := lookupAlias(l.StateAliasToCanonical, part); ok {
+			return canonical
+		}
+		if canonical, ok := l.StateAliasToCanonical["{"+part+"}"]; ok {
+			return canonical
+		}
+		return ""
+	}
+	locationAt, country := -1, ""
+	for i := len(parts) - 1; i >= 0 && locationAt < 0; i-- {
+		if dateParts[i] {
+			continue
+		}
+		if canonical, ok := lookupAlias(l.CountryAliasToCanonical, parts[i]); ok {
+			locationAt, country = i, canonical
+		} else if r := region(parts[i]); r != "" {
+			locationAt = i + 1 // no country given; the region is at locationAt-1
+		}
+	}
+	if locationAt >= 0 && v.City == "" && v.Region == "" && v.Country == "" {
+		cityAt := locationAt - 1
+		if cityAt >= 0 {
+			if r := region(parts[cityAt]); r != "" {
+				v.Region = r
+				cityAt--
+				if country == "" {
+					country = l.StateToCountry[r]
+				}
+			}
+		}
+		if cityAt >= 0 && !dateParts[cityAt] {
+			v.City = venueCity(parts[cityAt])
+		}
+		if country != "" {
+			v.Country = l.countryISOCode(country)
+		}
+	}
+
+	// Series acronym with its year, e.g. "ER 2006" or "(CAiSE'20)", and the
+	// series number from an ordinal, e.g. "25th".
+	for i, part := range parts {
+		if dateParts[i] {
+			continue
+		}
+		for _, candidate := range strings.FieldsFunc(part, func(r rune) bool { return r == '(' || r == ')' || r == ':' || r == '–' }) {
+			for _, piece := range strings.Split(candidate, " - ") {
+				m := venueSeriesYear.FindStringSubmatch(strings.TrimSpace(venueTeXCleaner.Replace(piece)))
+				if m == nil || v.Series != "" {
+					continue
+				}
+				upper := 0
+				for _, r := range m[1] {
+					if r >= 'A' && r <= 'Z' {
+						upper++
+					}
+				}
+				if upper >= 2 && (year == "" || strings.HasSuffix(year, m[2])) {
+					v.Series = m[1]
+				}
+			}
+		}
+		if v.SeriesNumber == "" {
+			if m := venueOrdinal.FindStringSubmatch(venueTeXCleaner.Replace(part)); m != nil {
+				v.SeriesNumber = m[1]
+			}
+		}
+	}
+}
+
+// ParseVenue derives the venue of a proceedings entry from its booktitle, title
+// and address, in that order of preference.
+func (l *TBibTeXLibrary) ParseVenue(key string) TVenue {
+	var v TVenue
+	var sources []string
+	year := l.EntryFieldValueity(key, "year")
+	for _, field := range []string{"booktitle", TitleField, "address"} {
+		if text := l.EntryFieldValueity(key, field); text != "" {
+			l.parseVenueText(&v, text, year)
+			sources = append(sources, text)
+		}
+	}
+	v.ParsedFrom = strings.Join(sources, " | ")
+	return v
+}
+
+// ── entry_venues table ──────────────────────────────────────────────────────
+
+func ensureEntryVenuesTableExists() {
+	tryCreateTableIfNeeded(`
+		CREATE TABLE IF NOT EXISTS entry_venues (
+		  entry_key     TEXT PRIMARY KEY,
+		  series        TEXT NOT NULL DEFAULT '',
+		  series_number TEXT NOT NULL DEFAULT '',
+		  city          TEXT NOT NULL DEFAULT '',
+		  region        TEXT NOT NULL DEFAULT '',
+		  country       TEXT NOT NULL DEFAULT '',
+		  event_start   TEXT NOT NULL DEFAULT '',
+		  event_end     TEXT NOT NULL DEFAULT '',
+		  parsed_from   TEXT NOT NULL DEFAULT '',
+		  FOREIGN KEY (entry_key) REFERENCES bib_entry_keys(entry_key) ON DELETE CASCADE
+		);`)
+}
+
+const entryVenueColumns = `series, series_number, city, region, country, event_start, event_end, parsed_from`
+
+func scanEntryVenue(scan func(...any) error) (TVenue, error) {
+	var v TVenue
+	err := scan(&v.Series, &v.SeriesNumber, &v.City, &v.Region, &v.Country, &v.EventStart, &v.EventEnd, &v.ParsedFrom)
+	return v, err
+}
+
+// EntryVenue returns the stored venue of key, and whether there is one.
+func (l *TBibTeXLibrary) EntryVenue(key string) (TVenue, bool) {
+	v, err := scanEntryVenue(bibQueryRow(`SELECT `+entryVenueColumns+` FROM entry_venues WHERE entry_key = ?`, key).Scan)
+	return v, err == nil
+}
+
+func (l *TBibTeXLibrary) setEntryVenue(key string, v TVenue) {
+	dbExecSave("setEntryVenue", `INSERT INTO entry_venues (entry_key, `+entryVenueColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(entry_key) DO UPDATE SET series = excluded.series, series_number = excluded.series_number,
+		  city = excluded.city, region = excluded.region, country = excluded.country,
+		  event_start = excluded.event_start, event_end = excluded.event_end, parsed_from = excluded.parsed_from`,
+		key, v.Series, v.SeriesNumber, v.City, v.Region, v.Country, v.EventStart, v.EventEnd, v.ParsedFrom)
+}
+
+// forEachEntryVenue calls fn for every stored venue.
+func forEachEntryVenue(fn func(key string, v TVenue)) {
+	rows, err := bibQuery(`SELECT entry_key, ` + entryVenueColumns + ` FROM entry_venues ORDER BY entry_key`)
+	if err != nil {
+		dbInteraction.Warning("Could not query entry_venues: %s", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		v, err := scanEntryVenue(func(dest ...any) error { return rows.Scan(append([]any{&key}, dest...)...) })
+		if err == nil {
+			fn(key, v)
+		}
+	}
+}
+
+func ExportEntryVenues() {
+	ensureTablesDir()
+	path := tablesFilePath(EntryVenuesFilePath)
+	rows, err := db.Query(`SELECT entry_key, ` + entryVenueColumns + ` FROM entry_venues ORDER BY entry_key`)
+	if err != nil {
+		dbInteraction.Warning("Could not query entry_venues: %s", err)
+		return
+	}
+	defer rows.Close()
+	writeCSVExport(path, rows, 9)
+}
+
+func importEntryVenuesFromCSV(replace bool) {
+	path := tablesFilePath(EntryVenuesFilePath)
+	upsert := `INSERT OR REPLACE INTO entry_venues (entry_key, ` + entryVenueColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	validate := func(f []string) bool { return len(f) >= 8 && f[0] != "" }
+	var clearFn func()
+	if replace {
+		clearFn = func() { dbExecSave("importEntryVenuesFromCSV: clear", `DELETE FROM entry_venues`) }
+	}
+	n, ok := importTwoPhase(path, validate, clearFn, func(tx *sql.Tx, f []string) {
+		if len(f) < 9 {
+			f = append(f, "")
+		}
+		if _, err := tx.Exec(upsert, f[0], f[1], f[2], f[3], f[4], f[5], f[6], f[7], f[8]); err != nil {
+			dbInteraction.Warning("importEntryVenuesFromCSV: row write failed for %s: %s", f[0], err)
+			markDbWriteFailed(fmt.Sprintf("importEntryVenuesFromCSV: row write failed (key=%s): %s", f[0], err))
+		}
+	})
+	if ok {
+		importReport(map[bool]string{true: "Imported", false: "Added"}[replace], path, n)
+	}
+}
+
+// ── Checks ──────────────────────────────────────────────────────────────────
+
+// CheckEntryVenue (re)parses the venue of a proceedings entry when the texts it
+// was parsed from changed, and checks it against the entry itself.
+func (l *TBibTeXLibrary) CheckEntryVenue(entry *TBibTeXEntry) {
+	if entry.EntryType() != "proceedings" {
+		return
+	}
+	stored, found := l.EntryVenue(entry.Key)
+	if !found || stored.ParsedFrom != "" {
+		if parsed := l.ParseVenue(entry.Key); !found || parsed.ParsedFrom != stored.ParsedFrom {
+			if parsed.IsEmpty() {
+				if found {
+					dbExecSave("CheckEntryVenue: delete", `DELETE FROM entry_venues WHERE entry_key = ?`, entry.Key)
+				}
+				return
+			}
+			l.setEntryVenue(entry.Key, parsed)
+			stored = parsed
+		}
+	}
+
+	if year := entry.FieldValue("year"); year != "" && stored.Year() != "" && stored.Year() != year {
+		l.ReportEntryWarning(entry.Key, WarningVenueYearMismatch, stored.EventDate(), year)
+	}
+	if stored.Region != "" && stored.Country != "" {
+		if country := l.StateToCountry[stored.Region]; country != "" && l.countryISOCode(country) != stored.Country {
+			l.ReportEntryWarning(entry.Key, WarningVenueRegionCountry, stored.Region, country, stored.Country)
+		}
+	}
+}
+
+// CheckVenueSeries checks the venues of the proceedings of each series against
+// one another: one spelling of the series name, and series numbers that are
+// unique and increase with the years of the events.
+func (l *TBibTeXLibrary) CheckVenueSeries() {
+	type tEvent struct {
+		key, series, year string
+		number            int
+	}
+	series := map[string][]tEvent{}
+	forEachEntryVenue(func(key string, v TVenue) {
+		if v.Series == "" {
+			return
+		}
+		year := v.Year()
+		if year == "" {
+			year = l.EntryFieldValueity(key, "year")
+		}
+		number, _ := strconv.Atoi(v.SeriesNumber)
+		series[strings.ToLower(v.Series)] = append(series[strings.ToLower(v.Series)], tEvent{key, v.Series, year, number})
+	})
+
+	for _, name := range sortedMapKeys(series) {
+		events := series[name]
+		sort.Slice(events, func(i, j int) bool {
+			if events[i].year != events[j].year {
+				return events[i].year < events[j].year
+			}
+			return events[i].key < events[j].key
+		})
+
+		// The most common spelling is taken as the right one.
+		spellings := map[string]int{}
+		for _, e := range events {
+			spellings[e.series]++
+		}
+		preferred := ""
+		for _, spelling := range sortedMapKeys(spellings) {
+			if spellings[spelling] > spellings[preferred] {
+				preferred = spelling
+			}
+		}
+
+		byNumber := map[int]tEvent{}
+		var previous *tEvent
+		for i, e := range events {
+			if e.series != preferred {
+				l.ReportEntryWarning(e.key, WarningVenueSeriesSpelling, e.series, preferred)
+			}
+			if e.number == 0 {
+				continue
+			}
+			if other, seen := byNumber[e.number]; seen && other.year != e.year {
+				l.ReportEntryWarning(e.key, WarningVenueSeriesNumberReused, preferred, e.number, other.key, other.year)
+				l.EntryInvolvedInWarning(other.key)
+			}
+			byNumber[e.number] = e
+			if previous != nil && previous.year < e.year && previous.number >= e.number {
+				l.ReportEntryWarning(e.key, WarningVenueSeriesOrder, preferred, e.number, e.year, previous.number, previous.key, previous.year)
+				l.EntryInvolvedInWarning(previous.key)
+			}
+			previous = &events[i]
+		}
+	}
+}
+
+// venueFieldAssignments returns the venue of a proceedings entry as extra output
+// fields for a sync file with venue_fields: BibLaTeX venue and eventdate with
+// biber_mode, otherwise a BibTeX address. Fields the entry has itself are kept.
+func (l *TBibTeXLibrary) venueFieldAssignments(entry *TBibTeXEntry, cfg TBibGetConfig) string {
+	v, found := l.EntryVenue(entry.Key)
+	if !found {
+		return ""
+	}
+	result := ""
+	location := l.venueLocation(v)
+	if cfg.BiberMode {
+		if location != "" && entry.FieldValue("venue") == "" {
+			result += FormatBibTeXFieldAssignment("", "venue", location)
+		}
+		if v.EventStart != "" && entry.FieldValue("eventdate") == "" {
+			result += FormatBibTeXFieldAssignment("", "eventdate", v.EventDate())
+		}
+	} else if location != "" && entry.FieldValue("address") == "" {
+		result += FormatBibTeXFieldAssignment("", "address", location)
+	}
+	return result
+}
+
+// ReportVenues prints the stored venues, optionally of one series only.
+func (l *TBibTeXLibrary) ReportVenues(series string) {
+	count := 0
+	forEachEntryVenue(func(key string, v TVenue) {
+		if series != "" && !strings.EqualFold(v.Series, series) {
+			return
+		}
+		count++
+		name := strings.TrimSpace(v.Series + " " + v.SeriesNumber)
+		fmt.Printf("%s\t%s\t%s\t%s\n", key, name, v.EventDate(), l.venueLocation(v))
+	})
+	l.Progress(ProgressVenuesListed, count)
+}
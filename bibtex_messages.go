@@ -77,6 +77,24 @@ const (
 	WarningHarvestDblpCandidatesFound  = "No DBLP key on source entry '%s' — found %d candidate(s)"
 	ProgressHarvestParsed              = "harvest: %d entr%s parsed from %s"
 	ProgressHarvestSkipped             = "harvest: no entries found in source bib"
+	ProgressHarvestPDFsReading         = "harvest: reading PDF files"
+	ProgressHarvestPDFIdentified       = "harvest: %s — %s"
+	ProgressHarvestPDFsNone            = "harvest: no PDF files found in %s"
+	WarningHarvestPDFInvalid           = "harvest: %s is not a valid PDF file — skipped"
+
+	ProgressMergeLibraryOpened        = "merge_library: %d entr%s and %d contributor(s) in %s"
+	ProgressMergeLibraryContributors  = "merge_library: %d contributor(s) matched, %d added"
+	ProgressMergeLibraryEntries       = "merge_library: %d entr(y/ies) merged into existing ones, %d added"
+	ProgressMergeLibraryDecisions     = "merge_library: %d non-double pair(s), %d DBLP waiver(s), %d superseded value(s), %d key alias(es)/hint(s) taken over"
+	WarningMergeLibraryKeyCollision   = "merge_library: foreign key %s is also one of our own entry keys — not recorded as an alias of %s"
+
+	ProgressUndoNothing     = "undo: the session journal is empty — nothing to undo"
+	ProgressUndoSession     = "undo: reverting %d change(s) of session %s (%s)"
+	ProgressUndoTicker      = "undo: reverting changes"
+	WarningUndoBarrier      = "undo: session %s re-imported the bib file (%s) — only changes made after that can be undone; use -restore for earlier ones"
+	WarningUndoUnknownTable = "undo: table %s no longer has a primary key — skipping journal row %d"
+
+	ProgressSearchIndexTicker = "search: indexing titles, abstracts and notes"
 
 	WarningURLDead              = "URL appears unreachable or lacks human content (%s): %s — setting urldate to %s"
 	QuestionDoublePdfWaive      = "PDF shared by multiple entries — waive, merge, or skip? (w=waive all, m=merge, s=skip)"
@@ -86,6 +104,38 @@ const (
 	WarningLoneProceedings    = "Lone proceedings (no children): %s"
 	QuestionLoneProceedings   = "Waive, delete (+ hints/oldies), enter DBLP key, or skip? (w=waive, d=delete, k=dblp key, s=skip)"
 
+	WarningLintRule        = "Lint %s %s: %s" // severity, rule, message; must start with lintWarningPrefix
+	QuestionLintRule       = "Waive this lint rule for the entry, or skip? (w=waive, s=skip)"
+	ProgressLintRuleWaived = "Waived lint rule %s for %s"
+
+	QuestionNamePair           = "Keep current name? (Y/N = globally, y/n = this entry only), edit canonical (e), different people (d), quit (q)?"
+	WarningAnswersRecordFailed = "Cannot write answers file %s: %v"
+	WarningAnswerSkipped       = "No recorded answer for %q — answered %q"
+	WarningAnswerDeferred      = "No recorded answer for %q — deferred (session ended)"
+	ProgressAnswersReplayed    = "Answers: %d replayed, %d without a recorded answer"
+	ProgressAnswersOpenWritten = "Answers: %d open question(s) written to %s"
+
+	WarningHomeworkTUINoTerminal = "-tui needs a terminal and cannot replay -answers; running the linear homework instead"
+	ProgressHomeworkTUIDone      = "Homework: %d decided, %d skipped, %d still pending"
+
+	ProgressReviewServerListening = "Review server on http://%s/ with %d item(s) to review (Ctrl-C to stop)"
+	ProgressReviewDecision        = "Review: %s"
+	WarningReviewServerFailed     = "Review server: %v"
+
+	WarningPolicyFileInvalid  = "Conflict policy %s ignored: %v"
+	WarningPolicyRuleInvalid  = "Conflict policy rule %q ignored: %s"
+	WarningPolicyReportFailed = "Cannot read the conflict policy decisions: %v"
+	ProgressPolicyMerge       = "Merging %s into %s by conflict policy rule %q"
+	ProgressPolicyNoDecisions = "No conflict policy decisions recorded"
+
+	WarningHookUnknownEvent   = "Hook with unknown event %q ignored (known events: %s)"
+	WarningHookWithoutAction  = "Hook for %s has neither actions nor a command"
+	WarningHookCommandFailed  = "Hook command %q for %s failed: %v"
+	WarningHookCommandOutput  = "Hook command %q for %s printed no valid result: %v"
+	WarningHookChangeRejected = "Hook command %q requested a change to %s (%s) that cannot be made"
+	ProgressHookActions       = "Hook %s: entry_actions made %d change(s)"
+	ProgressHookCommand       = "Hook command %q for %s made %d change(s)"
+
 	QuestionSubsetBibChanged  = "Bib entry changed — merge into library? (field challenges will follow)"
 	QuestionSubsetDeleteEntry = "Entry removed from subset bib — delete from library?"
 	QuestionSubsetBothChanged = "Both bib and DB changed — apply bib changes to library? (y=yes, n=keep DB version)"
@@ -121,8 +171,29 @@ const (
 	WarningHTMLDisguisedAsPDF             = "Discarding HTML file disguised as PDF for %s: %s"
 	WarningPSConversionFailed             = "PS→PDF conversion failed for %s: %v"
 	ProgressConvertedPSToPDF              = "Converted PostScript to PDF for %s"
+	ProgressVerifyingPDFContents          = "Verifying PDF contents against entry metadata"
+	ProgressPDFContentMatch               = "PDF for %s matches its entry (score %.2f: %s)"
+	ProgressPDFContentUndetermined        = "PDF for %s could not be verified: %s"
+	ProgressPDFMovedToOwner               = "Moved PDF: %s.pdf → %s.pdf"
+	ProgressPDFsSwapped                   = "Swapped the PDFs of %s and %s"
+	WarningPDFContentMismatch             = "PDF for %s does not seem to match its entry (score %.2f: %s)\n  Title: %s\n  Path: %s"
+	WarningPDFBelongsToOther              = "\n  It seems to be the PDF of %s (%s)\n  Title: %s"
+	WarningPDFOwnerMismatchToo            = "\n  The PDF of %s does not match its entry either."
+	WarningPDFOwnerHasOwnPDF              = "\n  %s already has a PDF of its own that matches it."
+	WarningNoPDFForEntry                  = "There is no PDF for %s."
+	ProgressEmbeddingPDFMetadata          = "Embedding entry metadata into PDFs"
+	ProgressEmbeddedPDFMetadata           = "Embedded metadata into %d PDF(s); %d already up to date"
+	ProgressRefreshedPDFMetadata          = "Refreshed the metadata embedded in the PDF of %s"
+	WarningPDFMetadataNotEmbedded         = "Could not embed metadata into the PDF of %s: %s"
+	ProgressAttachmentAdded               = "Attached %s to %s as %s: %s"
+	ProgressAttachmentRenamed             = "Renamed attachment: %s → %s"
+	ProgressAttachmentDuplicateTrashed    = "Trashed attachment %s (same content as %s)"
+	WarningAttachmentNotAdded             = "Could not attach %s to %s: %s"
+	WarningAttachmentNotRenamed           = "Could not rename attachment %s → %s: %s"
+	WarningAttachmentNotMoved             = "Could not move attachment %s to %s"
+	WarningAttachUnknownKey               = "There is no entry with key %s."
 	ProgressFetchingDBLPEntry             = "Fetching DBLP entry for %s from dblp.org"
-	ProgressDownloadingPDF                = "Downloading PDF for %s: %s"
+	ProgressDownloadingPDFFrom            = "Downloading PDF for %s (%s): %s"
 	ProgressPDFDownloaded                 = "Downloaded PDF for %s → %s"
 	WarningPDFDownloadFailed              = "Download failed for %s (%s): %v"
 
@@ -172,6 +243,15 @@ const (
 	WarningCannotDeriveUniquePreferredAlias  = "Cannot derive unique preferred alias (base: %s): all title keywords already in use."
 	WarningNoTitleKeywordsForPreferredAlias  = "Cannot derive preferred alias (base: %s): no usable title keywords found."
 	ProgressGeneratedPreferredAlias = "  Generated preferred alias %s for %s"
+	ProgressReplacedPreferredAlias  = "  Replaced preferred alias %s by %s for %s (alias template changed; the old alias still resolves)"
+	WarningCannotDeriveTemplateAlias = "Cannot derive preferred alias from template %q: no alternative can be filled."
+	WarningUnknownAliasPlaceholder   = "Unknown alias template placeholder %s."
+	WarningVenueYearMismatch         = "Event date %s of the venue does not match year %s."
+	WarningVenueRegionCountry        = "Venue region %s lies in %s, not in %s."
+	WarningVenueSeriesSpelling       = "Series %s is elsewhere spelled %s."
+	WarningVenueSeriesNumberReused   = "Series %s number %d is also used by %s (%s)."
+	WarningVenueSeriesOrder          = "Series %s number %d (%s) does not follow number %d of %s (%s)."
+	ProgressVenuesListed             = "Listed %d venue(s)"
 	ProgressRemovedRedundantURL     = "Removed redundant URL for %s: %s"
 	WarningBadISBN                       = "Wrong ISBN: %q."
 	WarningISBNMismatchFromCrossrefDOI   = "Crossref to %s: DOI-derived ISBN %s conflicts with parent ISBN %s."
@@ -225,4 +305,6 @@ const (
 	StatTitleGroupsWithUnresolvedDuplicates = "Title groups with unresolved duplicates"
 	StatEntriesWithUnresolvedDblpCandidates = "Entries with unresolved DBLP candidates"
 	StatContributorsWithOrcidNotYetEnriched = "Contributors with ORCID not yet enriched"
+	StatEntriesWithLintFindings             = "Entries with lint findings"
+	StatPolicyDecisions                     = "Conflict policy decisions"
 )
@@ -294,6 +294,17 @@ func (t *TProgressTicker) DoneQuiet() {
 	activeTicker = nil
 }
 
+// questionAnsweredHook, when set, is called after every answered question; the
+// session journal uses it to attribute the writes that follow to a new decision.
+var questionAnsweredHook func()
+
+// answered signals that the user gave a valid answer to a question.
+func (r *TInteraction) answered() {
+	if questionAnsweredHook != nil {
+		questionAnsweredHook()
+	}
+}
+
 type TInteraction struct {
 	silenced           bool
 	progressSuppressed bool // suppress Progress() output without silencing warnings/questions
@@ -318,18 +329,22 @@ func (r *TInteraction) QuestionsAnswered() int {
 // ResetQuestionFlag is a no-op retained for call-site compatibility; to be removed in 17.2 cleanup.
 func (r *TInteraction) ResetQuestionFlag() {}
 
-// AskForInput prints prompt and returns the trimmed line the user types.
+// AskForInput prints prompt and returns the trimmed line the user types. context
+// names the keys the question is about, for its answer id (see questionID).
 // Typing "q" quits immediately (see quitNow) and never returns.
-// In non-TTY sessions, quits immediately (see quitNow) and never returns.
-func (r *TInteraction) AskForInput(prompt string) (string, error) {
-	if !isTTY {
+// In non-TTY sessions, quits immediately (see quitNow) and never returns, unless
+// answers are replayed (see interaction_answers.go).
+func (r *TInteraction) AskForInput(prompt string, context ...any) (string, error) {
+	if !isTTY && !answersReplaying() {
 		r.quitRequested = true
 		quitNow()
 		return "", nil
 	}
-	SpinnerInterrupt()
-	fmt.Fprintf(os.Stderr, "\nQUESTION: %s (q=quit): ", prompt)
-	line := readStdinLine()
+	if isTTY {
+		SpinnerInterrupt()
+		fmt.Fprintf(os.Stderr, "\nQUESTION: %s (q=quit): ", prompt)
+	}
+	line := answerLine(prompt, context, nil, "")
 	if line == "q" {
 		r.quitRequested = true
 		fmt.Fprintln(os.Stderr)
@@ -337,6 +352,7 @@ func (r *TInteraction) AskForInput(prompt string) (string, error) {
 		return "", nil
 	}
 	fmt.Fprintln(os.Stderr)
+	r.answered()
 	return line, nil
 }
 
@@ -480,7 +496,9 @@ func (r *TInteraction) WarningGrouped(warning string, context ...any) bool {
 // immediately (see quitNow) — it never returns to the caller.
 // When warning is non-empty it is printed immediately (bypassing any deferral)
 // as inline context for the question.
-// In non-TTY sessions, quits immediately (see quitNow) and never returns.
+// In non-TTY sessions, quits immediately (see quitNow) and never returns, unless
+// answers are replayed (see interaction_answers.go). With an empty warning,
+// context only names the keys the question is about (see questionID).
 func (r *TInteraction) WarningQuestion(question string, options TStringSet, warning string, context ...any) string {
 	return r.warningQuestionCore(question, options, warning, false, context...)
 }
@@ -495,13 +513,15 @@ func (r *TInteraction) WarningQuestionGrouped(question string, options TStringSe
 }
 
 func (r *TInteraction) warningQuestionCore(question string, options TStringSet, warning string, grouped bool, context ...any) string {
-	if !isTTY {
+	if !isTTY && !answersReplaying() {
 		r.quitRequested = true
 		quitNow()
 		return "q"
 	}
 	r.questionsAnswered++
 
+	kind := questionKind(question, warning)
+	id := questionID(kind, context, options.ElementsSorted())
 	optionSet := "("
 	separator := ""
 	for _, option := range options.ElementsSorted() {
@@ -519,12 +539,15 @@ func (r *TInteraction) warningQuestionCore(question string, options TStringSet,
 		} else {
 			r.printWarningLine(warning, context...)
 		}
-		fmt.Fprint(os.Stderr, "QUESTION: "+question+" "+optionSet)
+		stderrPrintf("QUESTION: %s %s", question, optionSet)
 	} else {
 		SpinnerInterrupt()
-		fmt.Fprint(os.Stderr, "\nQUESTION: "+question+" "+optionSet)
+		stderrPrintf("\nQUESTION: %s %s", question, optionSet)
 	}
 
+	if answersReplaying() {
+		return r.replayedOption(replayAnswer(id, options.ElementsSorted(), skipAnswer(kind, options.ElementsSorted())))
+	}
 	for {
 		option := readStdinLine()
 		if option == "q" {
@@ -535,17 +558,31 @@ func (r *TInteraction) warningQuestionCore(question string, options TStringSet,
 		}
 		if options.Contains(option) {
 			fmt.Fprintln(os.Stderr)
+			recordAnswer(id, option)
+			r.answered()
 			return option
 		}
 		fmt.Fprint(os.Stderr, optionSet)
 	}
 }
 
+// replayedOption completes a question answered from the answers file; a
+// deferred question ("q") ends the session like a typed "q".
+func (r *TInteraction) replayedOption(option string) string {
+	if option == "q" {
+		r.quitRequested = true
+		quitNow()
+		return "q"
+	}
+	r.answered()
+	return option
+}
+
 // WarningQuestionOrdered is like WarningQuestion but uses the caller-supplied slice
 // to control the display order of options (useful when ASCII sort would mis-group them).
 // In non-TTY sessions, quits immediately (see quitNow) and never returns.
 func (r *TInteraction) WarningQuestionOrdered(question string, ordered []string, warning string, context ...any) string {
-	if !isTTY {
+	if !isTTY && !answersReplaying() {
 		r.quitRequested = true
 		quitNow()
 		return "q"
@@ -569,14 +606,19 @@ func (r *TInteraction) WarningQuestionOrdered(question string, ordered []string,
 
 	r.questionsAnswered++
 
+	kind := questionKind(question, warning)
+	id := questionID(kind, context, ordered)
 	if warning != "" {
 		r.printWarningLine(warning, context...)
-		fmt.Fprint(os.Stderr, "QUESTION: "+question+" "+optionSet)
+		stderrPrintf("QUESTION: %s %s", question, optionSet)
 	} else {
 		SpinnerInterrupt()
-		fmt.Fprint(os.Stderr, "\nQUESTION: "+question+" "+optionSet)
+		stderrPrintf("\nQUESTION: %s %s", question, optionSet)
 	}
 
+	if answersReplaying() {
+		return r.replayedOption(replayAnswer(id, ordered, skipAnswer(kind, ordered)))
+	}
 	for {
 		option := readStdinLine()
 		if option == "q" {
@@ -587,6 +629,8 @@ func (r *TInteraction) WarningQuestionOrdered(question string, ordered []string,
 		}
 		if valid.Contains(option) {
 			fmt.Fprintln(os.Stderr)
+			recordAnswer(id, option)
+			r.answered()
 			return option
 		}
 		fmt.Fprint(os.Stderr, optionSet)
@@ -644,22 +688,30 @@ func printStatBlock(header string, rows []statRow, trailingBlank bool) {
 }
 
 // ConfirmAction always prompts the user for y/n/q confirmation, even when the
-// interaction is silenced. Use for safety gates that must not be skipped
+// interaction is silenced; context is as for AskForInput. Use for safety gates that must not be skipped
 // by batch-mode callers. "q" quits immediately (see quitNow) and never returns.
-// In non-TTY sessions, quits immediately (see quitNow) and never returns.
-func (r *TInteraction) ConfirmAction(prompt string) bool {
-	if !isTTY {
+// In non-TTY sessions, quits immediately (see quitNow) and never returns, unless
+// answers are replayed (see interaction_answers.go).
+func (r *TInteraction) ConfirmAction(prompt string, context ...any) bool {
+	if !isTTY && !answersReplaying() {
 		r.quitRequested = true
 		quitNow()
 		return false
 	}
 	r.questionsAnswered++
+	yesNo := []string{"n", "y"}
+	id := questionID(prompt, context, yesNo)
 	SpinnerInterrupt()
-	fmt.Fprintf(os.Stderr, "\nCONFIRM:  %s (y/n/q): ", prompt)
+	stderrPrintf("\nCONFIRM:  %s (y/n/q): ", prompt)
+	if answersReplaying() {
+		return r.replayedOption(replayAnswer(id, yesNo, "n")) == "y"
+	}
 	for {
 		answer := readStdinLine()
 		if answer == "y" || answer == "n" {
 			fmt.Fprintln(os.Stderr)
+			recordAnswer(id, answer)
+			r.answered()
 			return answer == "y"
 		}
 		if answer == "q" {
@@ -0,0 +1,289 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - interaction
+ *   - interaction_answers
+ *
+ * Recording and replaying the answers to interactive questions, so homework can
+ * be prepared offline, reviewed and shared.
+ *
+ * Every question has a stable id: its kind (the question text), the keys it is
+ * about and its options. The keys are the single-line string arguments the
+ * question is asked with (the arguments of its warning, or the context given to
+ * ConfirmAction, AskForInput and answerLine), or those named with askingAbout
+ * when its warning is a display that cannot carry them. They never come from
+ * earlier, unrelated warnings:
+ *
+ *	Lone proceedings (no children): ... | EP12ab | d/k/s/w
+ *
+ * -record_answers <file> appends each answer given at the terminal to file, as
+ * one JSON object {"id": ..., "answer": ...} per line. -answers <file> replays
+ * them without reading stdin. A question without a (valid) recorded answer is
+ * skipped where the question has a skip answer, and otherwise deferred by ending
+ * the session. Either way it is listed at the end of the session and written to
+ * <file>.open with an empty answer, ready to be filled in for the next replay.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// answersOpenExtension is appended to the -answers file for the questions it
+	// had no answer for.
+	answersOpenExtension = ".open"
+
+	// answerKeyMaxLength bounds the string arguments taken as keys of a question;
+	// longer ones are displays (entries, blocks) rather than keys.
+	answerKeyMaxLength = 200
+)
+
+// TRecordedAnswer is one line of an answers file.
+type TRecordedAnswer struct {
+	ID     string `json:"id"`
+	Answer string `json:"answer"`
+}
+
+// TOpenQuestion is a replayed question that had no recorded answer.
+type TOpenQuestion struct {
+	ID       string `json:"id"`
+	Answer   string `json:"answer"`             // always "", to be filled in
+	Fallback string `json:"fallback,omitempty"` // the answer used instead
+	Deferred bool   `json:"deferred,omitempty"` // no skip answer: the session was ended
+}
+
+var (
+	answersRecordFile *os.File          // -record_answers
+	answersReplayPath string            // -answers
+	answersReplayed   map[string]string // id → answer, from answersReplayPath
+	answersReplayUsed int
+	answersOpen       []TOpenQuestion
+
+	// answerNextKeys are the keys of the next question, named by askingAbout.
+	answerNextKeys []string
+
+	// answerSkips gives the skip answer of questions whose options do not make
+	// it obvious (see skipAnswer).
+	answerSkips = map[string]string{
+		QuestionNamePair:         "y", // keep the current name, for this entry only
+		QuestionMergePDFConflict: "k",
+	}
+)
+
+// openAnswerFiles opens the -record_answers file for appending and loads the
+// -answers file.
+func openAnswerFiles(recordPath, replayPath string) error {
+	if recordPath != "" {
+		f, err := os.OpenFile(expandHome(recordPath), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		answersRecordFile = f
+	}
+	if replayPath == "" {
+		return nil
+	}
+	answersReplayPath = expandHome(replayPath)
+	f, err := os.Open(answersReplayPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	answersReplayed = map[string]string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var a TRecordedAnswer
+		if err := json.Unmarshal([]byte(text), &a); err != nil {
+			return fmt.Errorf("%s line %d: %w", answersReplayPath, line, err)
+		}
+		if a.ID != "" {
+			answersReplayed[a.ID] = a.Answer // a later answer overrides an earlier one
+		}
+	}
+	return scanner.Err()
+}
+
+// answersReplaying reports whether questions are answered from an answers file.
+func answersReplaying() bool {
+	return answersReplayed != nil
+}
+
+// answerKeys returns the single-line string arguments of a question's warning.
+func answerKeys(context []any) []string {
+	var keys []string
+	for _, arg := range context {
+		if s, ok := arg.(string); ok {
+			s = strings.TrimSpace(s)
+			if s != "" && len(s) <= answerKeyMaxLength && !strings.ContainsAny(s, "\n\r") {
+				keys = append(keys, s)
+			}
+		}
+	}
+	return keys
+}
+
+// askingAbout names the keys of the next question, for questions whose warning
+// is a display (e.g. two entries side by side) rather than a list of keys.
+func askingAbout(keys ...string) {
+	answerNextKeys = keys
+}
+
+// questionID returns the stable id of a question of the given kind, with the
+// given context and options.
+func questionID(kind string, context []any, options []string) string {
+	keys := answerKeys(context)
+	if answerNextKeys != nil {
+		keys = append(answerNextKeys, keys...)
+		answerNextKeys = nil
+	}
+	return strings.TrimSpace(kind) + " | " + strings.Join(keys, "; ") + " | " + strings.Join(options, "/")
+}
+
+// questionKind returns the kind of a question: its text, or the warning
+// template for the few questions that only have a warning.
+func questionKind(question, warning string) string {
+	if strings.TrimSpace(question) == "" {
+		return warning
+	}
+	return question
+}
+
+// skipAnswer returns the option that leaves things as they are ("" for free
+// input), or "q" when the question has none and must be deferred instead.
+func skipAnswer(kind string, options []string) string {
+	has := TStringSetNew()
+	has.Add(options...)
+	if skip, ok := answerSkips[kind]; ok && has.Contains(skip) {
+		return skip
+	}
+	switch {
+	case has.Contains("s"):
+		return "s"
+	case has.Contains("0"):
+		return "0" // none of the candidates
+	case has.Contains("n") && has.Contains("y") && has.Size() == 2:
+		return "n"
+	case len(options) == 0:
+		return "" // free input: an empty line
+	}
+	return "q"
+}
+
+// recordAnswer appends an answer given at the terminal to the -record_answers file.
+func recordAnswer(id, answer string) {
+	if answersRecordFile == nil {
+		return
+	}
+	line, err := json.Marshal(TRecordedAnswer{ID: id, Answer: answer})
+	if err == nil {
+		_, err = answersRecordFile.Write(append(line, '\n'))
+	}
+	if err != nil {
+		Reporting.Warning(WarningAnswersRecordFailed, answersRecordFile.Name(), err)
+		answersRecordFile = nil
+	}
+}
+
+// replayAnswer returns the recorded answer to the question id when it is one of
+// options (nil accepts any answer), and otherwise registers the question as open
+// and returns skip. A skip of "q" means the question is deferred: the caller
+// ends the session.
+func replayAnswer(id string, options []string, skip string) string {
+	if answer, ok := answersReplayed[id]; ok && validAnswer(answer, options) {
+		answersReplayUsed++
+		if isTTY {
+			fmt.Fprintf(os.Stderr, "%s [replayed]\n", answer)
+		}
+		return answer
+	}
+	if skip == "q" {
+		answersOpen = append(answersOpen, TOpenQuestion{ID: id, Deferred: true})
+	} else {
+		answersOpen = append(answersOpen, TOpenQuestion{ID: id, Fallback: skip})
+	}
+	if isTTY {
+		if skip == "q" {
+			fmt.Fprintln(os.Stderr, "[no recorded answer — deferred]")
+		} else {
+			fmt.Fprintf(os.Stderr, "%s [no recorded answer — skipped]\n", skip)
+		}
+	}
+	return skip
+}
+
+// validAnswer reports whether answer is one of options; nil accepts any answer.
+func validAnswer(answer string, options []string) bool {
+	if options == nil {
+		return true
+	}
+	for _, option := range options {
+		if answer == option {
+			return true
+		}
+	}
+	return false
+}
+
+// answerLine reads the answer to a question that is prompted for by the caller
+// itself: from the answers file when replaying, and from stdin (recording it
+// when valid) otherwise. options lists the valid answers; nil accepts any input.
+func answerLine(kind string, context []any, options []string, skip string) string {
+	id := questionID(kind, context, options)
+	if answersReplaying() {
+		return replayAnswer(id, options, skip)
+	}
+	answer := readStdinLine()
+	if answer != "q" && validAnswer(answer, options) {
+		recordAnswer(id, answer)
+	}
+	return answer
+}
+
+// reportOpenAnswers lists the replayed questions that had no recorded answer and
+// writes them to the -answers file's .open companion.
+func reportOpenAnswers() {
+	if !answersReplaying() {
+		return
+	}
+	Reporting.Progress(ProgressAnswersReplayed, answersReplayUsed, len(answersOpen))
+	if len(answersOpen) == 0 {
+		return
+	}
+	sort.SliceStable(answersOpen, func(i, j int) bool { return answersOpen[i].ID < answersOpen[j].ID })
+	var out strings.Builder
+	for _, q := range answersOpen {
+		if q.Deferred {
+			Reporting.Warning(WarningAnswerDeferred, q.ID)
+		} else {
+			Reporting.Warning(WarningAnswerSkipped, q.ID, q.Fallback)
+		}
+		line, _ := json.Marshal(q)
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	openPath := answersReplayPath + answersOpenExtension
+	if err := os.WriteFile(openPath, []byte(out.String()), 0644); err != nil {
+		Reporting.Warning(WarningAnswersRecordFailed, openPath, err)
+		return
+	}
+	Reporting.Progress(ProgressAnswersOpenWritten, len(answersOpen), openPath)
+	answersOpen = nil
+}
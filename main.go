@@ -70,6 +70,8 @@ var (
 	cmdFix                     bool // -fix: apply full per-entry checks when combined with -sync or -harvest
 	cmdPull                    bool // -pull: with -sync, skip up-sync (phase 1); only write bib output from DB
 	cmdMatchedOrcidDataOnly    bool // -matched_orcid_data_only: skip ORCID challenges in step 3
+	queryColumns               string // -columns: output columns of -find_entries queries
+	outputFormat               string // -format: tsv (default) or json
 )
 
 // stderrPrintf writes to stderr only when running in a TTY session.
@@ -195,7 +197,10 @@ func parseSyncBibFile(path string) bool {
 	// the previous in-memory state (clearBibTables only clears the DB tables).
 	Library.GroupEntries = TStringSetMap{}
 	Library.Comments = nil
+	Library.SmartGroups = nil
 	Library.Progress(ProgressClearingBibTables)
+	pauseSessionJournal("re-import of " + filepath.Base(path))
+	defer resumeSessionJournal()
 	clearBibTables()
 	beginBibTransaction()
 	parseCh := make(chan bool, 1)
@@ -241,6 +246,7 @@ syncParseLoop:
 	}
 	bibEntriesModified = true
 	setTableDirty("dblp_hierarchy")
+	rebuildEntrySearchIndex()
 	initEntryCache()
 	reportCacheMode()
 	refreshBibDbTimestamp()
@@ -255,7 +261,10 @@ func parseBibIntoDb() bool {
 	}
 	Library.GroupEntries = TStringSetMap{}
 	Library.Comments = nil
+	Library.SmartGroups = nil
 	Library.Progress(ProgressClearingBibTables)
+	pauseSessionJournal("re-import of " + filepath.Base(BibFile))
+	defer resumeSessionJournal()
 	clearBibTables()
 	beginBibTransaction()
 	readCh := make(chan bool, 1)
@@ -293,6 +302,7 @@ bibParseLoop:
 
 	bibEntriesModified = false // parse is a load, not a modification
 	setTableDirty("dblp_hierarchy")
+	rebuildEntrySearchIndex()
 	initEntryCache()
 	reportCacheMode()
 	refreshBibDbTimestamp()
@@ -352,7 +362,7 @@ func printSessionStats() {
 	// actionable backlog that in fact never shrinks; scope to what -triage_author_mappings
 	// (and its auto-run at the end of -update_all_dblp_entries) can actually resolve, same
 	// as the homework line below.
-	bibQueryRow(`SELECT COUNT(*) FROM superseded_field_values WHERE field IN ('author', 'editor') AND triage_status IS NULL`).Scan(&losingPending)
+	losingPending = countTriagePending()
 
 	sessionStartEntryCount = total
 	sessionStartDblpKeyCount = dblpKeys
@@ -442,6 +452,7 @@ func openLibraryToUpdate() bool {
 	}
 
 	cleanupIgnoredTitleNonDoubles(&Library)
+	maybeBuildEntrySearchIndex()
 	Library.LoadPDFFiles()
 	if !skipStartupChecks {
 		Library.CheckDblpDuplicates()
@@ -941,6 +952,24 @@ func retireResolvedAuthorEditorSuperseded() {
 	}
 }
 
+// retireSupersededValue removes a triaged superseded author/editor value.
+func retireSupersededValue(key, field, superseded string) {
+	if err := bibExec(`DELETE FROM superseded_field_values WHERE entry_key=? AND field=? AND value=?`, key, field, superseded); err != nil {
+		// Previously silent — see the matching comment in TKeyAliasTable.Load.
+		Library.Warning("retireSuperseded: delete failed (key=%s, field=%s): %s", key, field, err)
+		markDbWriteFailed(fmt.Sprintf("retireSuperseded: delete failed (key=%s, field=%s, value=%s): %s", key, field, superseded, err))
+	}
+}
+
+// markSupersededKept marks a superseded author/editor value as reviewed and kept.
+func markSupersededKept(key, field, superseded string) {
+	if err := bibExec(`UPDATE superseded_field_values SET triage_status='kept' WHERE entry_key=? AND field=? AND value=?`, key, field, superseded); err != nil {
+		// Previously silent — see the matching comment in TKeyAliasTable.Load.
+		Library.Warning("markKept: update failed (key=%s, field=%s): %s", key, field, err)
+		markDbWriteFailed(fmt.Sprintf("markKept: update failed (key=%s, field=%s, value=%s): %s", key, field, superseded, err))
+	}
+}
+
 // --- Command functions ---
 
 // doTriageAuthorMappings interactively reviews all author/editor pairs in superseded_field_values.
@@ -981,21 +1010,8 @@ func doTriageAuthorMappings() {
 	}
 	stderrPrintf("\n")
 
-	retireSuperseded := func(key, field, superseded string) {
-		if err := bibExec(`DELETE FROM superseded_field_values WHERE entry_key=? AND field=? AND value=?`, key, field, superseded); err != nil {
-			// Previously silent — see the matching comment in TKeyAliasTable.Load.
-			Library.Warning("retireSuperseded: delete failed (key=%s, field=%s): %s", key, field, err)
-			markDbWriteFailed(fmt.Sprintf("retireSuperseded: delete failed (key=%s, field=%s, value=%s): %s", key, field, superseded, err))
-		}
-	}
-
-	markKept := func(key, field, superseded string) {
-		if err := bibExec(`UPDATE superseded_field_values SET triage_status='kept' WHERE entry_key=? AND field=? AND value=?`, key, field, superseded); err != nil {
-			// Previously silent — see the matching comment in TKeyAliasTable.Load.
-			Library.Warning("markKept: update failed (key=%s, field=%s): %s", key, field, err)
-			markDbWriteFailed(fmt.Sprintf("markKept: update failed (key=%s, field=%s, value=%s): %s", key, field, superseded, err))
-		}
-	}
+	retireSuperseded := retireSupersededValue
+	markKept := markSupersededKept
 
 	splitOnAnd := func(value string) []string {
 		parts := strings.Split(value, " and ")
@@ -1380,7 +1396,7 @@ outer:
 				"Resolving these first may eliminate this ambiguity automatically.",
 				candID, len(entriesWithCandidates))
 			Library.ResetQuestionFlag()
-			yn, _ := Library.AskForInput("Fix DBLP for these entries now? (y=yes, n=skip)")
+			yn, _ := Library.AskForInput("Fix DBLP for these entries now? (y=yes, n=skip)", candID)
 			if strings.TrimSpace(yn) != "y" {
 				continue
 			}
@@ -1440,7 +1456,7 @@ outer:
 			}
 		}
 		Library.ResetQuestionFlag()
-		raw, inputErr := Library.AskForInput(fmt.Sprintf("Pick (1-%d), k=keep, m=merge all into #%d, q=quit", len(candidates), bestIdx))
+		raw, inputErr := Library.AskForInput(fmt.Sprintf("Pick (1-%d), k=keep, m=merge all into #%d, q=quit", len(candidates), bestIdx), r.alias)
 		if inputErr != nil {
 			break outer
 		}
@@ -1451,35 +1467,7 @@ outer:
 			// keep current assignment
 		case "m":
 			// Merge all candidates into pre-computed best (#bestIdx).
-			bestID := candidates[bestIdx-1]
-			for _, candID := range candidates {
-				if candID == bestID {
-					continue
-				}
-				if fromCand := Library.ContributorByID[candID]; fromCand != nil {
-					Library.Progress("Merging %q (%s) into %q (%s).",
-						fromCand.Name, candID,
-						Library.ContributorByID[bestID].Name, bestID)
-					if mergeContributorInDB(candID, bestID) {
-						for n, id := range Library.NameToContributorID {
-							if id == candID {
-								Library.NameToContributorID[n] = bestID
-							}
-						}
-						// Any ORCID still pointing at the absorbed contributor must be
-						// redirected too, or a later lookup resolves to a deleted
-						// contributor ID and the next write through it hits a FOREIGN
-						// KEY violation (see bibtex_dblp_xml.go's DBLP-key merge sites
-						// for the same fix).
-						for orcid, id := range Library.ORCIDToContributorID {
-							if id == candID {
-								Library.ORCIDToContributorID[orcid] = bestID
-							}
-						}
-						delete(Library.ContributorByID, candID)
-					}
-				}
-			}
+			mergeContributorsInto(candidates, candidates[bestIdx-1])
 		default:
 			n, convErr := strconv.Atoi(strings.TrimSpace(raw))
 			if convErr != nil || n < 1 || n > len(candidates) {
@@ -1506,6 +1494,39 @@ outer:
 	}
 }
 
+// mergeContributorsInto merges every contributor of candidates other than bestID
+// into bestID, redirecting the in-memory name and ORCID maps.
+func mergeContributorsInto(candidates []string, bestID string) {
+	for _, candID := range candidates {
+		if candID == bestID {
+			continue
+		}
+		if fromCand := Library.ContributorByID[candID]; fromCand != nil {
+			Library.Progress("Merging %q (%s) into %q (%s).",
+				fromCand.Name, candID,
+				Library.ContributorByID[bestID].Name, bestID)
+			if mergeContributorInDB(candID, bestID) {
+				for n, id := range Library.NameToContributorID {
+					if id == candID {
+						Library.NameToContributorID[n] = bestID
+					}
+				}
+				// Any ORCID still pointing at the absorbed contributor must be
+				// redirected too, or a later lookup resolves to a deleted
+				// contributor ID and the next write through it hits a FOREIGN
+				// KEY violation (see bibtex_dblp_xml.go's DBLP-key merge sites
+				// for the same fix).
+				for orcid, id := range Library.ORCIDToContributorID {
+					if id == candID {
+						Library.ORCIDToContributorID[orcid] = bestID
+					}
+				}
+				delete(Library.ContributorByID, candID)
+			}
+		}
+	}
+}
+
 // reportHomework prints a summary of remaining work:
 func countUnresolvedGroups() int {
 	n := 0
@@ -1581,6 +1602,27 @@ func doHomework() {
 	doFixDuplicates()
 }
 
+// countTriagePending counts the author/editor superseded values awaiting
+// -triage_author_mappings.
+func countTriagePending() int {
+	var n int
+	bibQueryRow(`SELECT COUNT(*) FROM superseded_field_values WHERE field IN ('author', 'editor') AND triage_status IS NULL`).Scan(&n)
+	return n
+}
+
+// countOrcidNotYetEnriched counts contributors with an ORCID that have never been
+// enriched (no seen record). Only available when the contributor_orcid_seen table
+// exists (dev tree); the second result reports whether it does.
+func countOrcidNotYetEnriched() (int, bool) {
+	var n, seenTableExists int
+	bibQueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='contributor_orcid_seen'`).Scan(&seenTableExists)
+	if seenTableExists == 0 {
+		return 0, false
+	}
+	bibQueryRow(`SELECT COUNT(*) FROM contributors WHERE orcid != '' AND NOT EXISTS (SELECT 1 FROM contributor_orcid_seen s WHERE s.contributor_id = contributors.id AND s.canonical != '')`).Scan(&n)
+	return n, true
+}
+
 func reportHomework() {
 	// Session-change summary: compare current state to what was captured at session open.
 	currentEntries := countBibEntries()
@@ -1616,14 +1658,7 @@ func reportHomework() {
 		printStatBlock("Session changes:", changeRows, true)
 	}
 
-	// Count contributors with an ORCID that have never been enriched (no seen record).
-	// Only available when the contributor_orcid_seen table exists (dev tree).
-	newOrcidContributors := 0
-	var seenTableExists int
-	bibQueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='contributor_orcid_seen'`).Scan(&seenTableExists)
-	if seenTableExists > 0 {
-		bibQueryRow(`SELECT COUNT(*) FROM contributors WHERE orcid != '' AND NOT EXISTS (SELECT 1 FROM contributor_orcid_seen s WHERE s.contributor_id = contributors.id AND s.canonical != '')`).Scan(&newOrcidContributors)
-	}
+	newOrcidContributors, seenTableExists := countOrcidNotYetEnriched()
 
 	hwComment := func(count int, cmd string) string {
 		if cmd != "" && count > 0 {
@@ -1631,11 +1666,10 @@ func reportHomework() {
 		}
 		return ""
 	}
-	var triagePending int
-	bibQueryRow(`SELECT COUNT(*) FROM superseded_field_values WHERE field IN ('author', 'editor') AND triage_status IS NULL`).Scan(&triagePending)
+	triagePending := countTriagePending()
 
 	var hwRows []statRow
-	if seenTableExists > 0 {
+	if seenTableExists {
 		hwRows = append(hwRows, statRow{StatContributorsWithOrcidNotYetEnriched, fmt.Sprintf("%d", newOrcidContributors), hwComment(newOrcidContributors, "enrich_contributor_data")})
 	}
 	hwRows = append(hwRows,
@@ -1643,6 +1677,10 @@ func reportHomework() {
 		statRow{StatEntriesWithUnresolvedDblpCandidates, fmt.Sprintf("%d", dblpCandidates), hwComment(dblpCandidates, "fix_candidates")},
 		statRow{StatTitleGroupsWithUnresolvedDuplicates, fmt.Sprintf("%d", unresolvedGroups), hwComment(unresolvedGroups, "fix_duplicates")},
 	)
+	if len(Library.lintRules()) > 0 {
+		lintFindings := countLintFindings()
+		hwRows = append(hwRows, statRow{StatEntriesWithLintFindings, fmt.Sprintf("%d", lintFindings), hwComment(lintFindings, "fix_entries")})
+	}
 	printStatBlock("Homework:", hwRows, true)
 	stderrPrintf("\n")
 }
@@ -1733,6 +1771,7 @@ func doDefaultRun() {
 		Library.BeginDeferringMessages()
 		Library.CheckDuplicateDBLPKeys()
 		Library.CheckLoneProceedings()
+		Library.CheckVenueSeries()
 		Library.FlushDeferredMessages()
 		if Library.QuitWasRequested() {
 			return
@@ -1754,6 +1793,42 @@ func doCheckPDFs() {
 	}
 }
 
+func doVerifyPDFs(args []string) {
+	if openLibraryToUpdate() {
+		var keys []string
+		if len(args) == 0 {
+			for key := range Library.PDFFiles {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		} else {
+			for _, arg := range args {
+				keys = append(keys, resolveInputKey(cleanKey(arg)))
+			}
+		}
+		Library.VerifyPDFContents(keys, len(args) > 0)
+	}
+}
+
+func doEmbedPDFMetadata(args []string) {
+	if openLibraryToUpdate() {
+		var keys []string
+		if len(args) == 0 {
+			for key := range Library.PDFFiles {
+				if Library.EntryExists(key) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+		} else {
+			for _, arg := range args {
+				keys = append(keys, resolveInputKey(cleanKey(arg)))
+			}
+		}
+		Library.EmbedAllPDFMetadata(keys)
+	}
+}
+
 func doGetPdfs() {
 	if openLibraryToUpdate() {
 		Library.ReadURLsIgnoreFile()
@@ -1762,6 +1837,24 @@ func doGetPdfs() {
 }
 
 func doFindEntries(args []string) {
+	if query, isQuery := queryFromArgs(args); isQuery {
+		cond, err := ParseQuery(query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid query %q: %s\n", query, err)
+			os.Exit(1)
+		}
+		if openLibraryToReport() {
+			keys := Library.QueryEntries(cond)
+			if err := Library.WriteQueryResults(os.Stdout, keys, parseQueryColumns(queryColumns), outputFormat); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not write query results: %s\n", err)
+			}
+		}
+		return
+	}
+	if len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "Usage: -find_entries <field> [<value>] | -find_entries <query> | -find_entries where <query>")
+		os.Exit(1)
+	}
 	if openLibraryToReport() {
 		field := strings.ToLower(args[0])
 		value := ""
@@ -1770,7 +1863,7 @@ func doFindEntries(args []string) {
 		}
 		var matches []TBibFieldMatch
 		if field == "groups" {
-			matches = findBibEntriesByGroup(value)
+			matches = Library.FindEntriesByGroup(value)
 		} else {
 			matches = findBibEntriesByField(field, value)
 		}
@@ -1841,8 +1934,21 @@ func doRenderGroup(args []string, useAliases bool) {
 				fmt.Fprintf(os.Stderr, "Could not write %s: %s\n", path, err)
 			}
 		}
-		for _, m := range findBibEntriesByGroup(group) {
-			key := Library.MapEntryKey(m.Key)
+		var groupKeys []string
+		if query, isQuery := explicitQuery(group); isQuery {
+			keys, err := Library.QueryEntryKeys(query)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid query %q: %s\n", query, err)
+				return
+			}
+			groupKeys = keys
+		} else {
+			for _, m := range Library.FindEntriesByGroup(group) {
+				groupKeys = append(groupKeys, m.Key)
+			}
+		}
+		for _, groupKey := range groupKeys {
+			key := Library.MapEntryKey(groupKey)
 			if key == "" {
 				continue
 			}
@@ -1892,7 +1998,7 @@ func doRenderGroup(args []string, useAliases bool) {
 
 func doListGroupAliases(args []string) {
 	if openLibraryToReport() {
-		for _, m := range findBibEntriesByGroup(args[0]) {
+		for _, m := range Library.FindEntriesByGroup(args[0]) {
 			key := Library.MapEntryKey(m.Key)
 			if key == "" {
 				continue
@@ -2037,7 +2143,7 @@ func doEntryKeyAlias(args []string, withMap bool) {
 		rawKey := cleanKey(args[0])
 		key := Library.MapEntryKey(rawKey)
 		alias := Library.PreferredKey(key)
-		if alias == "" && key != rawKey && validPreferredKeyAlias.MatchString(rawKey) {
+		if alias == "" && key != rawKey && validPreferredAlias(rawKey) {
 			// Input is a valid preferred-alias that resolved to a canonical key;
 			// use it directly so -map works before preferredalias is set on the entry.
 			alias = rawKey
@@ -2046,7 +2152,7 @@ func doEntryKeyAlias(args []string, withMap bool) {
 			// No preferred alias yet — try to generate one from author/year/title.
 			entry := Library.buildEntry(key)
 			if derived := Library.derivePreferredAlias(entry); derived != "" {
-				Library.setPreferredAlias(entry, derived)
+				Library.setGeneratedPreferredAlias(entry, derived)
 				alias = derived
 			}
 		}
@@ -2206,11 +2312,78 @@ func doShowEntry(args []string) {
 	}
 }
 
+func doExplain(args []string) {
+	if openLibraryToReport() {
+		key := resolveInputKey(cleanKey(args[0]))
+		field := ""
+		if len(args) > 1 {
+			field = strings.ToLower(args[1])
+		}
+		report := Library.ExplainEntry(key, field)
+		if report == "" {
+			fmt.Fprintf(os.Stderr, "No entry found for %s\n", args[0])
+			return
+		}
+		fmt.Print(report)
+	}
+}
+
+func doSearch(args []string) {
+	terms := strings.Join(args, " ")
+	// Searching is read-only: the index is built when the library is opened for
+	// update or imported (see maybeBuildEntrySearchIndex).
+	if !openLibraryToReport() {
+		return
+	}
+	if entrySearchIndexEmpty() && countBibEntries() > 0 {
+		fmt.Fprintln(os.Stderr, "The search index has not been built yet; it is built by the next run that updates the library.")
+		return
+	}
+	results, err := Library.SearchEntries(terms)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid search %q: %s\n", terms, err)
+		os.Exit(1)
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "No entries match %q\n", terms)
+		return
+	}
+	if err := Library.WriteSearchResults(os.Stdout, results, outputFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write search results: %s\n", err)
+	}
+}
+
+func doStats() {
+	if openLibraryToReport() {
+		buildTitleIndexFromDb(&Library)
+		if err := Library.CollectStats().WriteStats(os.Stdout, outputFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not write the statistics: %s\n", err)
+		}
+	}
+}
+
+// doFixEntries checks the given entries, or, without arguments, the entries
+// with lint findings, offering to waive each lint finding along the way.
 func doFixEntries(args []string) {
 	if openLibraryToUpdate() {
 		Library.ReadKeyNonDoublesFile()
 		Library.FixDblpHierarchy()
-		for _, key := range cleanKeys(args) {
+		keys := cleanKeys(args)
+		if len(args) == 0 {
+			keys = lintFindingKeys()
+			if len(keys) == 0 {
+				fmt.Fprintln(os.Stderr, "No lint findings. Usage: -fix_entries <key>...")
+				return
+			}
+		}
+		lintWalkthrough = true
+		for _, key := range keys {
+			if Library.QuitWasRequested() {
+				break
+			}
+			if !Library.EntryExists(Library.MapEntryKey(key)) {
+				continue
+			}
 			Library.Progress("  Checking %s", Library.MapEntryKey(key))
 			doAllChecks(key)
 		}
@@ -2728,6 +2901,7 @@ func addDoiEntry(entry *TBibTeXEntry, label string) string {
 	}
 	Library.Progress("Watch: added from DOI (%s): %s", label, key)
 	doAllChecks(key)
+	Library.fireHook(THookEvent{Event: HookEntryCreated, Keys: []string{Library.MapEntryKey(key)}, Source: "watch"})
 	return key
 }
 
@@ -3017,7 +3191,7 @@ func doSetPreferredAlias(args []string) {
 			fmt.Fprintf(os.Stderr, "Entry %s does not exist\n", key)
 			return
 		}
-		if !validPreferredKeyAlias.MatchString(alias) {
+		if !validPreferredAlias(alias) {
 			Library.Error(ErrorSetPreferredAliasInvalidFormat, alias)
 			return
 		}
@@ -3026,6 +3200,7 @@ func doSetPreferredAlias(args []string) {
 			return
 		}
 		Library.setPreferredAlias(entry, alias)
+		Library.setLineage(key, PreferredAliasField, alias, "", true)
 		doAllChecks(key)
 	}
 }
@@ -3136,7 +3311,7 @@ func doCorrectName(args []string) {
 	}
 
 	fmt.Fprintln(os.Stderr, "")
-	if !Reporting.ConfirmAction("Proceed?") {
+	if !Reporting.ConfirmAction("Proceed?", oldName, newName) {
 		return
 	}
 
@@ -3593,7 +3768,7 @@ func doEnrichOrcidProfilesCore() {
 		case "n":
 			newCanon = passportNatural
 		case "e":
-			raw, _ := Library.AskForInput("Enter new canonical name")
+			raw, _ := Library.AskForInput("Enter new canonical name", p.orcid)
 			newCanon = strings.TrimSpace(raw)
 		default: // "k" or empty → keep
 		}
@@ -3709,7 +3884,7 @@ func doUpgradeWatchActions() {
 						Library.Progress("  %d) %s (%s)", i+1, name, cid)
 					}
 					Library.Progress("  0) keep as name entry")
-					choice, _ := Library.AskForInput("Enter number (0=keep as name entry)")
+					choice, _ := Library.AskForInput("Enter number (0=keep as name entry)", e.Value)
 					n := 0
 					fmt.Sscan(choice, &n)
 					if n >= 1 && n <= len(ids) {
@@ -3852,6 +4027,8 @@ func main() {
 
 	baseFlag := flag.String("base", "", "path/basename of the library (required)")
 	flag.BoolVar(&forceWrite, "force_write", false, "force write even if unchanged")
+	flag.StringVar(&queryColumns, "columns", "", "comma-separated output columns for -find_entries queries (default "+defaultQueryColumns+")")
+	flag.StringVar(&outputFormat, "format", "tsv", "output format for -find_entries queries and -search (tsv or json) and for -stats (text, json or html)")
 
 	flag.BoolVar(&cmdTrustHints, "trust_hints", false, "harvest: auto-accept key-hint matches without confirmation")
 	flag.BoolVar(&cmdCollectKeys, "collect_keys", false, "harvest: add source entry keys to the hints DB when unambiguous")
@@ -3868,6 +4045,14 @@ func main() {
 		cmdEntryKey           bool
 		cmdEntryKeyAlias      bool
 		cmdShowEntry          bool
+		cmdExplain            bool // -explain <key> [field]: show the provenance of an entry's field values
+		cmdSearch             bool // -search "<terms>": full-text search over titles, abstracts and notes
+		cmdServe              bool // -serve <addr>: read-only JSON HTTP API over the library
+		cmdReviewServer       bool // -review_server <addr>: local web UI for duplicate and DBLP candidate review
+		cmdPolicyReport       bool // -policy_report [session]: decisions made by the conflict policy
+		cmdVenues             bool // -venues [series]: parsed venues of proceedings
+		cmdLsp                bool // -lsp: language server for \cite{} keys in .tex and entry warnings in .bib files
+		cmdStats              bool // -stats: library statistics and health report
 		cmdFixEntries         bool
 		cmdFixDuplicates        bool // -fix_duplicates: fix entries in unresolved title groups
 		cmdFixCandidates        bool // -fix_candidates: link unmatched entries to DBLP
@@ -3878,6 +4063,7 @@ func main() {
 		cmdAssignOrcid              bool // -assign_orcid: assign an ORCID to a contributor
 		cmdEnrichContributorData    bool // -enrich_contributor_data: run full contributor enrichment pipeline
 		cmdHomework                 bool // -homework: run all pending homework in priority order
+		cmdTUI                      bool // -tui: with -homework, triage in a full-screen terminal UI
 		cmdMergeContributors     bool // -merge_contributors: merge two contributors into one
 		cmdAddDblpEntry   bool
 		cmdAddDblpEntries bool
@@ -3904,6 +4090,9 @@ func main() {
 		cmdRenderAsHTML       bool
 		cmdRenderAsText       bool
 		cmdCheckPdfs                bool
+		cmdVerifyPdfs               bool
+		cmdEmbedPdfMetadata         bool
+		cmdAttach                   bool // -attach <key> <role> <file>: attach slides, supplementary material, … to an entry
 		cmdAlignBooktitleCountries  bool
 		cmdUpdateOrcidCache         bool
 		cmdLoadDblpXml              bool
@@ -3922,22 +4111,42 @@ func main() {
 		cmdExport tableListFlag
 		cmdImport tableListFlag
 
+		// One-file-per-entry textual export/import, for curating the library in git.
+		cmdExportEntries bool
+		cmdImportEntries bool
+
 		cmdSetSyncStatus string // -set_sync_status <status> <source_key> <stem>: set/clear a sync status flag
 
 		cmdImportAllCSV bool // legacy migration helper; kept for migrate.sh compat
 		cmdImportBib    bool
 
 		cmdHarvest bool // -harvest: harvest entries from a bib file (path from args) or stdin
+		cmdHarvestPDFs bool // -harvest_pdfs: harvest entries from the PDF files in a folder (path from args)
+
+		cmdMergeLibrary bool // -merge_library <other-base>: merge another library database into ours
+
+		cmdUndo bool // -undo [session|last N decisions]: revert the last session from the session journal
+
+		cmdRecordAnswers string // -record_answers <file>: record the answers given to interactive questions
+		cmdReplayAnswers string // -answers <file>: replay recorded answers instead of asking
 	)
 
 	flag.BoolVar(&cmdSync, "sync", false, "sync library to bib file(s) via exchange config; optional arg narrows to one file")
 	flag.BoolVar(&cmdPull, "pull", false, "with -sync: skip up-sync (phase 1) and re-import; only write bib output from DB")
 	flag.BoolVar(&cmdGetPdfs, "get_pdfs", false, "download missing PDFs into the files folder")
-	flag.BoolVar(&cmdFindEntries, "find_entries", false, "list entries matching field [value] (key TAB value per line)")
+	flag.BoolVar(&cmdFindEntries, "find_entries", false, "list entries matching field [value] (key TAB value per line), or a query such as 'type=article and year=2015..2020 and not has_pdf' (as one argument, or after 'where')")
 	flag.BoolVar(&cmdEntryKey, "entry_key", false, "resolve alias to canonical key")
 	flag.BoolVar(&cmdEntryKeyAlias, "entry_key_alias", false, "get preferred alias for a key")
 	flag.BoolVar(&cmdShowEntry, "show_entry", false, "print full entry content")
-	flag.BoolVar(&cmdFixEntries, "fix_entries", false, "fix/check specific entries")
+	flag.BoolVar(&cmdExplain, "explain", false, "show where an entry's field values came from: -explain <key> [field]")
+	flag.BoolVar(&cmdServe, "serve", false, "serve entries, groups, contributors, aliases, renderings and search as a read-only JSON HTTP API: -serve <addr> (e.g. localhost:8080)")
+	flag.BoolVar(&cmdReviewServer, "review_server", false, "serve a local web page to review potential duplicates and DBLP candidates (merge, non-double, link, waive): -review_server <addr> (e.g. localhost:8081)")
+	flag.BoolVar(&cmdPolicyReport, "policy_report", false, "list the field conflicts and merges decided by the conflict policy in a session: -policy_report [session] (default: the latest)")
+	flag.BoolVar(&cmdVenues, "venues", false, "list the venues (series, number, event dates and location) parsed from proceedings: -venues [series]")
+	flag.BoolVar(&cmdStats, "stats", false, "report library statistics and curation progress; -format text (default), json or html")
+	flag.BoolVar(&cmdLsp, "lsp", false, "run a language server (JSON-RPC on stdin/stdout) offering citation key completion, hover, diagnostics and alias fixes in .tex and .bib files")
+	flag.BoolVar(&cmdSearch, "search", false, `full-text search over titles, abstracts and notes, ranked with snippets: -search "<terms>" (supports "phrases", prefix* and AND/OR/NOT)`)
+	flag.BoolVar(&cmdFixEntries, "fix_entries", false, "fix/check specific entries, or (without keys) the entries with lint findings")
 	flag.BoolVar(&cmdFixEntries, "fix_entry", false, "alias for -fix_entries")
 	flag.BoolVar(&cmdFixDuplicates, "fix_duplicates", false, "interactively resolve title-duplicate pairs in the library")
 	flag.BoolVar(&cmdTriageAuthorMappings, "triage_author_mappings", false, "triage author/editor entries in superseded_field_values")
@@ -3947,6 +4156,7 @@ func main() {
 	flag.BoolVar(&cmdAssignOrcid, "assign_orcid", false, "assign an ORCID to a contributor: -assign_orcid <name-or-EP-id> <orcid>")
 	flag.BoolVar(&cmdEnrichContributorData, "enrich_contributor_data", false, "run full contributor enrichment pipeline: absorb DBLP names+ORCIDs, enrich from ORCID profiles, merge ORCID duplicates")
 	flag.BoolVar(&cmdHomework, "homework", false, "run all pending homework in priority order: enrich contributors, triage superseded values, fix DBLP candidates, fix duplicates")
+	flag.BoolVar(&cmdTUI, "tui", false, "with -homework: triage all pending items in a full-screen terminal UI with filtering, jumping and side-by-side diffs")
 	flag.BoolVar(&cmdMatchedOrcidDataOnly, "matched_orcid_data_only", false, "with -enrich_contributor_data: skip ORCID challenges in step 3, leaving them as homework")
 	flag.BoolVar(&cmdMergeContributors, "merge_contributors", false, "merge one or more contributors into another: -merge_contributors <from...> <into>")
 	flag.BoolVar(&cmdAddDblpEntries, "update_all_dblp_entries", false, "update all library entries that have a DBLP key with fresh DBLP data")
@@ -3970,7 +4180,7 @@ func main() {
 	flag.BoolVar(&cmdRemoveFromGroup, "remove_from_group", false, "remove an entry from a group")
 	flag.BoolVar(&cmdSetGroups, "set_groups", false, "set group membership: -set_groups <key> [+] <group>...")
 	flag.BoolVar(&cmdSetField, "set_field", false, "set a field on an entry: -set_field <key> <field> [<value>] (omit value to clear)")
-	flag.BoolVar(&cmdRenderGroup, "render_group", false, "render all entries in a group to pubs/citations folders")
+	flag.BoolVar(&cmdRenderGroup, "render_group", false, "render all entries in a group (or matching a query given as 'where <query>') to pubs/citations folders")
 	flag.BoolVar(&cmdListGroupAliases, "list_group_aliases", false, "list canonical|alias pairs for all entries in a group")
 	flag.BoolVar(&cmdUseAliases, "use_aliases", false, "use preferred aliases as file names in -render_group")
 	flag.BoolVar(&cmdRenderAsBibTeX, "render_as_bibtex", false, "render entry as self-contained BibTeX")
@@ -3978,13 +4188,16 @@ func main() {
 	flag.BoolVar(&cmdRenderAsHTML, "render_as_html", false, "render entry as HTML bibliography reference")
 	flag.BoolVar(&cmdRenderAsText, "render_as_text", false, "render entry as plain-text bibliography reference")
 	flag.BoolVar(&cmdCheckPdfs, "check_pdfs", false, "check PDF health, orphan files, and duplicates in the files folder")
+	flag.BoolVar(&cmdVerifyPdfs, "verify_pdfs", false, "check that PDFs (of the given keys, or all) match their entries' DOI, title and first author")
+	flag.BoolVar(&cmdEmbedPdfMetadata, "embed_pdf_metadata", false, "embed key, title, contributors, DOI, DBLP key and citation into the PDFs (of the given keys, or all)")
+	flag.BoolVar(&cmdAttach, "attach", false, "attach a file to an entry: -attach <key> <role> <file>, with role paper, slides, supplement, preprint or camera-ready")
 flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "detect and fix unbraced country names in booktitle fields")
 	flag.BoolVar(&cmdUpdateOrcidCache, "update_orcid", false, "refresh the ORCID disk cache for all known contributors (oldest-first, q+Enter to stop)")
 	flag.BoolVar(&cmdLoadDblpXml, "load_dblp_xml", false, "load a DBLP .xml.gz export into the local DBLP file store")
 	flag.BoolVar(&cmdUpdateDblp, "update_dblp", false, "download the latest DBLP XML export from dblp.uni-trier.de")
 	flag.BoolVar(&cmdRepairDblpManifest, "repair_dblp_manifest", false, "rebuild DBLP manifest and title index from a .xml.gz export")
 	flag.BoolVar(&cmdRebuildDblpCrossrefIndex, "rebuild_dblp_crossref_index", false, "rebuild DBLP crossref children index from stored data.json files")
-	flag.BoolVar(&cmdRebuildDblpTitleIndex, "rebuild_dblp_title_index", false, "rebuild DBLP title index from stored data.json files (no XML needed; -base required for folder config)")
+	flag.BoolVar(&cmdRebuildDblpTitleIndex, "rebuild_dblp_title_index", false, "rebuild DBLP title and DOI indexes from stored data.json files (no XML needed; -base required for folder config)")
 	flag.BoolVar(&cmdRestoreKeyHints, "restore_key_hints", false, "restore key hints from a backup CSV, remapping old keys via key_oldies")
 	flag.StringVar(&restoreKeyHintsPath, "hints_csv", "", "path to the backup key_hints.csv for -restore_key_hints")
 	flag.BoolVar(&cmdDeleteGarbage, "delete_garbage", false, "delete DBLP trash folder contents and exit")
@@ -3995,10 +4208,17 @@ flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "d
 	// Unified table export / import (v23.0)
 	flag.Var(&cmdExport, "export", "export tables to <base>.tables/ (bare = all; or comma-separated table names)")
 	flag.Var(&cmdImport, "import", "import tables from <base>.tables/, replace-all with confirmation (bare = all; or comma-separated table names)")
+	flag.BoolVar(&cmdExportEntries, "export_entries", false, "export the library as one text file per entry/contributor: -export_entries [folder] (default <base>.entries/)")
+	flag.BoolVar(&cmdImportEntries, "import_entries", false, "apply a folder written by -export_entries as an update: -import_entries [folder] (default <base>.entries/)")
 	flag.StringVar(&cmdSetSyncStatus, "set_sync_status", "", "set/clear a sync status flag: -set_sync_status <status|''> <source_key> <stem>")
 	flag.BoolVar(&cmdImportAllCSV, "import_all_csv", false, "import all mapping CSVs (migration helper for migrate.sh)")
 	flag.BoolVar(&cmdImportBib, "import_bib", false, "import a bib file into the DB (requires filename argument; use to initialise or reinitialise bib_entries)")
+	flag.BoolVar(&cmdMergeLibrary, "merge_library", false, "merge another bibtex_check library into this one: -merge_library <other-base>")
+	flag.BoolVar(&cmdUndo, "undo", false, "undo the last session, or only its last N decisions: -undo [session|last N decisions]")
 	flag.BoolVar(&cmdHarvest, "harvest", false, "interactively ingest entries from a bib file (path from args) or stdin into the library")
+	flag.BoolVar(&cmdHarvestPDFs, "harvest_pdfs", false, "interactively ingest the PDF files in a folder (path from args) into the library, identified by DOI, arXiv id, ISBN or title")
+	flag.StringVar(&cmdRecordAnswers, "record_answers", "", "append the answers given to interactive questions to this file, for replay with -answers")
+	flag.StringVar(&cmdReplayAnswers, "answers", "", "answer interactive questions from this file (see -record_answers) instead of stdin; unanswered ones are skipped or deferred and written to <file>.open")
 
 	flag.Parse()
 	args := flag.Args()
@@ -4014,6 +4234,8 @@ flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "d
 				cmdMap = true
 			case "-use_aliases", "--use_aliases":
 				cmdUseAliases = true
+			case "-tui", "--tui":
+				cmdTUI = true
 			default:
 				filtered = append(filtered, a)
 			}
@@ -4049,6 +4271,10 @@ flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "d
 	BibFile = bibTeXBaseName + BibFileExtension
 
 	Reporting = TInteraction{}
+	if err := openAnswerFiles(cmdRecordAnswers, cmdReplayAnswers); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot open answers file:", err)
+		os.Exit(1)
+	}
 
 	loadBibTeXFolders(bibTeXFolder + bibTeXBaseName + SettingsFileExtension)
 	if backupFolder == "" {
@@ -4145,7 +4371,7 @@ flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "d
 	maybeMigrateDblpNameFiles()
 	connectToDatabase()
 
-	if !cmdSync && !cmdFindEntries && !cmdEntryKey && !cmdEntryKeyAlias && !cmdShowEntry {
+	if !cmdSync && !cmdFindEntries && !cmdEntryKey && !cmdEntryKeyAlias && !cmdShowEntry && !cmdExplain && !cmdPolicyReport && !cmdVenues && !cmdSearch && !cmdServe && !cmdLsp && !cmdStats {
 		maybeStartDblpTrashCleanup()
 	}
 
@@ -4161,8 +4387,8 @@ flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "d
 		doGetPdfs()
 
 	case cmdFindEntries:
-		if len(args) == 0 || len(args) > 2 {
-			fmt.Fprintln(os.Stderr, "Usage: -find_entries <field> [<value>]")
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Usage: -find_entries <field> [<value>] | -find_entries <query> | -find_entries where <query>")
 			os.Exit(1)
 		}
 		doFindEntries(args)
@@ -4195,11 +4421,65 @@ flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "d
 		}
 		doShowEntry(args)
 
-	case cmdFixEntries:
+	case cmdExplain:
+		if len(args) == 0 || len(args) > 2 {
+			fmt.Fprintln(os.Stderr, "Usage: -explain <key> [field]")
+			os.Exit(1)
+		}
+		doExplain(args)
+
+	case cmdPolicyReport:
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, "Usage: -policy_report [session]")
+			os.Exit(1)
+		}
+		doPolicyReport(strings.Join(args, ""))
+
+	case cmdVenues:
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, "Usage: -venues [series]")
+			os.Exit(1)
+		}
+		if openLibraryToReport() {
+			Library.ReportVenues(strings.Join(args, ""))
+		}
+
+	case cmdSearch:
 		if len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "Usage: -fix_entries <key>...")
+			fmt.Fprintln(os.Stderr, `Usage: -search "<terms>"`)
+			os.Exit(1)
+		}
+		doSearch(args)
+
+	case cmdServe:
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: -serve <addr>   (e.g. -serve localhost:8080)")
 			os.Exit(1)
 		}
+		if err := ServeLibrary(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "serve: %s\n", err)
+			os.Exit(1)
+		}
+
+	case cmdReviewServer:
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: -review_server <addr>   (e.g. -review_server localhost:8081)")
+			os.Exit(1)
+		}
+		if err := ReviewLibrary(args[0]); err != nil {
+			Library.Warning(WarningReviewServerFailed, err)
+		}
+
+	case cmdStats:
+		doStats()
+
+	case cmdLsp:
+		if err := ServeLanguageServer(); err != nil {
+			fmt.Fprintf(os.Stderr, "lsp: %s\n", err)
+			os.Exit(1)
+		}
+
+	case cmdFixEntries:
 		doFixEntries(args)
 
 	case cmdFixDuplicates:
@@ -4228,7 +4508,11 @@ flag.BoolVar(&cmdAlignBooktitleCountries, "align_booktitle_countries", false, "d
 		doUpdateOrcidCache()
 
 	case cmdHomework:
-		doHomework()
+		if cmdTUI {
+			doHomeworkTUI()
+		} else {
+			doHomework()
+		}
 
 	case cmdEnrichContributorData:
 		doEnrichContributorData()
@@ -4390,7 +4674,7 @@ case cmdWatch:
 
 	case cmdRenderGroup:
 		if len(args) != 3 {
-			fmt.Fprintln(os.Stderr, "Usage: -render_group [-use_aliases] <group> <pubs_folder> <citations_folder>")
+			fmt.Fprintln(os.Stderr, "Usage: -render_group [-use_aliases] <group|\"where <query>\"> <pubs_folder> <citations_folder>")
 			os.Exit(1)
 		}
 		doRenderGroup(args, cmdUseAliases)
@@ -4433,6 +4717,19 @@ case cmdWatch:
 	case cmdCheckPdfs:
 		doCheckPDFs()
 
+	case cmdVerifyPdfs:
+		doVerifyPDFs(args)
+
+	case cmdEmbedPdfMetadata:
+		doEmbedPDFMetadata(args)
+
+	case cmdAttach:
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, "Usage: -attach <key> <role> <file>")
+			os.Exit(1)
+		}
+		doAttach(args)
+
 case cmdAlignBooktitleCountries:
 		if openLibraryToUpdate() {
 			Library.CheckAlignBooktitleCountries()
@@ -4463,6 +4760,19 @@ case cmdAlignBooktitleCountries:
 			ImportTables(importSpec, &Library)
 		}
 
+	case cmdExportEntries:
+		if openLibraryToReport() {
+			Library.ExportEntryFiles(entryFilesFolderFromArgs(args))
+		}
+
+	case cmdImportEntries:
+		skipStartupChecks = true
+		if openLibraryToUpdate() {
+			if Library.ImportEntryFiles(entryFilesFolderFromArgs(args)) {
+				bibEntriesModified = true
+			}
+		}
+
 	case cmdImportAllCSV:
 		// Does not require ValidBibDb: mapping tables are imported independently of bib entries.
 		if prepareWorkingDatabase() {
@@ -4478,6 +4788,22 @@ case cmdAlignBooktitleCountries:
 		}
 		doImportBib(args[0])
 
+	case cmdMergeLibrary:
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: -merge_library <other-base>")
+			os.Exit(1)
+		}
+		requireNoDblpImport()
+		doMergeLibrary(args[0])
+
+	case cmdUndo:
+		decisions, ok := parseUndoArgs(args)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Usage: -undo [session|last N decisions]")
+			os.Exit(1)
+		}
+		doUndo(decisions)
+
 	case cmdHarvest:
 		path := ""
 		if len(args) > 0 {
@@ -4485,6 +4811,13 @@ case cmdAlignBooktitleCountries:
 		}
 		doHarvest(path)
 
+	case cmdHarvestPDFs:
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Usage: -harvest_pdfs <dir>")
+			os.Exit(1)
+		}
+		doHarvestPDFs(args[0])
+
 	default:
 		if len(args) > 0 {
 			fmt.Fprintln(os.Stderr, "Unexpected arguments (did you forget a command flag?):", args)
@@ -4495,6 +4828,12 @@ case cmdAlignBooktitleCountries:
 
 	Library.CheckDblpKeyMissingWarnings()
 
+	// Hook events still queued (field and group changes wait for a safe point) are
+	// dispatched before the session's changes are settled below.
+	if !Library.QuitWasRequested() {
+		Library.flushHooks()
+	}
+
 	// Drain any author/editor superseded-values backlog this run produced, rather than
 	// leaving it as a separate manual step every command has to remember to trigger.
 	// Still interactive where a real judgment call is needed — doTriageAuthorMappings
@@ -4507,6 +4846,11 @@ case cmdAlignBooktitleCountries:
 		doTriageAuthorMappings()
 	}
 
+	// PDFs that carry embedded entry metadata follow the changes of their entries.
+	if bibEntriesModified && !Library.QuitWasRequested() {
+		Library.RefreshEmbeddedPDFMetadata()
+	}
+
 	// DB-primary (step 13.6 + 22.7): the bib file is never written during a normal run.
 	// Bib file writes happen only via -sync (full mode). All entry changes are already
 	// persisted to the working DB by setEntryField; finaliseWorkingDatabase flushes them home.
@@ -4516,6 +4860,8 @@ case cmdAlignBooktitleCountries:
 	}
 
 	saveKeyNonDoublesToDb(&Library)
+	reportOpenAnswers()
+	reportPolicyDecisions()
 
 	if !postCheckGate() {
 		dbInteraction.Warning("Post-check gate failed — home database not updated")
@@ -4532,6 +4878,8 @@ case cmdAlignBooktitleCountries:
 func gracefulQuit() {
 	forceCommitBibTransaction()
 	saveKeyNonDoublesToDb(&Library)
+	reportOpenAnswers()
+	reportPolicyDecisions()
 
 	if !postCheckGate() {
 		dbInteraction.Warning("Post-check gate failed — home database not updated")
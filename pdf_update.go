@@ -0,0 +1,301 @@
+/*
+ *
+ * Module:    bibtex_check
+ * Component:
+ * - pdf_update
+ *
+ * Incremental updates of PDF files.
+ *
+ * New and replaced objects are appended to the file, followed by a cross-reference
+ * section and trailer that point back (/Prev) to the previous ones, so that the
+ * original bytes of the file are left untouched. Files whose last cross-reference
+ * section is a cross-reference stream get a cross-reference stream, others a
+ * classic xref table. The length of the file before its first update by us is
+ * kept in the trailer (/BibCheckOriginalLength), so that the original content
+ * can still be recognised, e.g. when looking for duplicate PDFs.
+ *
+ * Creator: Henderik A. Proper (e.proper@acm.org), Luxembourg, in collaboration with Claude.ai
+ *
+ * Version of: 19.10.2026
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+const pdfOriginalLengthKey = "BibCheckOriginalLength"
+
+var (
+	errPDFNoTrailer = errors.New("no usable trailer found in PDF")
+
+	pdfOriginalLengthPattern = regexp.MustCompile(`/` + pdfOriginalLengthKey + `\s+(\d+)`)
+)
+
+type (
+	// pdfTrailer is the trailer of the last cross-reference section of a file.
+	pdfTrailer struct {
+		dict       pdfDict
+		xrefOffset int
+		xrefStream bool
+	}
+
+	// pdfUpdateObject is an object to be written by an incremental update; stream
+	// is nil for objects without a stream.
+	pdfUpdateObject struct {
+		ref    pdfRef
+		value  any
+		stream []byte
+	}
+)
+
+// trailer locates the trailer of the last cross-reference section via startxref.
+func (d *pdfDocument) trailer() (pdfTrailer, error) {
+	i := bytes.LastIndex(d.data, []byte("startxref"))
+	if i < 0 {
+		return pdfTrailer{}, errPDFNoTrailer
+	}
+	x := &pdfLexer{data: d.data, pos: i + len("startxref")}
+	offset, ok := pdfNumber(x.value())
+	if !ok || offset <= 0 || int(offset) >= len(d.data) {
+		return pdfTrailer{}, errPDFNoTrailer
+	}
+	x = &pdfLexer{data: d.data, pos: int(offset)}
+	x.skipSpace()
+
+	if bytes.HasPrefix(d.data[x.pos:], []byte("xref")) {
+		j := bytes.Index(d.data[x.pos:], []byte("trailer"))
+		if j < 0 {
+			return pdfTrailer{}, errPDFNoTrailer
+		}
+		x.pos += j + len("trailer")
+		dict, ok := x.value().(pdfDict)
+		if !ok || dict["Root"] == nil {
+			return pdfTrailer{}, errPDFNoTrailer
+		}
+		return pdfTrailer{dict: dict, xrefOffset: int(offset)}, nil
+	}
+
+	match := pdfObjectPattern.FindSubmatchIndex(d.data[x.pos:])
+	if match == nil || match[0] != 0 {
+		return pdfTrailer{}, errPDFNoTrailer
+	}
+	x.pos += match[1]
+	dict, ok := x.value().(pdfDict)
+	if !ok || dict["Type"] != pdfName("XRef") || dict["Root"] == nil {
+		return pdfTrailer{}, errPDFNoTrailer
+	}
+	return pdfTrailer{dict: dict, xrefOffset: int(offset), xrefStream: true}, nil
+}
+
+// pdfOriginalLength returns the length of a file before its first incremental
+// update by us, or len(data) when it has none.
+func pdfOriginalLength(data []byte) int {
+	tail := data[max(0, len(data)-4096):]
+	matches := pdfOriginalLengthPattern.FindAllSubmatch(tail, -1)
+	if len(matches) == 0 {
+		return len(data)
+	}
+	n, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+	if err != nil || n <= 0 || n > len(data) {
+		return len(data)
+	}
+	return n
+}
+
+// pdfTextString encodes s as a PDF text string: as is when it is ASCII, and as
+// UTF-16BE with a byte order mark otherwise.
+func pdfTextString(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	b := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return string(b)
+}
+
+// pdfWriteValue serialises a value as produced by pdfLexer.
+func pdfWriteValue(b *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case float64:
+		if v == float64(int64(v)) {
+			b.WriteString(strconv.FormatInt(int64(v), 10))
+		} else {
+			b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case string:
+		printable := true
+		for i := 0; i < len(v); i++ {
+			if v[i] < 0x20 || v[i] >= 0x7F {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			b.WriteByte('(')
+			for i := 0; i < len(v); i++ {
+				if v[i] == '(' || v[i] == ')' || v[i] == '\\' {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(v[i])
+			}
+			b.WriteByte(')')
+		} else {
+			fmt.Fprintf(b, "<%X>", v)
+		}
+	case pdfName:
+		b.WriteByte('/')
+		for i := 0; i < len(v); i++ {
+			if c := v[i]; c <= 0x20 || c >= 0x7F || c == '#' || pdfIsDelimiter(c) {
+				fmt.Fprintf(b, "#%02X", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	case pdfKeyword:
+		b.WriteString(string(v))
+	case pdfRef:
+		fmt.Fprintf(b, "%d %d R", v.num, v.gen)
+	case []any:
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			pdfWriteValue(b, item)
+		}
+		b.WriteByte(']')
+	case pdfDict:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b.WriteString("<<")
+		for _, key := range keys {
+			b.WriteByte(' ')
+			pdfWriteValue(b, pdfName(key))
+			b.WriteByte(' ')
+			pdfWriteValue(b, v[key])
+		}
+		b.WriteString(" >>")
+	}
+}
+
+func pdfWriteObject(b *bytes.Buffer, object pdfUpdateObject) {
+	fmt.Fprintf(b, "%d %d obj\n", object.ref.num, object.ref.gen)
+	if object.stream == nil {
+		pdfWriteValue(b, object.value)
+	} else {
+		dict := pdfDict{}
+		if d, ok := object.value.(pdfDict); ok {
+			for key, value := range d {
+				dict[key] = value
+			}
+		}
+		dict["Length"] = len(object.stream)
+		pdfWriteValue(b, dict)
+		b.WriteString("\nstream\n")
+		b.Write(object.stream)
+		b.WriteString("\nendstream")
+	}
+	b.WriteString("\nendobj\n")
+}
+
+// pdfAppendUpdate returns data with an incremental update appended that writes
+// objects. size is the /Size of the new cross-reference section (one more than
+// the highest object number in use); trailer keys other than the cross-reference
+// bookkeeping are carried over from t, and extra keys are added.
+func pdfAppendUpdate(data []byte, t pdfTrailer, objects []pdfUpdateObject, size int, extra pdfDict) []byte {
+	var b bytes.Buffer
+	b.Write(data)
+	if len(data) > 0 && data[len(data)-1] != '\n' && data[len(data)-1] != '\r' {
+		b.WriteByte('\n')
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].ref.num < objects[j].ref.num })
+	offsets := map[int]int{}
+	for _, object := range objects {
+		offsets[object.ref.num] = b.Len()
+		pdfWriteObject(&b, object)
+	}
+
+	trailer := pdfDict{}
+	for _, key := range []string{"Root", "Info", "ID"} {
+		if value, ok := t.dict[key]; ok {
+			trailer[key] = value
+		}
+	}
+	if original, ok := t.dict[pdfOriginalLengthKey]; ok {
+		trailer[pdfOriginalLengthKey] = original
+	} else {
+		trailer[pdfOriginalLengthKey] = len(data)
+	}
+	for key, value := range extra {
+		trailer[key] = value
+	}
+	trailer["Prev"] = t.xrefOffset
+
+	xrefOffset := b.Len()
+	if t.xrefStream {
+		xrefRef := pdfRef{size, 0}
+		offsets[size] = xrefOffset
+		var index []any
+		var entries []byte
+		nums := make([]int, 0, len(offsets))
+		for num := range offsets {
+			nums = append(nums, num)
+		}
+		sort.Ints(nums)
+		for _, num := range nums {
+			off := offsets[num]
+			gen := 0
+			for _, object := range objects {
+				if object.ref.num == num {
+					gen = object.ref.gen
+				}
+			}
+			index = append(index, num, 1)
+			entries = append(entries, 1, byte(off>>24), byte(off>>16), byte(off>>8), byte(off), byte(gen>>8), byte(gen))
+		}
+		trailer["Type"] = pdfName("XRef")
+		trailer["Size"] = size + 1
+		trailer["W"] = []any{1, 4, 2}
+		trailer["Index"] = index
+		pdfWriteObject(&b, pdfUpdateObject{ref: xrefRef, value: trailer, stream: entries})
+	} else {
+		trailer["Size"] = size
+		b.WriteString("xref\n")
+		for _, object := range objects {
+			fmt.Fprintf(&b, "%d 1\n%010d %05d n\r\n", object.ref.num, offsets[object.ref.num], object.ref.gen)
+		}
+		b.WriteString("trailer\n")
+		pdfWriteValue(&b, trailer)
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return b.Bytes()
+}